@@ -83,3 +83,34 @@ func TestDecodeAndEncodeHashMap(t *testing.T) {
 		t.Fatalf("got different result")
 	}
 }
+
+func TestDecodeEncryptedTextComment(t *testing.T) {
+	cipherText := make([]byte, 300)
+	for i := range cipherText {
+		cipherText[i] = byte(i)
+	}
+	c := boc.NewCell()
+	if err := c.WriteUint(0x2167da4b, 32); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlb.Marshal(c, tlb.Bytes(cipherText)); err != nil {
+		t.Fatal(err)
+	}
+	if c.RefsSize() == 0 {
+		t.Fatal("cipher text should be split into snake cells")
+	}
+	opCode, opName, value, err := InternalMessageDecoder(c, nil)
+	if err != nil {
+		t.Fatalf("Unable to decode: %v", err)
+	}
+	if *opCode != EncryptedTextCommentMsgOpCode || *opName != EncryptedTextCommentMsgOp {
+		t.Fatalf("got unexpected op: %v", *opName)
+	}
+	body, ok := value.(EncryptedTextCommentMsgBody)
+	if !ok {
+		t.Fatalf("got unexpected type: %T", value)
+	}
+	if !reflect.DeepEqual([]byte(body.CipherText), cipherText) {
+		t.Fatalf("cipher text mismatch")
+	}
+}
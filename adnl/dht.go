@@ -0,0 +1,438 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	magicDhtNode           = 0x84533248 // crc32("dht.node id:PublicKey addr_list:adnl.addressList version:int signature:bytes = dht.Node")
+	magicDhtNodes          = 0x7974a0be // crc32("dht.nodes nodes:vector dht.node = dht.Nodes")
+	magicDhtKey            = 0xf667de8f // crc32("dht.key id:int256 name:bytes idx:int = dht.Key")
+	magicDhtKeyDescription = 0x281d4e05 // crc32("dht.keyDescription key:dht.key id:PublicKey update_rule:dht.UpdateRule signature:bytes = dht.KeyDescription")
+	magicDhtFindNode       = 0x6ce2ce6b // crc32("dht.findNode key:int256 k:int = dht.Nodes")
+	magicDhtFindValue      = 0xae4b6011 // crc32("dht.findValue key:int256 k:int = dht.ValueResult")
+	magicAdnlAddressList   = 0x2227e658 // crc32("adnl.addressList addrs:vector adnl.Address version:int reinit_date:int priority:int expire_at:int = adnl.AddressList")
+	magicOverlayNodes      = 0xe487290e // crc32("overlay.nodes nodes:vector overlay.node = overlay.Nodes")
+
+	defaultDhtK = 10
+	defaultDhtA = 3
+	// dhtQueryTimeout limits the time to wait for an answer of a single DHT node.
+	dhtQueryTimeout = 3 * time.Second
+)
+
+var (
+	ErrDhtValueNotFound = errors.New("adnl: dht value not found")
+)
+
+type dhtNode struct {
+	id   tl.Int256
+	key  ed25519.PublicKey
+	addr string
+}
+
+// DHTClient looks up values and nodes in the TON DHT.
+// It only sends queries and doesn't store values for other nodes.
+type DHTClient struct {
+	gateway *Gateway
+	k       int
+	a       int
+
+	mu    sync.Mutex
+	nodes map[tl.Int256]dhtNode
+}
+
+// NewDHTClient returns a DHT client that sends queries through the gateway
+// and uses static nodes of the given configuration to bootstrap lookups.
+// The gateway must be listening.
+func NewDHTClient(gateway *Gateway, conf config.DHTConfig) (*DHTClient, error) {
+	c := &DHTClient{
+		gateway: gateway,
+		k:       conf.K,
+		a:       conf.A,
+		nodes:   map[tl.Int256]dhtNode{},
+	}
+	if c.k <= 0 {
+		c.k = defaultDhtK
+	}
+	if c.a <= 0 {
+		c.a = defaultDhtA
+	}
+	for _, n := range conf.StaticNodes {
+		if len(n.Hosts) == 0 {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(n.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dht node key %v: %w", n.Key, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid dht node key length: %v", len(key))
+		}
+		id, err := PublicKeyEd25519(key).ShortID()
+		if err != nil {
+			return nil, err
+		}
+		c.nodes[id] = dhtNode{id: id, key: key, addr: n.Hosts[0]}
+	}
+	if len(c.nodes) == 0 {
+		return nil, fmt.Errorf("no dht nodes")
+	}
+	return c, nil
+}
+
+// KeyID returns an ID of the DHT key which is equal to sha256 of the TL-serialized key.
+func KeyID(key DhtKeyC) (tl.Int256, error) {
+	b, err := marshalBoxed(magicDhtKey, key)
+	if err != nil {
+		return tl.Int256{}, err
+	}
+	return tl.Int256(sha256.Sum256(b)), nil
+}
+
+// FindValue looks up a value stored under the given key.
+// It returns ErrDhtValueNotFound if none of the nodes close to the key has the value.
+func (c *DHTClient) FindValue(ctx context.Context, key DhtKeyC) (DhtValueC, error) {
+	id, err := KeyID(key)
+	if err != nil {
+		return DhtValueC{}, err
+	}
+	query, err := marshalBoxed(magicDhtFindValue, DhtFindValueC{Key: id, K: uint32(c.k)})
+	if err != nil {
+		return DhtValueC{}, err
+	}
+	var (
+		mu    sync.Mutex
+		value *DhtValueC
+	)
+	err = c.lookup(ctx, id, query, func(answer []byte) ([]DhtNodeC, bool, error) {
+		var res DhtValueResult
+		if err := tl.Unmarshal(bytes.NewReader(answer), &res); err != nil {
+			return nil, false, err
+		}
+		switch res.SumType {
+		case "DhtValueNotFound":
+			return res.DhtValueNotFound.Nodes.Nodes, false, nil
+		case "DhtValueFound":
+			v := res.DhtValueFound.Value.DhtValueC
+			if err := checkDhtValue(key, v); err != nil {
+				return nil, false, err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			value = &v
+			return nil, true, nil
+		}
+		return nil, false, fmt.Errorf("unknown dht.ValueResult")
+	})
+	if err != nil {
+		return DhtValueC{}, err
+	}
+	if value == nil {
+		return DhtValueC{}, ErrDhtValueNotFound
+	}
+	return *value, nil
+}
+
+// FindNodes looks up DHT nodes which are the closest to the given key ID.
+func (c *DHTClient) FindNodes(ctx context.Context, id tl.Int256) ([]DhtNodeC, error) {
+	query, err := marshalBoxed(magicDhtFindNode, DhtFindNodeC{Key: id, K: uint32(c.k)})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu    sync.Mutex
+		found = map[tl.Int256]DhtNodeC{}
+	)
+	err = c.lookup(ctx, id, query, func(answer []byte) ([]DhtNodeC, bool, error) {
+		var res DhtNodesC
+		if err := unmarshalBoxed(answer, magicDhtNodes, &res); err != nil {
+			return nil, false, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, n := range res.Nodes {
+			if nodeID, err := n.Id.ShortID(); err == nil {
+				found[nodeID] = n
+			}
+		}
+		return res.Nodes, false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]tl.Int256, 0, len(found))
+	for nodeID := range found {
+		ids = append(ids, nodeID)
+	}
+	sortByDistance(ids, id)
+	nodes := make([]DhtNodeC, 0, min(len(ids), c.k))
+	for _, nodeID := range ids[:min(len(ids), c.k)] {
+		nodes = append(nodes, found[nodeID])
+	}
+	return nodes, nil
+}
+
+// ResolveADNLAddress looks up a public key and UDP addresses of the given ADNL address.
+// The address can be obtained with liteclient.ParseADNLAddress or from a DNS record.
+func (c *DHTClient) ResolveADNLAddress(ctx context.Context, addr ton.Bits256) (ed25519.PublicKey, []string, error) {
+	value, err := c.FindValue(ctx, DhtKeyC{Id: tl.Int256(addr), Name: []byte("address")})
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := value.Key.Id.Ed25519()
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported key type: %v", value.Key.Id.SumType)
+	}
+	var list AdnlAddressListC
+	if err := unmarshalBoxed(value.Value, magicAdnlAddressList, &list); err != nil {
+		return nil, nil, fmt.Errorf("invalid address list: %w", err)
+	}
+	var hosts []string
+	for _, a := range list.Addrs {
+		switch a.SumType {
+		case "AdnlAddressUdp":
+			hosts = append(hosts, udpHost(a.AdnlAddressUdp.Ip, a.AdnlAddressUdp.Port))
+		case "AdnlAddressUdp6":
+			hosts = append(hosts, net.JoinHostPort(net.IP(a.AdnlAddressUdp6.Ip[:]).String(), strconv.Itoa(int(a.AdnlAddressUdp6.Port))))
+		}
+	}
+	return key, hosts, nil
+}
+
+// ResolveDNSRecord resolves an ADNL address stored in a dns_adnl_address record.
+func (c *DHTClient) ResolveDNSRecord(ctx context.Context, record tlb.DNSRecord) (ed25519.PublicKey, []string, error) {
+	if record.SumType != "DNSAdnlAddress" {
+		return nil, nil, fmt.Errorf("not an adnl address record: %v", record.SumType)
+	}
+	return c.ResolveADNLAddress(ctx, ton.Bits256(record.DNSAdnlAddress.Address))
+}
+
+// FindOverlayNodes looks up nodes of the given overlay announced in the DHT.
+// Signatures of the overlay nodes are not verified.
+func (c *DHTClient) FindOverlayNodes(ctx context.Context, overlay liteclient.OverlayID) ([]OverlayNodeC, error) {
+	id, err := overlay.ComputeShortID()
+	if err != nil {
+		return nil, err
+	}
+	value, err := c.FindValue(ctx, DhtKeyC{Id: id, Name: []byte("nodes")})
+	if err != nil {
+		return nil, err
+	}
+	var list OverlayNodesC
+	if err := unmarshalBoxed(value.Value, magicOverlayNodes, &list); err != nil {
+		return nil, fmt.Errorf("invalid overlay nodes: %w", err)
+	}
+	nodes := make([]OverlayNodeC, 0, len(list.Nodes))
+	for _, n := range list.Nodes {
+		if n.Overlay == id {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// lookup queries nodes which are the closest to the given ID, A nodes at a time,
+// until handle reports that the lookup is done or there are no more nodes to query among the K closest ones.
+// Nodes returned by handle are added to the routing table.
+func (c *DHTClient) lookup(ctx context.Context, id tl.Int256, query []byte, handle func(answer []byte) ([]DhtNodeC, bool, error)) error {
+	queried := map[tl.Int256]struct{}{}
+	for {
+		candidates := c.closestNodes(id, c.k)
+		var batch []dhtNode
+		for _, n := range candidates {
+			if _, ok := queried[n.id]; ok {
+				continue
+			}
+			queried[n.id] = struct{}{}
+			batch = append(batch, n)
+			if len(batch) == c.a {
+				break
+			}
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			done bool
+		)
+		for _, n := range batch {
+			wg.Add(1)
+			go func(n dhtNode) {
+				defer wg.Done()
+				answer, err := c.query(ctx, n, query)
+				if err != nil {
+					return
+				}
+				nodes, ok, err := handle(answer)
+				if err != nil {
+					return
+				}
+				c.addNodes(nodes)
+				if ok {
+					mu.Lock()
+					done = true
+					mu.Unlock()
+				}
+			}(n)
+		}
+		wg.Wait()
+		if done {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *DHTClient) query(ctx context.Context, n dhtNode, query []byte) ([]byte, error) {
+	peer, err := c.gateway.Peer(n.addr, n.key)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dhtQueryTimeout)
+	defer cancel()
+	return peer.Query(ctx, query)
+}
+
+func (c *DHTClient) closestNodes(id tl.Int256, k int) []dhtNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]tl.Int256, 0, len(c.nodes))
+	for nodeID := range c.nodes {
+		ids = append(ids, nodeID)
+	}
+	sortByDistance(ids, id)
+	nodes := make([]dhtNode, 0, min(len(ids), k))
+	for _, nodeID := range ids[:min(len(ids), k)] {
+		nodes = append(nodes, c.nodes[nodeID])
+	}
+	return nodes
+}
+
+// addNodes adds nodes with valid signatures and UDP addresses to the routing table.
+func (c *DHTClient) addNodes(nodes []DhtNodeC) {
+	for _, n := range nodes {
+		node, err := checkDhtNode(n)
+		if err != nil {
+			continue
+		}
+		c.mu.Lock()
+		c.nodes[node.id] = node
+		c.mu.Unlock()
+	}
+}
+
+func checkDhtNode(n DhtNodeC) (dhtNode, error) {
+	key, ok := n.Id.Ed25519()
+	if !ok {
+		return dhtNode{}, fmt.Errorf("unsupported key type: %v", n.Id.SumType)
+	}
+	signature := n.Signature
+	n.Signature = nil
+	b, err := marshalBoxed(magicDhtNode, n)
+	if err != nil {
+		return dhtNode{}, err
+	}
+	if !ed25519.Verify(key, b, signature) {
+		return dhtNode{}, fmt.Errorf("invalid dht node signature")
+	}
+	id, err := n.Id.ShortID()
+	if err != nil {
+		return dhtNode{}, err
+	}
+	for _, a := range n.AddrList.Addrs {
+		if a.SumType != "AdnlAddressUdp" {
+			continue
+		}
+		return dhtNode{id: id, key: key, addr: udpHost(a.AdnlAddressUdp.Ip, a.AdnlAddressUdp.Port)}, nil
+	}
+	return dhtNode{}, fmt.Errorf("dht node has no udp address")
+}
+
+// checkDhtValue verifies that the value is stored under the requested key, is not expired
+// and, for the signature update rule, is signed by the owner of the key.
+// An "address" value must always use the signature update rule,
+// otherwise anyone could redirect an ADNL address.
+func checkDhtValue(key DhtKeyC, value DhtValueC) error {
+	desc := value.Key
+	if desc.Key.Id != key.Id || !bytes.Equal(desc.Key.Name, key.Name) || desc.Key.Idx != key.Idx {
+		return fmt.Errorf("dht value key mismatch")
+	}
+	if int64(value.Ttl) < time.Now().Unix() {
+		return fmt.Errorf("dht value expired")
+	}
+	if desc.UpdateRule.SumType != "DhtUpdateRuleSignature" {
+		if string(key.Name) == "address" {
+			return fmt.Errorf("dht address value must be signed by its owner")
+		}
+		return nil
+	}
+	pub, ok := desc.Id.Ed25519()
+	if !ok {
+		return fmt.Errorf("unsupported key type: %v", desc.Id.SumType)
+	}
+	ownerID, err := desc.Id.ShortID()
+	if err != nil {
+		return err
+	}
+	if ownerID != key.Id {
+		return fmt.Errorf("dht key is not owned by its public key")
+	}
+	unsignedDesc := desc
+	unsignedDesc.Signature = nil
+	b, err := marshalBoxed(magicDhtKeyDescription, unsignedDesc)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, b, desc.Signature) {
+		return fmt.Errorf("invalid dht key description signature")
+	}
+	unsignedValue := value
+	unsignedValue.Signature = nil
+	b, err = DhtValue{unsignedValue}.MarshalTL()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, b, value.Signature) {
+		return fmt.Errorf("invalid dht value signature")
+	}
+	return nil
+}
+
+// udpHost converts adnl.address.udp to the "ip:port" form, ip is stored as a big-endian integer.
+func udpHost(ip uint32, port uint32) string {
+	return net.JoinHostPort(net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String(), strconv.Itoa(int(port)))
+}
+
+// sortByDistance sorts IDs by XOR distance to the given ID.
+func sortByDistance(ids []tl.Int256, to tl.Int256) {
+	sort.Slice(ids, func(i, j int) bool {
+		for k := range to {
+			a, b := ids[i][k]^to[k], ids[j][k]^to[k]
+			if a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+}
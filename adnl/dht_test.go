@@ -0,0 +1,144 @@
+package adnl
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func signedAddressValue(t *testing.T, key ed25519.PrivateKey, list AdnlAddressListC) DhtValueC {
+	pub := PublicKeyEd25519(key.Public().(ed25519.PublicKey))
+	id, err := pub.ShortID()
+	if err != nil {
+		t.Fatalf("ShortID() failed: %v", err)
+	}
+	var rule DhtUpdateRule
+	rule.SumType = "DhtUpdateRuleSignature"
+	desc := DhtKeyDescriptionC{
+		Key:        DhtKeyC{Id: id, Name: []byte("address")},
+		Id:         pub,
+		UpdateRule: rule,
+	}
+	b, err := marshalBoxed(magicDhtKeyDescription, desc)
+	if err != nil {
+		t.Fatalf("marshalBoxed() failed: %v", err)
+	}
+	desc.Signature = ed25519.Sign(key, b)
+	value, err := marshalBoxed(magicAdnlAddressList, list)
+	if err != nil {
+		t.Fatalf("marshalBoxed() failed: %v", err)
+	}
+	v := DhtValueC{Key: desc, Value: value, Ttl: uint32(time.Now().Add(time.Hour).Unix())}
+	b, err = DhtValue{v}.MarshalTL()
+	if err != nil {
+		t.Fatalf("MarshalTL() failed: %v", err)
+	}
+	v.Signature = ed25519.Sign(key, b)
+	return v
+}
+
+func TestDHTClient_ResolveADNLAddress(t *testing.T) {
+	_, owner, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	var udp AdnlAddress
+	udp.SumType = "AdnlAddressUdp"
+	udp.AdnlAddressUdp.Ip = 0x7f000001
+	udp.AdnlAddressUdp.Port = 30303
+	value := signedAddressValue(t, owner, AdnlAddressListC{Addrs: []AdnlAddress{udp}})
+	ownerID, err := PublicKeyEd25519(owner.Public().(ed25519.PublicKey)).ShortID()
+	if err != nil {
+		t.Fatalf("ShortID() failed: %v", err)
+	}
+	keyID, err := KeyID(DhtKeyC{Id: ownerID, Name: []byte("address")})
+	if err != nil {
+		t.Fatalf("KeyID() failed: %v", err)
+	}
+
+	node := newTestGateway(t)
+	node.SetQueryHandler(func(peer *Peer, query []byte) ([]byte, error) {
+		var req DhtFindValueC
+		if err := unmarshalBoxed(query, magicDhtFindValue, &req); err != nil {
+			return nil, err
+		}
+		var res DhtValueResult
+		if req.Key == keyID {
+			res.SumType = "DhtValueFound"
+			res.DhtValueFound.Value = DhtValue{value}
+		} else {
+			res.SumType = "DhtValueNotFound"
+		}
+		return tl.Marshal(res)
+	})
+	client, err := NewDHTClient(newTestGateway(t), config.DHTConfig{
+		StaticNodes: []config.DHTNode{{
+			Key:   base64.StdEncoding.EncodeToString(node.key.Public().(ed25519.PublicKey)),
+			Hosts: []string{node.Addr().String()},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewDHTClient() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, hosts, err := client.ResolveADNLAddress(ctx, ton.Bits256(ownerID))
+	if err != nil {
+		t.Fatalf("ResolveADNLAddress() failed: %v", err)
+	}
+	if !key.Equal(owner.Public()) {
+		t.Fatalf("unexpected key: %x", key)
+	}
+	if want := net.JoinHostPort("127.0.0.1", "30303"); len(hosts) != 1 || hosts[0] != want {
+		t.Fatalf("want %v, got %v", want, hosts)
+	}
+
+	_, _, err = client.ResolveADNLAddress(ctx, ton.Bits256{1})
+	if !errors.Is(err, ErrDhtValueNotFound) {
+		t.Fatalf("want ErrDhtValueNotFound, got %v", err)
+	}
+}
+
+func TestCheckDhtValue_address(t *testing.T) {
+	_, owner, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	_, attacker, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	ownerID, err := PublicKeyEd25519(owner.Public().(ed25519.PublicKey)).ShortID()
+	if err != nil {
+		t.Fatalf("ShortID() failed: %v", err)
+	}
+	key := DhtKeyC{Id: ownerID, Name: []byte("address")}
+	value := signedAddressValue(t, owner, AdnlAddressListC{})
+	if err := checkDhtValue(key, value); err != nil {
+		t.Fatalf("checkDhtValue() failed: %v", err)
+	}
+
+	unsigned := value
+	unsigned.Key.UpdateRule.SumType = "DhtUpdateRuleAnybody"
+	unsigned.Key.Signature = nil
+	unsigned.Signature = nil
+	if err := checkDhtValue(key, unsigned); err == nil {
+		t.Fatalf("unsigned address value must be rejected")
+	}
+
+	forged := signedAddressValue(t, attacker, AdnlAddressListC{})
+	forged.Key.Key.Id = ownerID
+	if err := checkDhtValue(key, forged); err == nil {
+		t.Fatalf("address value signed by another key must be rejected")
+	}
+}
@@ -0,0 +1,585 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	magicPacketContents = 0xd142cd89 // crc32("adnl.packetContents rand1:bytes flags:# ... rand2:bytes = adnl.PacketContents")
+
+	// maxPacketSize is a maximum size of a UDP datagram accepted by the gateway.
+	maxPacketSize = 64 << 10
+	// maxMessageSize is a maximum size of a serialized message sent in one packet,
+	// larger messages are split into adnl.message.part.
+	maxMessageSize = 1024
+	// maxPartialMessageSize limits the size of a message assembled from parts.
+	maxPartialMessageSize = 16 << 20
+	// partialMessageTTL is a time to wait for all parts of a message.
+	partialMessageTTL = 10 * time.Second
+)
+
+var (
+	ErrGatewayClosed = errors.New("adnl: gateway closed")
+)
+
+// QueryHandler processes adnl.message.query received from a peer and returns an answer.
+type QueryHandler func(peer *Peer, query []byte) ([]byte, error)
+
+// CustomHandler processes adnl.message.custom received from a peer.
+type CustomHandler func(peer *Peer, data []byte)
+
+// Gateway sends and receives ADNL packets over UDP.
+//
+// Packets are encrypted with one-time keys and signed with the gateway key,
+// ADNL channels are not supported.
+type Gateway struct {
+	key        ed25519.PrivateKey
+	id         tl.Int256
+	reinitDate uint32
+	conn       net.PacketConn
+
+	mu            sync.Mutex
+	peers         map[tl.Int256]*Peer
+	queries       map[tl.Int256]chan []byte
+	parts         map[tl.Int256]*partialMessage
+	queryHandler  QueryHandler
+	customHandler CustomHandler
+	overlays      map[tl.Int256]*Overlay
+	addrs         []AdnlAddress
+	closed        chan struct{}
+	closeOnce     sync.Once
+}
+
+type partialMessage struct {
+	data     []byte
+	received int
+	// offsets are offsets of the received parts, a repeated part is ignored.
+	offsets   map[uint32]struct{}
+	expiresAt time.Time
+}
+
+// NewGateway returns a gateway identified by the given key.
+// If the key is nil, a random one is generated.
+func NewGateway(key ed25519.PrivateKey) (*Gateway, error) {
+	if key == nil {
+		var err error
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	address, err := liteclient.NewAddress(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	return &Gateway{
+		key:        key,
+		id:         tl.Int256(address.ShortID()),
+		reinitDate: uint32(time.Now().Unix()),
+		peers:      map[tl.Int256]*Peer{},
+		queries:    map[tl.Int256]chan []byte{},
+		parts:      map[tl.Int256]*partialMessage{},
+		overlays:   map[tl.Int256]*Overlay{},
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+// Listen opens a UDP socket on the given address, like ":0", and starts receiving packets.
+func (g *Gateway) Listen(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.conn = conn
+	g.mu.Unlock()
+	go g.reader(conn)
+	return nil
+}
+
+// Close closes the socket of the gateway, queries that are in flight fail.
+func (g *Gateway) Close() {
+	g.closeOnce.Do(func() {
+		close(g.closed)
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.conn != nil {
+			g.conn.Close()
+		}
+	})
+}
+
+// ID returns an ADNL address of the gateway.
+func (g *Gateway) ID() tl.Int256 {
+	return g.id
+}
+
+// Addr returns a local address of the gateway socket.
+func (g *Gateway) Addr() net.Addr {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.conn == nil {
+		return nil
+	}
+	return g.conn.LocalAddr()
+}
+
+// SetExternalAddress sets a public IPv4 address of the gateway, like "1.2.3.4:30303",
+// which is sent to peers, so they can send messages to the gateway, for example, overlay broadcasts.
+func (g *Gateway) SetExternalAddress(addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return err
+	}
+	ip := udpAddr.IP.To4()
+	if ip == nil {
+		return fmt.Errorf("not an ipv4 address: %v", addr)
+	}
+	var a AdnlAddress
+	a.SumType = "AdnlAddressUdp"
+	a.AdnlAddressUdp.Ip = binary.BigEndian.Uint32(ip)
+	a.AdnlAddressUdp.Port = uint32(udpAddr.Port)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.addrs = []AdnlAddress{a}
+	return nil
+}
+
+func (g *Gateway) SetQueryHandler(handler QueryHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.queryHandler = handler
+}
+
+func (g *Gateway) SetCustomHandler(handler CustomHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.customHandler = handler
+}
+
+// Peer returns a peer with the given key reachable at the given UDP address.
+func (g *Gateway) Peer(addr string, key ed25519.PublicKey) (*Peer, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return g.peer(udpAddr, key)
+}
+
+func (g *Gateway) peer(addr *net.UDPAddr, key ed25519.PublicKey) (*Peer, error) {
+	id, err := PublicKeyEd25519(key).ShortID()
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, ok := g.peers[id]; ok {
+		p.setAddr(addr)
+		return p, nil
+	}
+	p := &Peer{gateway: g, id: id, key: key, addr: addr}
+	g.peers[id] = p
+	return p, nil
+}
+
+func (g *Gateway) write(b []byte, addr net.Addr) error {
+	g.mu.Lock()
+	conn := g.conn
+	g.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("adnl: gateway is not listening")
+	}
+	_, err := conn.WriteTo(b, addr)
+	return err
+}
+
+func (g *Gateway) reader(conn net.PacketConn) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-g.closed:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		if err := g.processPacket(udpAddr, bytes.Clone(buf[:n])); err != nil {
+			slog.Debug("adnl: failed to process packet", "from", addr.String(), "error", err.Error())
+		}
+	}
+}
+
+func (g *Gateway) processPacket(addr *net.UDPAddr, b []byte) error {
+	if len(b) < 96 || !bytes.Equal(b[:32], g.id[:]) {
+		// packets sent to ADNL channels are not supported.
+		return fmt.Errorf("unknown destination")
+	}
+	data, err := decrypt(g.key, b)
+	if err != nil {
+		return err
+	}
+	var packet AdnlPacketContentsC
+	if err := unmarshalBoxed(data, magicPacketContents, &packet); err != nil {
+		return err
+	}
+	peer, err := g.packetPeer(addr, packet)
+	if err != nil {
+		return err
+	}
+	peer.received(packet)
+	if packet.Message != nil {
+		g.processMessage(peer, *packet.Message)
+	}
+	for _, msg := range packet.Messages {
+		g.processMessage(peer, msg)
+	}
+	return nil
+}
+
+// packetPeer verifies a signature of the packet and returns its sender.
+func (g *Gateway) packetPeer(addr *net.UDPAddr, packet AdnlPacketContentsC) (*Peer, error) {
+	if packet.From == nil {
+		if packet.FromShort == nil {
+			return nil, fmt.Errorf("unknown sender")
+		}
+		g.mu.Lock()
+		peer, ok := g.peers[packet.FromShort.Id]
+		g.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown sender")
+		}
+		return peer, nil
+	}
+	key, ok := packet.From.Ed25519()
+	if !ok {
+		return nil, fmt.Errorf("unsupported sender key")
+	}
+	if len(packet.Signature) == 0 {
+		return nil, fmt.Errorf("packet is not signed")
+	}
+	signature := packet.Signature
+	packet.Flags &^= 1 << 11
+	packet.Signature = nil
+	signed, err := marshalBoxed(magicPacketContents, packet)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(key, signed, signature) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	return g.peer(addr, key)
+}
+
+func (g *Gateway) processMessage(peer *Peer, msg AdnlMessage) {
+	switch msg.SumType {
+	case "AdnlMessageAnswer":
+		g.mu.Lock()
+		ch, ok := g.queries[msg.AdnlMessageAnswer.QueryId]
+		delete(g.queries, msg.AdnlMessageAnswer.QueryId)
+		g.mu.Unlock()
+		if ok {
+			ch <- msg.AdnlMessageAnswer.Answer
+		}
+	case "AdnlMessageQuery":
+		g.mu.Lock()
+		handler := g.queryHandler
+		g.mu.Unlock()
+		if overlay, query, ok := g.overlay(msg.AdnlMessageQuery.Query, magicOverlayQuery); ok {
+			handler = func(peer *Peer, _ []byte) ([]byte, error) {
+				return overlay.processQuery(peer, query)
+			}
+		}
+		if handler == nil {
+			return
+		}
+		go func() {
+			answer, err := handler(peer, msg.AdnlMessageQuery.Query)
+			if err != nil {
+				return
+			}
+			var resp AdnlMessage
+			resp.SumType = "AdnlMessageAnswer"
+			resp.AdnlMessageAnswer.QueryId = msg.AdnlMessageQuery.QueryId
+			resp.AdnlMessageAnswer.Answer = answer
+			if err := peer.send(resp); err != nil {
+				slog.Debug("adnl: failed to send answer", "error", err.Error())
+			}
+		}()
+	case "AdnlMessageCustom":
+		if overlay, data, ok := g.overlay(msg.AdnlMessageCustom.Data, magicOverlayMessage); ok {
+			overlay.processMessage(peer, data)
+			return
+		}
+		g.mu.Lock()
+		handler := g.customHandler
+		g.mu.Unlock()
+		if handler != nil {
+			handler(peer, msg.AdnlMessageCustom.Data)
+		}
+	case "AdnlMessagePart":
+		if data, ok := g.processPart(msg); ok {
+			var full AdnlMessage
+			if err := tl.Unmarshal(bytes.NewReader(data), &full); err == nil && full.SumType != "AdnlMessagePart" {
+				g.processMessage(peer, full)
+			}
+		}
+	}
+}
+
+// overlay returns an overlay which a message prefixed with overlay.query or overlay.message is sent to.
+func (g *Gateway) overlay(b []byte, tag uint32) (*Overlay, []byte, bool) {
+	if len(b) < 36 || binary.LittleEndian.Uint32(b[:4]) != tag {
+		return nil, nil, false
+	}
+	var id tl.Int256
+	copy(id[:], b[4:36])
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	overlay, ok := g.overlays[id]
+	return overlay, b[36:], ok
+}
+
+// processPart stores a part of a message and returns the message once all its parts are received.
+func (g *Gateway) processPart(msg AdnlMessage) ([]byte, bool) {
+	part := msg.AdnlMessagePart
+	size := int(part.TotalSize)
+	if size > maxPartialMessageSize || int(part.Offset)+len(part.Data) > size {
+		return nil, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	for hash, p := range g.parts {
+		if now.After(p.expiresAt) {
+			delete(g.parts, hash)
+		}
+	}
+	p, ok := g.parts[part.Hash]
+	if !ok {
+		p = &partialMessage{data: make([]byte, size), offsets: map[uint32]struct{}{}, expiresAt: now.Add(partialMessageTTL)}
+		g.parts[part.Hash] = p
+	}
+	if len(p.data) != size {
+		return nil, false
+	}
+	if _, ok := p.offsets[part.Offset]; ok {
+		return nil, false
+	}
+	p.offsets[part.Offset] = struct{}{}
+	copy(p.data[part.Offset:], part.Data)
+	p.received += len(part.Data)
+	if p.received < size {
+		return nil, false
+	}
+	delete(g.parts, part.Hash)
+	if tl.Int256(sha256.Sum256(p.data)) != part.Hash {
+		return nil, false
+	}
+	return p.data, true
+}
+
+func (g *Gateway) registerQuery(id tl.Int256) chan []byte {
+	ch := make(chan []byte, 1)
+	g.mu.Lock()
+	g.queries[id] = ch
+	g.mu.Unlock()
+	return ch
+}
+
+func (g *Gateway) unregisterQuery(id tl.Int256) {
+	g.mu.Lock()
+	delete(g.queries, id)
+	g.mu.Unlock()
+}
+
+// Peer is a remote ADNL node.
+type Peer struct {
+	gateway *Gateway
+	id      tl.Int256
+	key     ed25519.PublicKey
+
+	mu   sync.Mutex
+	addr *net.UDPAddr
+	// seqno is a sequence number of the last packet sent to the peer.
+	seqno uint64
+	// confirmSeqno is the highest sequence number of a packet received from the peer.
+	confirmSeqno uint64
+	// reinitDate is a reinit date of the peer.
+	reinitDate uint32
+}
+
+// ID returns an ADNL address of the peer.
+func (p *Peer) ID() tl.Int256 {
+	return p.id
+}
+
+func (p *Peer) Key() ed25519.PublicKey {
+	return p.key
+}
+
+func (p *Peer) Addr() *net.UDPAddr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.addr
+}
+
+func (p *Peer) setAddr(addr *net.UDPAddr) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addr = addr
+}
+
+func (p *Peer) received(packet AdnlPacketContentsC) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if packet.Seqno != nil && *packet.Seqno > p.confirmSeqno {
+		p.confirmSeqno = *packet.Seqno
+	}
+	if packet.ReinitDate != nil && *packet.ReinitDate > p.reinitDate {
+		p.reinitDate = *packet.ReinitDate
+	}
+}
+
+// Query sends adnl.message.query to the peer and waits for an answer.
+func (p *Peer) Query(ctx context.Context, query []byte) ([]byte, error) {
+	var msg AdnlMessage
+	msg.SumType = "AdnlMessageQuery"
+	if _, err := rand.Read(msg.AdnlMessageQuery.QueryId[:]); err != nil {
+		return nil, err
+	}
+	msg.AdnlMessageQuery.Query = query
+	id := msg.AdnlMessageQuery.QueryId
+	ch := p.gateway.registerQuery(id)
+	defer p.gateway.unregisterQuery(id)
+	if err := p.send(msg); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.gateway.closed:
+		return nil, ErrGatewayClosed
+	case answer := <-ch:
+		return answer, nil
+	}
+}
+
+// SendCustom sends adnl.message.custom to the peer.
+func (p *Peer) SendCustom(data []byte) error {
+	var msg AdnlMessage
+	msg.SumType = "AdnlMessageCustom"
+	msg.AdnlMessageCustom.Data = data
+	return p.send(msg)
+}
+
+// send sends the message in one packet or splits it into parts if it is too large.
+func (p *Peer) send(msg AdnlMessage) error {
+	b, err := tl.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(b) <= maxMessageSize {
+		return p.sendPacket(msg)
+	}
+	hash := tl.Int256(sha256.Sum256(b))
+	for offset := 0; offset < len(b); offset += maxMessageSize {
+		var part AdnlMessage
+		part.SumType = "AdnlMessagePart"
+		part.AdnlMessagePart.Hash = hash
+		part.AdnlMessagePart.TotalSize = uint32(len(b))
+		part.AdnlMessagePart.Offset = uint32(offset)
+		part.AdnlMessagePart.Data = b[offset:min(offset+maxMessageSize, len(b))]
+		if err := p.sendPacket(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Peer) sendPacket(msg AdnlMessage) error {
+	p.gateway.mu.Lock()
+	addrs := p.gateway.addrs
+	p.gateway.mu.Unlock()
+	p.mu.Lock()
+	p.seqno++
+	from := PublicKeyEd25519(p.gateway.key.Public().(ed25519.PublicKey))
+	seqno, confirmSeqno := p.seqno, p.confirmSeqno
+	reinitDate, dstReinitDate := p.gateway.reinitDate, p.reinitDate
+	packet := AdnlPacketContentsC{
+		Rand1:         randomBytes(),
+		Flags:         1 | 1<<2 | 1<<4 | 1<<6 | 1<<7 | 1<<10,
+		From:          &from,
+		Message:       &msg,
+		Address:       &AdnlAddressListC{Addrs: addrs, Version: reinitDate, ReinitDate: reinitDate},
+		Seqno:         &seqno,
+		ConfirmSeqno:  &confirmSeqno,
+		ReinitDate:    &reinitDate,
+		DstReinitDate: &dstReinitDate,
+		Rand2:         randomBytes(),
+	}
+	addr := p.addr
+	p.mu.Unlock()
+
+	signed, err := marshalBoxed(magicPacketContents, packet)
+	if err != nil {
+		return err
+	}
+	packet.Flags |= 1 << 11
+	packet.Signature = ed25519.Sign(p.gateway.key, signed)
+	data, err := marshalBoxed(magicPacketContents, packet)
+	if err != nil {
+		return err
+	}
+	b, err := encrypt(p.id, p.key, data)
+	if err != nil {
+		return err
+	}
+	return p.gateway.write(b, addr)
+}
+
+// randomBytes returns 7 or 15 random bytes, so they take 8 or 16 bytes in TL.
+func randomBytes() []byte {
+	var n [1]byte
+	rand.Read(n[:])
+	b := make([]byte, 7+int(n[0]&1)*8)
+	rand.Read(b)
+	return b
+}
+
+func marshalBoxed(tag uint32, v any) ([]byte, error) {
+	b, err := tl.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, tag), b...), nil
+}
+
+func unmarshalBoxed(b []byte, tag uint32, v any) error {
+	if len(b) < 4 || binary.LittleEndian.Uint32(b[:4]) != tag {
+		return fmt.Errorf("invalid tag")
+	}
+	return tl.Unmarshal(bytes.NewReader(b[4:]), v)
+}
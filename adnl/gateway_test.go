@@ -0,0 +1,104 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+func newTestGateway(t *testing.T) *Gateway {
+	g, err := NewGateway(nil)
+	if err != nil {
+		t.Fatalf("NewGateway() failed: %v", err)
+	}
+	if err := g.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(g.Close)
+	return g
+}
+
+func TestGateway(t *testing.T) {
+	server := newTestGateway(t)
+	client := newTestGateway(t)
+
+	server.SetQueryHandler(func(peer *Peer, query []byte) ([]byte, error) {
+		if peer.ID() != client.ID() {
+			t.Errorf("unexpected peer: %x", peer.ID())
+		}
+		return append([]byte("answer:"), query...), nil
+	})
+	custom := make(chan []byte, 1)
+	server.SetCustomHandler(func(peer *Peer, data []byte) {
+		custom <- data
+	})
+
+	peer, err := client.Peer(server.Addr().String(), server.key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatalf("Peer() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name  string
+		query []byte
+	}{
+		{name: "small query", query: []byte("ping")},
+		{name: "query split into parts", query: bytes.Repeat([]byte{1, 2, 3}, 3*maxMessageSize)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, err := peer.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() failed: %v", err)
+			}
+			if want := append([]byte("answer:"), tt.query...); !bytes.Equal(answer, want) {
+				t.Fatalf("unexpected answer of %v bytes", len(answer))
+			}
+		})
+	}
+
+	if err := peer.SendCustom([]byte("custom")); err != nil {
+		t.Fatalf("SendCustom() failed: %v", err)
+	}
+	select {
+	case data := <-custom:
+		if string(data) != "custom" {
+			t.Fatalf("want custom, got %s", data)
+		}
+	case <-ctx.Done():
+		t.Fatalf("custom message was not received")
+	}
+}
+
+func TestGateway_processPartRepeated(t *testing.T) {
+	g := newTestGateway(t)
+	data := []byte("message split into two parts")
+	hash := tl.Int256(sha256.Sum256(data))
+	part := func(offset int, chunk []byte) AdnlMessage {
+		var msg AdnlMessage
+		msg.SumType = "AdnlMessagePart"
+		msg.AdnlMessagePart.Hash = hash
+		msg.AdnlMessagePart.TotalSize = uint32(len(data))
+		msg.AdnlMessagePart.Offset = uint32(offset)
+		msg.AdnlMessagePart.Data = chunk
+		return msg
+	}
+	first := part(0, data[:len(data)/2])
+	if _, ok := g.processPart(first); ok {
+		t.Fatalf("message must not be complete after the first part")
+	}
+	if _, ok := g.processPart(first); ok {
+		t.Fatalf("a repeated part must not complete the message")
+	}
+	got, ok := g.processPart(part(len(data)/2, data[len(data)/2:]))
+	if !ok || !bytes.Equal(got, data) {
+		t.Fatalf("want the whole message, got %q", got)
+	}
+}
@@ -0,0 +1,3052 @@
+// Code generated - DO NOT EDIT.
+
+package adnl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/tonkeeper/tongo/tl"
+	"io"
+)
+
+type PublicKey struct {
+	tl.SumType
+	PubUnenc struct {
+		Data []byte
+	}
+	PubEd25519 struct {
+		Key tl.Int256
+	}
+	PubAes struct {
+		Key tl.Int256
+	}
+	PubOverlay struct {
+		Name []byte
+	}
+}
+
+func (t PublicKey) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "PubUnenc":
+		b, err = tl.Marshal(uint32(0xb61f450a))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubUnenc.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "PubEd25519":
+		b, err = tl.Marshal(uint32(0x4813b4c6))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubEd25519.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "PubAes":
+		b, err = tl.Marshal(uint32(0x2dbcadd4))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubAes.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "PubOverlay":
+		b, err = tl.Marshal(uint32(0x34ba45cb))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.PubOverlay.Name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *PublicKey) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xb61f450a:
+		t.SumType = "PubUnenc"
+		err = tl.Unmarshal(r, &t.PubUnenc.Data)
+		if err != nil {
+			return err
+		}
+	case 0x4813b4c6:
+		t.SumType = "PubEd25519"
+		err = tl.Unmarshal(r, &t.PubEd25519.Key)
+		if err != nil {
+			return err
+		}
+	case 0x2dbcadd4:
+		t.SumType = "PubAes"
+		err = tl.Unmarshal(r, &t.PubAes.Key)
+		if err != nil {
+			return err
+		}
+	case 0x34ba45cb:
+		t.SumType = "PubOverlay"
+		err = tl.Unmarshal(r, &t.PubOverlay.Name)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type AdnlIdShortC struct {
+	Id tl.Int256
+}
+
+func (t AdnlIdShortC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlIdShortC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type AdnlAddress struct {
+	tl.SumType
+	AdnlAddressUdp struct {
+		Ip   uint32
+		Port uint32
+	}
+	AdnlAddressUdp6 struct {
+		Ip   tl.Int128
+		Port uint32
+	}
+	AdnlAddressTunnel struct {
+		To     tl.Int256
+		Pubkey PublicKey
+	}
+}
+
+func (t AdnlAddress) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "AdnlAddressUdp":
+		b, err = tl.Marshal(uint32(0x670da6e7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlAddressUdp.Ip)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlAddressUdp.Port)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlAddressUdp6":
+		b, err = tl.Marshal(uint32(0xe31d63fa))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlAddressUdp6.Ip)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlAddressUdp6.Port)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlAddressTunnel":
+		b, err = tl.Marshal(uint32(0x92b02eb))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlAddressTunnel.To)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlAddressTunnel.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlAddress) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x670da6e7:
+		t.SumType = "AdnlAddressUdp"
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp.Ip)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp.Port)
+		if err != nil {
+			return err
+		}
+	case 0xe31d63fa:
+		t.SumType = "AdnlAddressUdp6"
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp6.Ip)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlAddressUdp6.Port)
+		if err != nil {
+			return err
+		}
+	case 0x92b02eb:
+		t.SumType = "AdnlAddressTunnel"
+		err = tl.Unmarshal(r, &t.AdnlAddressTunnel.To)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlAddressTunnel.Pubkey)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type AdnlAddressListC struct {
+	Addrs      []AdnlAddress
+	Version    uint32
+	ReinitDate uint32
+	Priority   uint32
+	ExpireAt   uint32
+}
+
+func (t AdnlAddressListC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Addrs)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.ReinitDate)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Priority)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.ExpireAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlAddressListC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Addrs)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.ReinitDate)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Priority)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.ExpireAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type AdnlMessage struct {
+	tl.SumType
+	AdnlMessageCreateChannel struct {
+		Key  tl.Int256
+		Date uint32
+	}
+	AdnlMessageConfirmChannel struct {
+		Key     tl.Int256
+		PeerKey tl.Int256
+		Date    uint32
+	}
+	AdnlMessageCustom struct {
+		Data []byte
+	}
+	AdnlMessageNop    struct{}
+	AdnlMessageReinit struct {
+		Date uint32
+	}
+	AdnlMessageQuery struct {
+		QueryId tl.Int256
+		Query   []byte
+	}
+	AdnlMessageAnswer struct {
+		QueryId tl.Int256
+		Answer  []byte
+	}
+	AdnlMessagePart struct {
+		Hash      tl.Int256
+		TotalSize uint32
+		Offset    uint32
+		Data      []byte
+	}
+}
+
+func (t AdnlMessage) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "AdnlMessageCreateChannel":
+		b, err = tl.Marshal(uint32(0xe673c3bb))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageCreateChannel.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageCreateChannel.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageConfirmChannel":
+		b, err = tl.Marshal(uint32(0x60dd1d69))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageConfirmChannel.Key)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageConfirmChannel.PeerKey)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageConfirmChannel.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageCustom":
+		b, err = tl.Marshal(uint32(0x204818f5))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageCustom.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageNop":
+		b, err = tl.Marshal(uint32(0x17f8dfda))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	case "AdnlMessageReinit":
+		b, err = tl.Marshal(uint32(0x10c20520))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageReinit.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageQuery":
+		b, err = tl.Marshal(uint32(0xb48bf97a))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageQuery.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageQuery.Query)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessageAnswer":
+		b, err = tl.Marshal(uint32(0xfac8416))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessageAnswer.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessageAnswer.Answer)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "AdnlMessagePart":
+		b, err = tl.Marshal(uint32(0xfd452d39))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.AdnlMessagePart.Hash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessagePart.TotalSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessagePart.Offset)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.AdnlMessagePart.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlMessage) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xe673c3bb:
+		t.SumType = "AdnlMessageCreateChannel"
+		err = tl.Unmarshal(r, &t.AdnlMessageCreateChannel.Key)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageCreateChannel.Date)
+		if err != nil {
+			return err
+		}
+	case 0x60dd1d69:
+		t.SumType = "AdnlMessageConfirmChannel"
+		err = tl.Unmarshal(r, &t.AdnlMessageConfirmChannel.Key)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageConfirmChannel.PeerKey)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageConfirmChannel.Date)
+		if err != nil {
+			return err
+		}
+	case 0x204818f5:
+		t.SumType = "AdnlMessageCustom"
+		err = tl.Unmarshal(r, &t.AdnlMessageCustom.Data)
+		if err != nil {
+			return err
+		}
+	case 0x17f8dfda:
+		t.SumType = "AdnlMessageNop"
+	case 0x10c20520:
+		t.SumType = "AdnlMessageReinit"
+		err = tl.Unmarshal(r, &t.AdnlMessageReinit.Date)
+		if err != nil {
+			return err
+		}
+	case 0xb48bf97a:
+		t.SumType = "AdnlMessageQuery"
+		err = tl.Unmarshal(r, &t.AdnlMessageQuery.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageQuery.Query)
+		if err != nil {
+			return err
+		}
+	case 0xfac8416:
+		t.SumType = "AdnlMessageAnswer"
+		err = tl.Unmarshal(r, &t.AdnlMessageAnswer.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessageAnswer.Answer)
+		if err != nil {
+			return err
+		}
+	case 0xfd452d39:
+		t.SumType = "AdnlMessagePart"
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.Hash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.TotalSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.Offset)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.AdnlMessagePart.Data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type AdnlPacketContentsC struct {
+	Rand1                       []byte
+	Flags                       uint32
+	From                        *PublicKey
+	FromShort                   *AdnlIdShortC
+	Message                     *AdnlMessage
+	Messages                    []AdnlMessage
+	Address                     *AdnlAddressListC
+	PriorityAddress             *AdnlAddressListC
+	Seqno                       *uint64
+	ConfirmSeqno                *uint64
+	RecvAddrListVersion         *uint32
+	RecvPriorityAddrListVersion *uint32
+	ReinitDate                  *uint32
+	DstReinitDate               *uint32
+	Signature                   []byte
+	Rand2                       []byte
+}
+
+func (t AdnlPacketContentsC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Rand1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Flags)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	if (t.Flags>>0)&1 == 1 {
+		b, err = tl.Marshal(t.From)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>1)&1 == 1 {
+		b, err = tl.Marshal(t.FromShort)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>2)&1 == 1 {
+		b, err = tl.Marshal(t.Message)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>3)&1 == 1 {
+		b, err = tl.Marshal(t.Messages)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>4)&1 == 1 {
+		b, err = tl.Marshal(t.Address)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>5)&1 == 1 {
+		b, err = tl.Marshal(t.PriorityAddress)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>6)&1 == 1 {
+		b, err = tl.Marshal(t.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>7)&1 == 1 {
+		b, err = tl.Marshal(t.ConfirmSeqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>8)&1 == 1 {
+		b, err = tl.Marshal(t.RecvAddrListVersion)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>9)&1 == 1 {
+		b, err = tl.Marshal(t.RecvPriorityAddrListVersion)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>10)&1 == 1 {
+		b, err = tl.Marshal(t.ReinitDate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>10)&1 == 1 {
+		b, err = tl.Marshal(t.DstReinitDate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if (t.Flags>>11)&1 == 1 {
+		b, err = tl.Marshal(t.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b, err = tl.Marshal(t.Rand2)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *AdnlPacketContentsC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Rand1)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Flags)
+	if err != nil {
+		return err
+	}
+	if (t.Flags>>0)&1 == 1 {
+		var tempFrom PublicKey
+		err = tl.Unmarshal(r, &tempFrom)
+		if err != nil {
+			return err
+		}
+		t.From = &tempFrom
+	}
+	if (t.Flags>>1)&1 == 1 {
+		var tempFromShort AdnlIdShortC
+		err = tl.Unmarshal(r, &tempFromShort)
+		if err != nil {
+			return err
+		}
+		t.FromShort = &tempFromShort
+	}
+	if (t.Flags>>2)&1 == 1 {
+		var tempMessage AdnlMessage
+		err = tl.Unmarshal(r, &tempMessage)
+		if err != nil {
+			return err
+		}
+		t.Message = &tempMessage
+	}
+	if (t.Flags>>3)&1 == 1 {
+		var tempMessages []AdnlMessage
+		err = tl.Unmarshal(r, &tempMessages)
+		if err != nil {
+			return err
+		}
+		t.Messages = tempMessages
+	}
+	if (t.Flags>>4)&1 == 1 {
+		var tempAddress AdnlAddressListC
+		err = tl.Unmarshal(r, &tempAddress)
+		if err != nil {
+			return err
+		}
+		t.Address = &tempAddress
+	}
+	if (t.Flags>>5)&1 == 1 {
+		var tempPriorityAddress AdnlAddressListC
+		err = tl.Unmarshal(r, &tempPriorityAddress)
+		if err != nil {
+			return err
+		}
+		t.PriorityAddress = &tempPriorityAddress
+	}
+	if (t.Flags>>6)&1 == 1 {
+		var tempSeqno uint64
+		err = tl.Unmarshal(r, &tempSeqno)
+		if err != nil {
+			return err
+		}
+		t.Seqno = &tempSeqno
+	}
+	if (t.Flags>>7)&1 == 1 {
+		var tempConfirmSeqno uint64
+		err = tl.Unmarshal(r, &tempConfirmSeqno)
+		if err != nil {
+			return err
+		}
+		t.ConfirmSeqno = &tempConfirmSeqno
+	}
+	if (t.Flags>>8)&1 == 1 {
+		var tempRecvAddrListVersion uint32
+		err = tl.Unmarshal(r, &tempRecvAddrListVersion)
+		if err != nil {
+			return err
+		}
+		t.RecvAddrListVersion = &tempRecvAddrListVersion
+	}
+	if (t.Flags>>9)&1 == 1 {
+		var tempRecvPriorityAddrListVersion uint32
+		err = tl.Unmarshal(r, &tempRecvPriorityAddrListVersion)
+		if err != nil {
+			return err
+		}
+		t.RecvPriorityAddrListVersion = &tempRecvPriorityAddrListVersion
+	}
+	if (t.Flags>>10)&1 == 1 {
+		var tempReinitDate uint32
+		err = tl.Unmarshal(r, &tempReinitDate)
+		if err != nil {
+			return err
+		}
+		t.ReinitDate = &tempReinitDate
+	}
+	if (t.Flags>>10)&1 == 1 {
+		var tempDstReinitDate uint32
+		err = tl.Unmarshal(r, &tempDstReinitDate)
+		if err != nil {
+			return err
+		}
+		t.DstReinitDate = &tempDstReinitDate
+	}
+	if (t.Flags>>11)&1 == 1 {
+		var tempSignature []byte
+		err = tl.Unmarshal(r, &tempSignature)
+		if err != nil {
+			return err
+		}
+		t.Signature = tempSignature
+	}
+	err = tl.Unmarshal(r, &t.Rand2)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtNodeC struct {
+	Id        PublicKey
+	AddrList  AdnlAddressListC
+	Version   uint32
+	Signature []byte
+}
+
+func (t DhtNodeC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.AddrList)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtNodeC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.AddrList)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtNodesC struct {
+	Nodes []DhtNodeC
+}
+
+func (t DhtNodesC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtNodesC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Nodes)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtKeyC struct {
+	Id   tl.Int256
+	Name []byte
+	Idx  uint32
+}
+
+func (t DhtKeyC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Idx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtKeyC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Name)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Idx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtUpdateRule struct {
+	tl.SumType
+	DhtUpdateRuleSignature    struct{}
+	DhtUpdateRuleAnybody      struct{}
+	DhtUpdateRuleOverlayNodes struct{}
+}
+
+func (t DhtUpdateRule) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "DhtUpdateRuleSignature":
+		b, err = tl.Marshal(uint32(0xcc9f31f7))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	case "DhtUpdateRuleAnybody":
+		b, err = tl.Marshal(uint32(0x61578e14))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	case "DhtUpdateRuleOverlayNodes":
+		b, err = tl.Marshal(uint32(0x26779383))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtUpdateRule) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xcc9f31f7:
+		t.SumType = "DhtUpdateRuleSignature"
+	case 0x61578e14:
+		t.SumType = "DhtUpdateRuleAnybody"
+	case 0x26779383:
+		t.SumType = "DhtUpdateRuleOverlayNodes"
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type DhtKeyDescriptionC struct {
+	Key        DhtKeyC
+	Id         PublicKey
+	UpdateRule DhtUpdateRule
+	Signature  []byte
+}
+
+func (t DhtKeyDescriptionC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.UpdateRule)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtKeyDescriptionC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.UpdateRule)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtValueC struct {
+	Key       DhtKeyDescriptionC
+	Value     []byte
+	Ttl       uint32
+	Signature []byte
+}
+
+func (t DhtValueC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Ttl)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtValueC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Ttl)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtPongC struct {
+	RandomId uint64
+}
+
+func (t DhtPongC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.RandomId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtPongC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.RandomId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtValueResult struct {
+	tl.SumType
+	DhtValueNotFound struct {
+		Nodes DhtNodesC
+	}
+	DhtValueFound struct {
+		Value DhtValue
+	}
+}
+
+func (t DhtValueResult) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "DhtValueNotFound":
+		b, err = tl.Marshal(uint32(0xa2620568))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.DhtValueNotFound.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "DhtValueFound":
+		b, err = tl.Marshal(uint32(0xe40cf774))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.DhtValueFound.Value)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtValueResult) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xa2620568:
+		t.SumType = "DhtValueNotFound"
+		err = tl.Unmarshal(r, &t.DhtValueNotFound.Nodes)
+		if err != nil {
+			return err
+		}
+	case 0xe40cf774:
+		t.SumType = "DhtValueFound"
+		err = tl.Unmarshal(r, &t.DhtValueFound.Value)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type FecType struct {
+	tl.SumType
+	FecRaptorQ struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+	FecRoundRobin struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+	FecOnline struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+}
+
+func (t FecType) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "FecRaptorQ":
+		b, err = tl.Marshal(uint32(0x8b93a7e0))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecRaptorQ.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRaptorQ.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRaptorQ.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "FecRoundRobin":
+		b, err = tl.Marshal(uint32(0x32f528e4))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecRoundRobin.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRoundRobin.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRoundRobin.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "FecOnline":
+		b, err = tl.Marshal(uint32(0x127660c))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecOnline.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecOnline.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecOnline.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *FecType) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x8b93a7e0:
+		t.SumType = "FecRaptorQ"
+		err = tl.Unmarshal(r, &t.FecRaptorQ.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRaptorQ.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRaptorQ.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	case 0x32f528e4:
+		t.SumType = "FecRoundRobin"
+		err = tl.Unmarshal(r, &t.FecRoundRobin.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRoundRobin.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRoundRobin.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	case 0x127660c:
+		t.SumType = "FecOnline"
+		err = tl.Unmarshal(r, &t.FecOnline.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecOnline.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecOnline.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type OverlayNodeToSignC struct {
+	Id      AdnlIdShortC
+	Overlay tl.Int256
+	Version uint32
+}
+
+func (t OverlayNodeToSignC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayNodeToSignC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayNodeC struct {
+	Id        PublicKey
+	Overlay   tl.Int256
+	Version   uint32
+	Signature []byte
+}
+
+func (t OverlayNodeC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Version)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayNodeC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Version)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayNodesC struct {
+	Nodes []OverlayNodeC
+}
+
+func (t OverlayNodesC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayNodesC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Nodes)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayMessageC struct {
+	Overlay tl.Int256
+}
+
+func (t OverlayMessageC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayMessageC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayCertificate struct {
+	tl.SumType
+	OverlayCertificate struct {
+		IssuedBy  PublicKey
+		ExpireAt  uint32
+		MaxSize   uint32
+		Signature []byte
+	}
+	OverlayCertificateV2 struct {
+		IssuedBy  PublicKey
+		ExpireAt  uint32
+		MaxSize   uint32
+		Flags     uint32
+		Signature []byte
+	}
+	OverlayEmptyCertificate struct{}
+}
+
+func (t OverlayCertificate) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "OverlayCertificate":
+		b, err = tl.Marshal(uint32(0xe09ed731))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayCertificate.IssuedBy)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificate.ExpireAt)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificate.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificate.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayCertificateV2":
+		b, err = tl.Marshal(uint32(0xb43f9c83))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayCertificateV2.IssuedBy)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificateV2.ExpireAt)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificateV2.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificateV2.Flags)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayCertificateV2.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayEmptyCertificate":
+		b, err = tl.Marshal(uint32(0x32dabccf))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayCertificate) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xe09ed731:
+		t.SumType = "OverlayCertificate"
+		err = tl.Unmarshal(r, &t.OverlayCertificate.IssuedBy)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificate.ExpireAt)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificate.MaxSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificate.Signature)
+		if err != nil {
+			return err
+		}
+	case 0xb43f9c83:
+		t.SumType = "OverlayCertificateV2"
+		err = tl.Unmarshal(r, &t.OverlayCertificateV2.IssuedBy)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificateV2.ExpireAt)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificateV2.MaxSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificateV2.Flags)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayCertificateV2.Signature)
+		if err != nil {
+			return err
+		}
+	case 0x32dabccf:
+		t.SumType = "OverlayEmptyCertificate"
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type OverlayBroadcast struct {
+	tl.SumType
+	OverlayFecReceived struct {
+		Hash tl.Int256
+	}
+	OverlayFecCompleted struct {
+		Hash tl.Int256
+	}
+	OverlayUnicast struct {
+		Data []byte
+	}
+	OverlayBroadcast struct {
+		Src         PublicKey
+		Certificate OverlayCertificate
+		Flags       uint32
+		Data        []byte
+		Date        uint32
+		Signature   []byte
+	}
+	OverlayBroadcastFec struct {
+		Src         PublicKey
+		Certificate OverlayCertificate
+		DataHash    tl.Int256
+		DataSize    uint32
+		Flags       uint32
+		Data        []byte
+		Seqno       uint32
+		Fec         FecType
+		Date        uint32
+		Signature   []byte
+	}
+	OverlayBroadcastFecShort struct {
+		Src           PublicKey
+		Certificate   OverlayCertificate
+		BroadcastHash tl.Int256
+		PartDataHash  tl.Int256
+		Seqno         uint32
+		Signature     []byte
+	}
+	OverlayBroadcastNotFound struct{}
+}
+
+func (t OverlayBroadcast) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "OverlayFecReceived":
+		b, err = tl.Marshal(uint32(0xd55c14ec))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayFecReceived.Hash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayFecCompleted":
+		b, err = tl.Marshal(uint32(0x9d76914))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayFecCompleted.Hash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayUnicast":
+		b, err = tl.Marshal(uint32(0x33534e24))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayUnicast.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayBroadcast":
+		b, err = tl.Marshal(uint32(0xb15a2b6b))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayBroadcast.Src)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcast.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcast.Flags)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcast.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcast.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcast.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayBroadcastFec":
+		b, err = tl.Marshal(uint32(0xbad7c36a))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Src)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.DataHash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Flags)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Fec)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Date)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFec.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayBroadcastFecShort":
+		b, err = tl.Marshal(uint32(0xf1881342))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.OverlayBroadcastFecShort.Src)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFecShort.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFecShort.BroadcastHash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFecShort.PartDataHash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFecShort.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.OverlayBroadcastFecShort.Signature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "OverlayBroadcastNotFound":
+		b, err = tl.Marshal(uint32(0x95863624))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayBroadcast) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xd55c14ec:
+		t.SumType = "OverlayFecReceived"
+		err = tl.Unmarshal(r, &t.OverlayFecReceived.Hash)
+		if err != nil {
+			return err
+		}
+	case 0x9d76914:
+		t.SumType = "OverlayFecCompleted"
+		err = tl.Unmarshal(r, &t.OverlayFecCompleted.Hash)
+		if err != nil {
+			return err
+		}
+	case 0x33534e24:
+		t.SumType = "OverlayUnicast"
+		err = tl.Unmarshal(r, &t.OverlayUnicast.Data)
+		if err != nil {
+			return err
+		}
+	case 0xb15a2b6b:
+		t.SumType = "OverlayBroadcast"
+		err = tl.Unmarshal(r, &t.OverlayBroadcast.Src)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcast.Certificate)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcast.Flags)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcast.Data)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcast.Date)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcast.Signature)
+		if err != nil {
+			return err
+		}
+	case 0xbad7c36a:
+		t.SumType = "OverlayBroadcastFec"
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Src)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Certificate)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.DataHash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Flags)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Data)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Seqno)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Fec)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Date)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFec.Signature)
+		if err != nil {
+			return err
+		}
+	case 0xf1881342:
+		t.SumType = "OverlayBroadcastFecShort"
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFecShort.Src)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFecShort.Certificate)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFecShort.BroadcastHash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFecShort.PartDataHash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFecShort.Seqno)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.OverlayBroadcastFecShort.Signature)
+		if err != nil {
+			return err
+		}
+	case 0x95863624:
+		t.SumType = "OverlayBroadcastNotFound"
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type TonNodeBlockIdExtC struct {
+	Workchain uint32
+	Shard     uint64
+	Seqno     uint32
+	RootHash  tl.Int256
+	FileHash  tl.Int256
+}
+
+func (t TonNodeBlockIdExtC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Workchain)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Shard)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Seqno)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.RootHash)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.FileHash)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *TonNodeBlockIdExtC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Workchain)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Shard)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Seqno)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.RootHash)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.FileHash)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type TonNodeBlockSignatureC struct {
+	Who       tl.Int256
+	Signature []byte
+}
+
+func (t TonNodeBlockSignatureC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Who)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Signature)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *TonNodeBlockSignatureC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Who)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Signature)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type TonNodeExternalMessageC struct {
+	Data []byte
+}
+
+func (t TonNodeExternalMessageC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *TonNodeExternalMessageC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type TonNodeNewShardBlockC struct {
+	Block   TonNodeBlockIdExtC
+	CcSeqno uint32
+	Data    []byte
+}
+
+func (t TonNodeNewShardBlockC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Block)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.CcSeqno)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *TonNodeNewShardBlockC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Block)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.CcSeqno)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type TonNodeBroadcast struct {
+	tl.SumType
+	TonNodeBlockBroadcast struct {
+		Id               TonNodeBlockIdExtC
+		CatchainSeqno    uint32
+		ValidatorSetHash uint32
+		Signatures       []TonNodeBlockSignatureC
+		Proof            []byte
+		Data             []byte
+	}
+	TonNodeExternalMessageBroadcast struct {
+		Message TonNodeExternalMessageC
+	}
+	TonNodeNewShardBlockBroadcast struct {
+		Block TonNodeNewShardBlockC
+	}
+	TonNodeNewBlockCandidateBroadcast struct {
+		Id                TonNodeBlockIdExtC
+		CatchainSeqno     uint32
+		ValidatorSetHash  uint32
+		CollatorSignature TonNodeBlockSignatureC
+		Data              []byte
+	}
+}
+
+func (t TonNodeBroadcast) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "TonNodeBlockBroadcast":
+		b, err = tl.Marshal(uint32(0xae2e1105))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.TonNodeBlockBroadcast.Id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeBlockBroadcast.CatchainSeqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeBlockBroadcast.ValidatorSetHash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeBlockBroadcast.Signatures)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeBlockBroadcast.Proof)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeBlockBroadcast.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "TonNodeExternalMessageBroadcast":
+		b, err = tl.Marshal(uint32(0x3d1b1867))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.TonNodeExternalMessageBroadcast.Message)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "TonNodeNewShardBlockBroadcast":
+		b, err = tl.Marshal(uint32(0xaf2fabc))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.TonNodeNewShardBlockBroadcast.Block)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "TonNodeNewBlockCandidateBroadcast":
+		b, err = tl.Marshal(uint32(0xbfed0dc0))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.TonNodeNewBlockCandidateBroadcast.Id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeNewBlockCandidateBroadcast.CatchainSeqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeNewBlockCandidateBroadcast.ValidatorSetHash)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeNewBlockCandidateBroadcast.CollatorSignature)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.TonNodeNewBlockCandidateBroadcast.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *TonNodeBroadcast) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0xae2e1105:
+		t.SumType = "TonNodeBlockBroadcast"
+		err = tl.Unmarshal(r, &t.TonNodeBlockBroadcast.Id)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeBlockBroadcast.CatchainSeqno)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeBlockBroadcast.ValidatorSetHash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeBlockBroadcast.Signatures)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeBlockBroadcast.Proof)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeBlockBroadcast.Data)
+		if err != nil {
+			return err
+		}
+	case 0x3d1b1867:
+		t.SumType = "TonNodeExternalMessageBroadcast"
+		err = tl.Unmarshal(r, &t.TonNodeExternalMessageBroadcast.Message)
+		if err != nil {
+			return err
+		}
+	case 0xaf2fabc:
+		t.SumType = "TonNodeNewShardBlockBroadcast"
+		err = tl.Unmarshal(r, &t.TonNodeNewShardBlockBroadcast.Block)
+		if err != nil {
+			return err
+		}
+	case 0xbfed0dc0:
+		t.SumType = "TonNodeNewBlockCandidateBroadcast"
+		err = tl.Unmarshal(r, &t.TonNodeNewBlockCandidateBroadcast.Id)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeNewBlockCandidateBroadcast.CatchainSeqno)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeNewBlockCandidateBroadcast.ValidatorSetHash)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeNewBlockCandidateBroadcast.CollatorSignature)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.TonNodeNewBlockCandidateBroadcast.Data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type DhtPingC struct {
+	RandomId uint64
+}
+
+func (t DhtPingC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.RandomId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtPingC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.RandomId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtFindNodeC struct {
+	Key tl.Int256
+	K   uint32
+}
+
+func (t DhtFindNodeC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.K)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtFindNodeC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.K)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type DhtFindValueC struct {
+	Key tl.Int256
+	K   uint32
+}
+
+func (t DhtFindValueC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.K)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *DhtFindValueC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Key)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.K)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayGetRandomPeersC struct {
+	Peers OverlayNodesC
+}
+
+func (t OverlayGetRandomPeersC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Peers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayGetRandomPeersC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Peers)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type OverlayQueryC struct {
+	Overlay tl.Int256
+}
+
+func (t OverlayQueryC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *OverlayQueryC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Overlay)
+	if err != nil {
+		return err
+	}
+	return nil
+}
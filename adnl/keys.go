@@ -0,0 +1,144 @@
+package adnl
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/curve25519-voi/curve"
+	ed25519crv "github.com/oasisprotocol/curve25519-voi/primitives/ed25519"
+	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	magicDhtValue = 0x90ad27cb // crc32("dht.value key:dht.keyDescription value:bytes ttl:int signature:bytes = dht.Value")
+)
+
+// DhtValue is a boxed dht.Value.
+type DhtValue struct {
+	DhtValueC
+}
+
+func (v DhtValue) MarshalTL() ([]byte, error) {
+	b, err := v.DhtValueC.MarshalTL()
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, magicDhtValue), b...), nil
+}
+
+func (v *DhtValue) UnmarshalTL(r io.Reader) error {
+	var tag uint32
+	if err := tl.Unmarshal(r, &tag); err != nil {
+		return err
+	}
+	if tag != magicDhtValue {
+		return fmt.Errorf("invalid tag")
+	}
+	return v.DhtValueC.UnmarshalTL(r)
+}
+
+// PublicKeyEd25519 returns a TL representation of an ed25519 public key.
+func PublicKeyEd25519(key ed25519.PublicKey) PublicKey {
+	var k PublicKey
+	k.SumType = "PubEd25519"
+	copy(k.PubEd25519.Key[:], key)
+	return k
+}
+
+// Ed25519 returns an ed25519 public key if the key is pub.ed25519.
+func (k PublicKey) Ed25519() (ed25519.PublicKey, bool) {
+	if k.SumType != "PubEd25519" {
+		return nil, false
+	}
+	return ed25519.PublicKey(bytes.Clone(k.PubEd25519.Key[:])), true
+}
+
+// ShortID returns an ADNL address of the key which is equal to sha256 of the TL-serialized key.
+func (k PublicKey) ShortID() (tl.Int256, error) {
+	b, err := tl.Marshal(k)
+	if err != nil {
+		return tl.Int256{}, err
+	}
+	return tl.Int256(sha256.Sum256(b)), nil
+}
+
+// sharedKey computes an x25519 shared secret of two ed25519 keys
+// the same way liteclient does during the ADNL TCP handshake.
+func sharedKey(ourKey ed25519.PrivateKey, peerKey ed25519.PublicKey) ([]byte, error) {
+	comp, err := curve.NewCompressedEdwardsYFromBytes(peerKey)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := curve.NewEdwardsPoint().SetCompressedY(comp)
+	if err != nil {
+		return nil, err
+	}
+	mp := curve.NewMontgomeryPoint().SetEdwards(ep)
+	bb := x25519.EdPrivateKeyToX25519(ed25519crv.PrivateKey(ourKey))
+	return x25519.X25519(bb, mp[:])
+}
+
+func newCipher(shared []byte, checksum []byte) (cipher.Stream, error) {
+	key := append(bytes.Clone(shared[:16]), checksum[16:32]...)
+	iv := append(bytes.Clone(checksum[:4]), shared[20:32]...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, iv), nil
+}
+
+// encrypt encrypts data for a peer with a one-time key.
+// The result is dst_id:int256 src_key:int256 checksum:int256 encrypted_data.
+func encrypt(peerID tl.Int256, peerKey ed25519.PublicKey, data []byte) ([]byte, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := sharedKey(priv, peerKey)
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(data)
+	stream, err := newCipher(shared, checksum[:])
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 96+len(data))
+	copy(b[:32], peerID[:])
+	copy(b[32:64], pub)
+	copy(b[64:96], checksum[:])
+	stream.XORKeyStream(b[96:], data)
+	return b, nil
+}
+
+// decrypt decrypts a packet encrypted by encrypt with the public key of the given key.
+func decrypt(key ed25519.PrivateKey, b []byte) ([]byte, error) {
+	if len(b) < 96 {
+		return nil, fmt.Errorf("packet is too short")
+	}
+	shared, err := sharedKey(key, b[32:64])
+	if err != nil {
+		return nil, err
+	}
+	checksum := b[64:96]
+	stream, err := newCipher(shared, checksum)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(b)-96)
+	stream.XORKeyStream(data, b[96:])
+	hash := sha256.Sum256(data)
+	if !bytes.Equal(hash[:], checksum) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	return data, nil
+}
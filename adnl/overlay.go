@@ -0,0 +1,434 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xssnick/raptorq"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	magicOverlayMessage        = 0x75252420 // crc32("overlay.message overlay:int256 = overlay.Message")
+	magicOverlayQuery          = 0xccfd8443 // crc32("overlay.query overlay:int256 = True")
+	magicOverlayNodeToSign     = 0x03d8a8e1 // crc32("overlay.node.toSign id:adnl.id.short overlay:int256 version:int = overlay.node.ToSign")
+	magicOverlayGetRandomPeers = 0x48ee64ab // crc32("overlay.getRandomPeers peers:overlay.nodes = overlay.Nodes")
+
+	// maxOverlayPeers is a number of peers the overlay connects to.
+	maxOverlayPeers = 20
+	// maxOverlayNodes limits the number of known nodes of the overlay.
+	maxOverlayNodes = 200
+	// maxBroadcastSize limits the size of a FEC broadcast.
+	maxBroadcastSize = 16 << 20
+	// fecSymbolSize is a size of a RaptorQ symbol of FEC broadcasts.
+	fecSymbolSize = 768
+	// maxFecBroadcasts limits the number of FEC broadcasts being decoded at the same time.
+	maxFecBroadcasts = 64
+	// maxFecBroadcastsSize limits the total size of FEC broadcasts being decoded at the same time.
+	maxFecBroadcastsSize = 64 << 20
+	// broadcastTTL is a time to remember delivered broadcasts to ignore duplicates
+	// and to wait for all parts of a FEC broadcast.
+	broadcastTTL = time.Minute
+)
+
+var (
+	ErrNoOverlayPeers = errors.New("adnl: no overlay peers")
+)
+
+// Broadcast is a message broadcast to all members of an overlay.
+type Broadcast struct {
+	// Source is a key of the node which created the broadcast.
+	Source PublicKey
+	// Peer is a node which delivered the broadcast.
+	Peer *Peer
+	Data []byte
+}
+
+// BroadcastHandler processes broadcasts received in an overlay.
+type BroadcastHandler func(b Broadcast)
+
+// Overlay is a member of a public overlay network, like an overlay of a shard
+// where new blocks and external messages are broadcast.
+//
+// Signatures of broadcasts and certificates are not verified,
+// so broadcast contents must be validated by a handler, for example, with block proofs.
+type Overlay struct {
+	gateway   *Gateway
+	dht       *DHTClient
+	overlayID liteclient.OverlayID
+	id        tl.Int256
+
+	mu         sync.Mutex
+	handler    BroadcastHandler
+	peers      map[tl.Int256]*Peer
+	nodes      map[tl.Int256]OverlayNodeC
+	delivered  map[tl.Int256]time.Time
+	broadcasts map[tl.Int256]*fecBroadcast
+}
+
+type fecBroadcast struct {
+	source    PublicKey
+	fec       FecType
+	decoder   *raptorq.Decoder
+	startedAt time.Time
+}
+
+// NewOverlay joins the gateway to the overlay, messages prefixed with overlay.message and overlay.query
+// are processed by the overlay instead of handlers of the gateway.
+// The DHT client is used to discover nodes of the overlay and can be nil if peers are added with AddPeer.
+func NewOverlay(gateway *Gateway, dht *DHTClient, overlay liteclient.OverlayID) (*Overlay, error) {
+	id, err := overlay.ComputeShortID()
+	if err != nil {
+		return nil, err
+	}
+	o := &Overlay{
+		gateway:    gateway,
+		dht:        dht,
+		overlayID:  overlay,
+		id:         id,
+		peers:      map[tl.Int256]*Peer{},
+		nodes:      map[tl.Int256]OverlayNodeC{},
+		delivered:  map[tl.Int256]time.Time{},
+		broadcasts: map[tl.Int256]*fecBroadcast{},
+	}
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	if _, ok := gateway.overlays[id]; ok {
+		return nil, fmt.Errorf("overlay %x is already joined", id)
+	}
+	gateway.overlays[id] = o
+	return o, nil
+}
+
+// ID returns a short ID of the overlay.
+func (o *Overlay) ID() tl.Int256 {
+	return o.id
+}
+
+// Close leaves the overlay.
+func (o *Overlay) Close() {
+	o.gateway.mu.Lock()
+	defer o.gateway.mu.Unlock()
+	delete(o.gateway.overlays, o.id)
+}
+
+func (o *Overlay) SetBroadcastHandler(handler BroadcastHandler) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.handler = handler
+}
+
+// AddPeer adds a known member of the overlay.
+func (o *Overlay) AddPeer(peer *Peer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.peers[peer.ID()] = peer
+}
+
+// Peers returns members of the overlay the gateway is connected to.
+func (o *Overlay) Peers() []*Peer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	peers := make([]*Peer, 0, len(o.peers))
+	for _, p := range o.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// Join looks up nodes of the overlay in the DHT, connects to them
+// and exchanges lists of nodes with peers, so they start sending broadcasts to the gateway.
+// Peers forget about the gateway after a while, so Join should be called periodically.
+func (o *Overlay) Join(ctx context.Context) error {
+	if o.dht != nil {
+		nodes, err := o.dht.FindOverlayNodes(ctx, o.overlayID)
+		if err != nil && !errors.Is(err, ErrDhtValueNotFound) {
+			return err
+		}
+		o.addNodes(nodes)
+		o.connect(ctx)
+	}
+	peers := o.Peers()
+	if len(peers) == 0 {
+		return ErrNoOverlayPeers
+	}
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		wg.Add(1)
+		go func(p *Peer) {
+			defer wg.Done()
+			nodes, err := o.getRandomPeers(ctx, p)
+			if err != nil {
+				return
+			}
+			o.addNodes(nodes)
+		}(p)
+	}
+	wg.Wait()
+	if o.dht != nil {
+		o.connect(ctx)
+	}
+	return nil
+}
+
+// Query sends a query prefixed with overlay.query to a member of the overlay.
+func (o *Overlay) Query(ctx context.Context, peer *Peer, query []byte) ([]byte, error) {
+	prefix, err := marshalBoxed(magicOverlayQuery, OverlayQueryC{Overlay: o.id})
+	if err != nil {
+		return nil, err
+	}
+	return peer.Query(ctx, append(prefix, query...))
+}
+
+func (o *Overlay) getRandomPeers(ctx context.Context, peer *Peer) ([]OverlayNodeC, error) {
+	nodes, err := o.randomNodes()
+	if err != nil {
+		return nil, err
+	}
+	query, err := marshalBoxed(magicOverlayGetRandomPeers, OverlayGetRandomPeersC{Peers: OverlayNodesC{Nodes: nodes}})
+	if err != nil {
+		return nil, err
+	}
+	answer, err := o.Query(ctx, peer, query)
+	if err != nil {
+		return nil, err
+	}
+	var res OverlayNodesC
+	if err := unmarshalBoxed(answer, magicOverlayNodes, &res); err != nil {
+		return nil, err
+	}
+	return res.Nodes, nil
+}
+
+// connect resolves addresses of known nodes with the DHT until there are enough peers.
+func (o *Overlay) connect(ctx context.Context) {
+	o.mu.Lock()
+	var candidates []tl.Int256
+	for id := range o.nodes {
+		if _, ok := o.peers[id]; !ok && id != o.gateway.id {
+			candidates = append(candidates, id)
+		}
+	}
+	missing := maxOverlayPeers - len(o.peers)
+	o.mu.Unlock()
+
+	for _, id := range candidates {
+		if missing <= 0 || ctx.Err() != nil {
+			return
+		}
+		key, hosts, err := o.dht.ResolveADNLAddress(ctx, ton.Bits256(id))
+		if err != nil || len(hosts) == 0 {
+			continue
+		}
+		peer, err := o.gateway.Peer(hosts[0], key)
+		if err != nil {
+			continue
+		}
+		o.AddPeer(peer)
+		missing--
+	}
+}
+
+// addNodes stores nodes of the overlay with valid signatures.
+func (o *Overlay) addNodes(nodes []OverlayNodeC) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, n := range nodes {
+		if len(o.nodes) >= maxOverlayNodes {
+			return
+		}
+		id, err := checkOverlayNode(n, o.id)
+		if err != nil || id == o.gateway.id {
+			continue
+		}
+		if known, ok := o.nodes[id]; !ok || known.Version < n.Version {
+			o.nodes[id] = n
+		}
+	}
+}
+
+// randomNodes returns the node of the gateway and some known nodes of the overlay.
+func (o *Overlay) randomNodes() ([]OverlayNodeC, error) {
+	version := uint32(time.Now().Unix())
+	b, err := marshalBoxed(magicOverlayNodeToSign, OverlayNodeToSignC{Id: AdnlIdShortC{Id: o.gateway.id}, Overlay: o.id, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	nodes := []OverlayNodeC{{
+		Id:        PublicKeyEd25519(o.gateway.key.Public().(ed25519.PublicKey)),
+		Overlay:   o.id,
+		Version:   version,
+		Signature: ed25519.Sign(o.gateway.key, b),
+	}}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// map iteration order is random.
+	for _, n := range o.nodes {
+		if len(nodes) >= 5 {
+			break
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (o *Overlay) processQuery(peer *Peer, query []byte) ([]byte, error) {
+	var req OverlayGetRandomPeersC
+	if err := unmarshalBoxed(query, magicOverlayGetRandomPeers, &req); err != nil {
+		return nil, fmt.Errorf("unsupported overlay query")
+	}
+	o.addNodes(req.Peers.Nodes)
+	o.AddPeer(peer)
+	nodes, err := o.randomNodes()
+	if err != nil {
+		return nil, err
+	}
+	return marshalBoxed(magicOverlayNodes, OverlayNodesC{Nodes: nodes})
+}
+
+func (o *Overlay) processMessage(peer *Peer, data []byte) {
+	var msg OverlayBroadcast
+	if err := tl.Unmarshal(bytes.NewReader(data), &msg); err != nil {
+		return
+	}
+	switch msg.SumType {
+	case "OverlayBroadcast":
+		o.deliver(tl.Int256(sha256.Sum256(msg.OverlayBroadcast.Data)), Broadcast{
+			Source: msg.OverlayBroadcast.Src,
+			Peer:   peer,
+			Data:   msg.OverlayBroadcast.Data,
+		})
+	case "OverlayUnicast":
+		o.deliver(tl.Int256(sha256.Sum256(msg.OverlayUnicast.Data)), Broadcast{
+			Source: PublicKeyEd25519(peer.Key()),
+			Peer:   peer,
+			Data:   msg.OverlayUnicast.Data,
+		})
+	case "OverlayBroadcastFec":
+		o.processFecPart(peer, msg)
+	}
+}
+
+// processFecPart adds a part of a FEC broadcast and delivers the broadcast once it is decoded.
+func (o *Overlay) processFecPart(peer *Peer, msg OverlayBroadcast) {
+	part := msg.OverlayBroadcastFec
+	// a decoder allocates memory proportional to these parameters, so they are checked before creating it.
+	fec := part.Fec.FecRaptorQ
+	if part.Fec.SumType != "FecRaptorQ" || fec.SymbolSize != fecSymbolSize ||
+		fec.DataSize != part.DataSize || part.DataSize == 0 || part.DataSize > maxBroadcastSize {
+		return
+	}
+	hash := part.DataHash
+	now := time.Now()
+	o.mu.Lock()
+	if _, ok := o.delivered[hash]; ok {
+		o.mu.Unlock()
+		return
+	}
+	b, ok := o.broadcasts[hash]
+	if !ok {
+		inFlightSize := uint64(part.DataSize)
+		for h, fb := range o.broadcasts {
+			if now.Sub(fb.startedAt) > broadcastTTL {
+				delete(o.broadcasts, h)
+				continue
+			}
+			inFlightSize += uint64(fb.fec.FecRaptorQ.DataSize)
+		}
+		if len(o.broadcasts) >= maxFecBroadcasts || inFlightSize > maxFecBroadcastsSize {
+			o.mu.Unlock()
+			return
+		}
+		decoder, err := raptorq.NewRaptorQ(fec.SymbolSize).CreateDecoder(part.DataSize)
+		if err != nil {
+			o.mu.Unlock()
+			return
+		}
+		b = &fecBroadcast{source: part.Src, fec: part.Fec, decoder: decoder, startedAt: now}
+		o.broadcasts[hash] = b
+	}
+	if b.fec != part.Fec {
+		o.mu.Unlock()
+		return
+	}
+	ready, err := b.decoder.AddSymbol(part.Seqno, part.Data)
+	if err != nil || !ready {
+		o.mu.Unlock()
+		return
+	}
+	decoded, data, err := b.decoder.Decode()
+	if err != nil || !decoded {
+		o.mu.Unlock()
+		return
+	}
+	delete(o.broadcasts, hash)
+	o.mu.Unlock()
+	if tl.Int256(sha256.Sum256(data)) != hash {
+		return
+	}
+	o.deliver(hash, Broadcast{Source: b.source, Peer: peer, Data: bytes.Clone(data)})
+}
+
+// deliver passes the broadcast to the handler unless it has been delivered already.
+func (o *Overlay) deliver(hash tl.Int256, b Broadcast) {
+	now := time.Now()
+	o.mu.Lock()
+	if _, ok := o.delivered[hash]; ok {
+		o.mu.Unlock()
+		return
+	}
+	for h, t := range o.delivered {
+		if now.Sub(t) > broadcastTTL {
+			delete(o.delivered, h)
+		}
+	}
+	o.delivered[hash] = now
+	handler := o.handler
+	o.mu.Unlock()
+	if handler != nil {
+		handler(b)
+	}
+}
+
+// checkOverlayNode verifies that the node is signed by its key and belongs to the overlay.
+// It returns an ADNL address of the node.
+func checkOverlayNode(n OverlayNodeC, overlay tl.Int256) (tl.Int256, error) {
+	if n.Overlay != overlay {
+		return tl.Int256{}, fmt.Errorf("overlay mismatch")
+	}
+	key, ok := n.Id.Ed25519()
+	if !ok {
+		return tl.Int256{}, fmt.Errorf("unsupported key type: %v", n.Id.SumType)
+	}
+	id, err := n.Id.ShortID()
+	if err != nil {
+		return tl.Int256{}, err
+	}
+	b, err := marshalBoxed(magicOverlayNodeToSign, OverlayNodeToSignC{Id: AdnlIdShortC{Id: id}, Overlay: n.Overlay, Version: n.Version})
+	if err != nil {
+		return tl.Int256{}, err
+	}
+	if !ed25519.Verify(key, b, n.Signature) {
+		return tl.Int256{}, fmt.Errorf("invalid overlay node signature")
+	}
+	return id, nil
+}
+
+// DecodeTonNodeBroadcast decodes data of a broadcast sent to a shard overlay,
+// like a new block or an external message.
+func DecodeTonNodeBroadcast(data []byte) (TonNodeBroadcast, error) {
+	var b TonNodeBroadcast
+	if err := tl.Unmarshal(bytes.NewReader(data), &b); err != nil {
+		return TonNodeBroadcast{}, err
+	}
+	return b, nil
+}
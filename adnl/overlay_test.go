@@ -0,0 +1,192 @@
+package adnl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/xssnick/raptorq"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+func sendOverlayMessage(t *testing.T, peer *Peer, overlay tl.Int256, msg OverlayBroadcast) {
+	prefix, err := marshalBoxed(magicOverlayMessage, OverlayMessageC{Overlay: overlay})
+	if err != nil {
+		t.Fatalf("marshalBoxed() failed: %v", err)
+	}
+	b, err := tl.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if err := peer.SendCustom(append(prefix, b...)); err != nil {
+		t.Fatalf("SendCustom() failed: %v", err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	overlayID := liteclient.OverlayID{Workchain: 0, Shard: -0x8000000000000000}
+	a, b := newTestGateway(t), newTestGateway(t)
+	overlayA, err := NewOverlay(a, nil, overlayID)
+	if err != nil {
+		t.Fatalf("NewOverlay() failed: %v", err)
+	}
+	overlayB, err := NewOverlay(b, nil, overlayID)
+	if err != nil {
+		t.Fatalf("NewOverlay() failed: %v", err)
+	}
+	received := make(chan Broadcast, 10)
+	overlayB.SetBroadcastHandler(func(b Broadcast) {
+		received <- b
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	peerA, err := b.Peer(a.Addr().String(), a.key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatalf("Peer() failed: %v", err)
+	}
+	overlayB.AddPeer(peerA)
+	if err := overlayB.Join(ctx); err != nil {
+		t.Fatalf("Join() failed: %v", err)
+	}
+	peers := overlayA.Peers()
+	if len(peers) != 1 || peers[0].ID() != b.ID() {
+		t.Fatalf("overlay A must learn about B after Join()")
+	}
+	if _, ok := overlayB.nodes[a.ID()]; !ok {
+		t.Fatalf("overlay B must learn a node of A after Join()")
+	}
+	peerB := peers[0]
+
+	var msg TonNodeBroadcast
+	msg.SumType = "TonNodeExternalMessageBroadcast"
+	msg.TonNodeExternalMessageBroadcast.Message.Data = []byte("external message")
+	data, err := tl.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	var simple OverlayBroadcast
+	simple.SumType = "OverlayBroadcast"
+	simple.OverlayBroadcast.Src = PublicKeyEd25519(a.key.Public().(ed25519.PublicKey))
+	simple.OverlayBroadcast.Certificate.SumType = "OverlayEmptyCertificate"
+	simple.OverlayBroadcast.Data = data
+	// the duplicate must be ignored.
+	sendOverlayMessage(t, peerB, overlayA.ID(), simple)
+	sendOverlayMessage(t, peerB, overlayA.ID(), simple)
+
+	large := make([]byte, 100<<10)
+	rand.Read(large)
+	encoder, err := raptorq.NewRaptorQ(768).CreateEncoder(large)
+	if err != nil {
+		t.Fatalf("CreateEncoder() failed: %v", err)
+	}
+	var fec OverlayBroadcast
+	fec.SumType = "OverlayBroadcastFec"
+	fec.OverlayBroadcastFec.Src = simple.OverlayBroadcast.Src
+	fec.OverlayBroadcastFec.Certificate = simple.OverlayBroadcast.Certificate
+	fec.OverlayBroadcastFec.DataHash = tl.Int256(sha256.Sum256(large))
+	fec.OverlayBroadcastFec.DataSize = uint32(len(large))
+	fec.OverlayBroadcastFec.Fec.SumType = "FecRaptorQ"
+	fec.OverlayBroadcastFec.Fec.FecRaptorQ.DataSize = uint32(len(large))
+	fec.OverlayBroadcastFec.Fec.FecRaptorQ.SymbolSize = 768
+	fec.OverlayBroadcastFec.Fec.FecRaptorQ.SymbolsCount = encoder.BaseSymbolsNum()
+	// source symbols are skipped to check that the broadcast is recovered from repair symbols.
+	// UDP datagrams are dropped when the receiver falls behind, so symbols are paced
+	// and repair symbols are sent until both broadcasts are received.
+	for seqno := uint32(0); seqno < 3*encoder.BaseSymbolsNum() && len(received) < 2; seqno++ {
+		if seqno < encoder.BaseSymbolsNum() && seqno%10 == 0 {
+			continue
+		}
+		fec.OverlayBroadcastFec.Seqno = seqno
+		fec.OverlayBroadcastFec.Data = encoder.GenSymbol(seqno)
+		sendOverlayMessage(t, peerB, overlayA.ID(), fec)
+		if seqno%10 == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	want := [][]byte{data, large}
+	for i := range want {
+		select {
+		case bc := <-received:
+			if !bytes.Equal(bc.Data, want[i]) {
+				t.Fatalf("broadcast %v mismatch", i)
+			}
+			if bc.Peer.ID() != a.ID() {
+				t.Fatalf("unexpected peer of broadcast %v", i)
+			}
+		case <-ctx.Done():
+			t.Fatalf("broadcast %v was not received", i)
+		}
+	}
+	select {
+	case <-received:
+		t.Fatalf("duplicate broadcast was delivered")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	decoded, err := DecodeTonNodeBroadcast(data)
+	if err != nil {
+		t.Fatalf("DecodeTonNodeBroadcast() failed: %v", err)
+	}
+	if decoded.SumType != "TonNodeExternalMessageBroadcast" || string(decoded.TonNodeExternalMessageBroadcast.Message.Data) != "external message" {
+		t.Fatalf("unexpected broadcast: %v", decoded.SumType)
+	}
+}
+
+func TestOverlay_FecLimits(t *testing.T) {
+	overlayID := liteclient.OverlayID{Workchain: 0, Shard: -0x8000000000000000}
+	o, err := NewOverlay(newTestGateway(t), nil, overlayID)
+	if err != nil {
+		t.Fatalf("NewOverlay() failed: %v", err)
+	}
+	fecPart := func(symbolSize, dataSize uint32) OverlayBroadcast {
+		var msg OverlayBroadcast
+		msg.SumType = "OverlayBroadcastFec"
+		rand.Read(msg.OverlayBroadcastFec.DataHash[:])
+		msg.OverlayBroadcastFec.DataSize = dataSize
+		msg.OverlayBroadcastFec.Data = make([]byte, symbolSize)
+		msg.OverlayBroadcastFec.Fec.SumType = "FecRaptorQ"
+		msg.OverlayBroadcastFec.Fec.FecRaptorQ.DataSize = dataSize
+		msg.OverlayBroadcastFec.Fec.FecRaptorQ.SymbolSize = symbolSize
+		msg.OverlayBroadcastFec.Fec.FecRaptorQ.SymbolsCount = (dataSize + symbolSize - 1) / symbolSize
+		return msg
+	}
+	for _, msg := range []OverlayBroadcast{
+		fecPart(1<<31, 1000),
+		fecPart(1, 1000),
+		fecPart(fecSymbolSize, 0),
+		fecPart(fecSymbolSize, maxBroadcastSize+1),
+	} {
+		o.processFecPart(nil, msg)
+	}
+	if len(o.broadcasts) != 0 {
+		t.Fatalf("broadcasts with invalid fec parameters must be ignored")
+	}
+
+	for i := 0; i < maxFecBroadcasts+10; i++ {
+		o.processFecPart(nil, fecPart(fecSymbolSize, 10_000))
+	}
+	if len(o.broadcasts) != maxFecBroadcasts {
+		t.Fatalf("want %v broadcasts in flight, got %v", maxFecBroadcasts, len(o.broadcasts))
+	}
+	for _, b := range o.broadcasts {
+		b.startedAt = time.Now().Add(-2 * broadcastTTL)
+	}
+	o.processFecPart(nil, fecPart(fecSymbolSize, maxBroadcastSize))
+	if len(o.broadcasts) != 1 {
+		t.Fatalf("stale broadcasts must be evicted, got %v in flight", len(o.broadcasts))
+	}
+	for i := 0; i < 10; i++ {
+		o.processFecPart(nil, fecPart(fecSymbolSize, maxBroadcastSize))
+	}
+	if len(o.broadcasts) != maxFecBroadcastsSize/maxBroadcastSize {
+		t.Fatalf("total size of broadcasts in flight must be limited, got %v in flight", len(o.broadcasts))
+	}
+}
@@ -0,0 +1,880 @@
+// Code generated - DO NOT EDIT.
+
+package rldp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/tonkeeper/tongo/tl"
+	"io"
+)
+
+type FecType struct {
+	tl.SumType
+	FecRaptorQ struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+	FecRoundRobin struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+	FecOnline struct {
+		DataSize     uint32
+		SymbolSize   uint32
+		SymbolsCount uint32
+	}
+}
+
+func (t FecType) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "FecRaptorQ":
+		b, err = tl.Marshal(uint32(0x8b93a7e0))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecRaptorQ.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRaptorQ.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRaptorQ.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "FecRoundRobin":
+		b, err = tl.Marshal(uint32(0x32f528e4))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecRoundRobin.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRoundRobin.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecRoundRobin.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "FecOnline":
+		b, err = tl.Marshal(uint32(0x127660c))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.FecOnline.DataSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecOnline.SymbolSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.FecOnline.SymbolsCount)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *FecType) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x8b93a7e0:
+		t.SumType = "FecRaptorQ"
+		err = tl.Unmarshal(r, &t.FecRaptorQ.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRaptorQ.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRaptorQ.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	case 0x32f528e4:
+		t.SumType = "FecRoundRobin"
+		err = tl.Unmarshal(r, &t.FecRoundRobin.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRoundRobin.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecRoundRobin.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	case 0x127660c:
+		t.SumType = "FecOnline"
+		err = tl.Unmarshal(r, &t.FecOnline.DataSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecOnline.SymbolSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.FecOnline.SymbolsCount)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type RldpMessagePart struct {
+	tl.SumType
+	RldpMessagePart struct {
+		TransferId tl.Int256
+		FecType    FecType
+		Part       uint32
+		TotalSize  uint64
+		Seqno      uint32
+		Data       []byte
+	}
+	RldpConfirm struct {
+		TransferId tl.Int256
+		Part       uint32
+		Seqno      uint32
+	}
+	RldpComplete struct {
+		TransferId tl.Int256
+		Part       uint32
+	}
+}
+
+func (t RldpMessagePart) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "RldpMessagePart":
+		b, err = tl.Marshal(uint32(0x185c22cc))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpMessagePart.TransferId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.FecType)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.Part)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.TotalSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessagePart.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpConfirm":
+		b, err = tl.Marshal(uint32(0xf582dc58))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpConfirm.TransferId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpConfirm.Part)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpConfirm.Seqno)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpComplete":
+		b, err = tl.Marshal(uint32(0xbc0cb2bf))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpComplete.TransferId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpComplete.Part)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *RldpMessagePart) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x185c22cc:
+		t.SumType = "RldpMessagePart"
+		err = tl.Unmarshal(r, &t.RldpMessagePart.TransferId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.FecType)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.Part)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.TotalSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.Seqno)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessagePart.Data)
+		if err != nil {
+			return err
+		}
+	case 0xf582dc58:
+		t.SumType = "RldpConfirm"
+		err = tl.Unmarshal(r, &t.RldpConfirm.TransferId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpConfirm.Part)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpConfirm.Seqno)
+		if err != nil {
+			return err
+		}
+	case 0xbc0cb2bf:
+		t.SumType = "RldpComplete"
+		err = tl.Unmarshal(r, &t.RldpComplete.TransferId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpComplete.Part)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type RldpMessage struct {
+	tl.SumType
+	RldpMessage struct {
+		Id   tl.Int256
+		Data []byte
+	}
+	RldpQuery struct {
+		QueryId       tl.Int256
+		MaxAnswerSize uint64
+		Timeout       uint32
+		Data          []byte
+	}
+	RldpAnswer struct {
+		QueryId tl.Int256
+		Data    []byte
+	}
+}
+
+func (t RldpMessage) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	switch t.SumType {
+	case "RldpMessage":
+		b, err = tl.Marshal(uint32(0x7d1bcd1e))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpMessage.Id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpMessage.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpQuery":
+		b, err = tl.Marshal(uint32(0x8a794d69))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpQuery.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpQuery.MaxAnswerSize)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpQuery.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpQuery.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	case "RldpAnswer":
+		b, err = tl.Marshal(uint32(0xa3fc5c03))
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		b, err = tl.Marshal(t.RldpAnswer.QueryId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+		b, err = tl.Marshal(t.RldpAnswer.Data)
+		if err != nil {
+			return nil, err
+		}
+		_, err = buf.Write(b)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sum type")
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *RldpMessage) UnmarshalTL(r io.Reader) error {
+	var err error
+	var b [4]byte
+	_, err = io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	tag := int(binary.LittleEndian.Uint32(b[:]))
+	switch tag {
+	case 0x7d1bcd1e:
+		t.SumType = "RldpMessage"
+		err = tl.Unmarshal(r, &t.RldpMessage.Id)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpMessage.Data)
+		if err != nil {
+			return err
+		}
+	case 0x8a794d69:
+		t.SumType = "RldpQuery"
+		err = tl.Unmarshal(r, &t.RldpQuery.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpQuery.MaxAnswerSize)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpQuery.Timeout)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpQuery.Data)
+		if err != nil {
+			return err
+		}
+	case 0xa3fc5c03:
+		t.SumType = "RldpAnswer"
+		err = tl.Unmarshal(r, &t.RldpAnswer.QueryId)
+		if err != nil {
+			return err
+		}
+		err = tl.Unmarshal(r, &t.RldpAnswer.Data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid tag")
+	}
+	return nil
+}
+
+type HttpHeaderC struct {
+	Name  string
+	Value string
+}
+
+func (t HttpHeaderC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Value)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpHeaderC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Name)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Value)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpPayloadPartC struct {
+	Data    []byte
+	Trailer []HttpHeaderC
+	Last    bool
+}
+
+func (t HttpPayloadPartC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Trailer)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Last)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpPayloadPartC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Data)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Trailer)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Last)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpResponseC struct {
+	HttpVersion string
+	StatusCode  uint32
+	Reason      string
+	Headers     []HttpHeaderC
+	NoPayload   bool
+}
+
+func (t HttpResponseC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.HttpVersion)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.StatusCode)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Reason)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Headers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.NoPayload)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpResponseC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.HttpVersion)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.StatusCode)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Reason)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Headers)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.NoPayload)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpRequestC struct {
+	Id          tl.Int256
+	Method      string
+	Url         string
+	HttpVersion string
+	Headers     []HttpHeaderC
+}
+
+func (t HttpRequestC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Method)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Url)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.HttpVersion)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Headers)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpRequestC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Method)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Url)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.HttpVersion)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Headers)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type HttpGetNextPayloadPartC struct {
+	Id           tl.Int256
+	Seqno        uint32
+	MaxChunkSize uint32
+}
+
+func (t HttpGetNextPayloadPartC) MarshalTL() ([]byte, error) {
+	var (
+		err error
+		b   []byte
+	)
+	buf := new(bytes.Buffer)
+	b, err = tl.Marshal(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.Seqno)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = tl.Marshal(t.MaxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	_, err = buf.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *HttpGetNextPayloadPartC) UnmarshalTL(r io.Reader) error {
+	var err error
+	err = tl.Unmarshal(r, &t.Id)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.Seqno)
+	if err != nil {
+		return err
+	}
+	err = tl.Unmarshal(r, &t.MaxChunkSize)
+	if err != nil {
+		return err
+	}
+	return nil
+}
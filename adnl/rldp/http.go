@@ -0,0 +1,301 @@
+package rldp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/contract/dns"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	magicHttpRequest            = 0x61b191e1 // crc32("http.request id:int256 method:string url:string http_version:string headers:vector http.header = http.Response")
+	magicHttpResponse           = 0xca48a74a // crc32("http.response http_version:string status_code:int reason:string headers:vector http.header no_payload:Bool = http.Response")
+	magicHttpGetNextPayloadPart = 0x90745d0c // crc32("http.getNextPayloadPart id:int256 seqno:int max_chunk_size:int = http.PayloadPart")
+	magicHttpPayloadPart        = 0x295ad764 // crc32("http.payloadPart data:bytes trailer:vector http.header last:Bool = http.PayloadPart")
+
+	// maxChunkSize is a size of a payload part requested from a server.
+	maxChunkSize = 128 << 10
+	// maxResponseHeaderSize limits the size of http.response.
+	maxResponseHeaderSize = 64 << 10
+)
+
+var (
+	// ErrStorageSiteNotSupported is returned for a domain whose site record points to a TON Storage bag
+	// instead of an ADNL address. Such sites can be fetched through a TON Storage gateway.
+	ErrStorageSiteNotSupported = errors.New("rldp: sites hosted in TON Storage are not supported")
+)
+
+// Resolver resolves DNS records of a domain, it is implemented by dns.DNS.
+type Resolver interface {
+	Resolve(ctx context.Context, domain string) (map[dns.DNSCategory]tlb.DNSRecord, error)
+}
+
+// HTTPTransport is an http.RoundTripper which fetches TON Sites over RLDP.
+//
+// A host of a request is either a domain like "foundation.ton", resolved to an ADNL address
+// with a site DNS record, or an ADNL address itself like "<address>.adnl".
+// The ADNL address is resolved to a peer with the DHT.
+// Requests without a deadline wait for every answer for the query timeout of the RLDP transport.
+type HTTPTransport struct {
+	transport *Transport
+	gateway   *adnl.Gateway
+	dht       *adnl.DHTClient
+	resolver  Resolver
+
+	// resolvePeer is overridden in tests.
+	resolvePeer func(ctx context.Context, host string) (*adnl.Peer, error)
+
+	mu       sync.Mutex
+	peers    map[string]*adnl.Peer
+	payloads map[tl.Int256]*requestPayload
+}
+
+type requestPayload struct {
+	body  io.Reader
+	seqno uint32
+}
+
+// NewHTTPTransport returns a transport which sends HTTP requests through the RLDP transport.
+// The resolver is only required for requests to domains.
+// The transport replaces a query handler of the RLDP transport to send request bodies.
+func NewHTTPTransport(transport *Transport, dht *adnl.DHTClient, resolver Resolver) *HTTPTransport {
+	t := &HTTPTransport{
+		transport: transport,
+		gateway:   transport.gateway,
+		dht:       dht,
+		resolver:  resolver,
+		peers:     map[string]*adnl.Peer{},
+		payloads:  map[tl.Int256]*requestPayload{},
+	}
+	t.resolvePeer = t.resolve
+	transport.SetQueryHandler(t.handleQuery)
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *HTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	peer, err := t.resolvePeer(ctx, req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	request := HttpRequestC{
+		Method:      req.Method,
+		Url:         req.URL.String(),
+		HttpVersion: "HTTP/1.1",
+		Headers:     []HttpHeaderC{{Name: "Host", Value: req.URL.Host}},
+	}
+	if _, err := rand.Read(request.Id[:]); err != nil {
+		return nil, err
+	}
+	for name, values := range req.Header {
+		for _, value := range values {
+			request.Headers = append(request.Headers, HttpHeaderC{Name: name, Value: value})
+		}
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		defer req.Body.Close()
+		if req.ContentLength >= 0 && req.Header.Get("Content-Length") == "" {
+			request.Headers = append(request.Headers, HttpHeaderC{Name: "Content-Length", Value: fmt.Sprint(req.ContentLength)})
+		}
+		t.mu.Lock()
+		t.payloads[request.Id] = &requestPayload{body: req.Body}
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			delete(t.payloads, request.Id)
+			t.mu.Unlock()
+		}()
+	}
+	query, err := marshalBoxed(magicHttpRequest, request)
+	if err != nil {
+		return nil, err
+	}
+	answer, err := t.transport.Query(ctx, peer, query, maxResponseHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	var response HttpResponseC
+	if err := unmarshalBoxed(answer, magicHttpResponse, &response); err != nil {
+		return nil, fmt.Errorf("invalid http.response: %w", err)
+	}
+	res := &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, response.Reason),
+		StatusCode:    int(response.StatusCode),
+		Proto:         response.HttpVersion,
+		Header:        http.Header{},
+		Body:          http.NoBody,
+		ContentLength: -1,
+		Request:       req,
+	}
+	res.ProtoMajor, res.ProtoMinor, _ = http.ParseHTTPVersion(response.HttpVersion)
+	for _, h := range response.Headers {
+		res.Header.Add(h.Name, h.Value)
+	}
+	if response.NoPayload {
+		res.ContentLength = 0
+		return res, nil
+	}
+	res.Body = &payloadReader{ctx: ctx, transport: t.transport, peer: peer, id: request.Id, response: res}
+	return res, nil
+}
+
+func (t *HTTPTransport) resolve(ctx context.Context, host string) (*adnl.Peer, error) {
+	t.mu.Lock()
+	peer, ok := t.peers[host]
+	t.mu.Unlock()
+	if ok {
+		return peer, nil
+	}
+	var address ton.Bits256
+	if strings.HasSuffix(host, ".adnl") {
+		var err error
+		address, err = liteclient.ParseADNLAddress(host)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if t.resolver == nil {
+			return nil, fmt.Errorf("no resolver for %v", host)
+		}
+		records, err := t.resolver.Resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		record, ok := records[dns.DNSCategorySite]
+		if !ok {
+			return nil, fmt.Errorf("%v has no site record: %w", host, dns.ErrNotResolved)
+		}
+		switch record.SumType {
+		case "DNSAdnlAddress":
+			address = ton.Bits256(record.DNSAdnlAddress.Address)
+		case "DNSStorageAddress":
+			return nil, ErrStorageSiteNotSupported
+		default:
+			return nil, fmt.Errorf("unsupported site record: %v", record.SumType)
+		}
+	}
+	key, hosts, err := t.dht.ResolveADNLAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%v has no addresses", host)
+	}
+	peer, err = t.gateway.Peer(hosts[0], key)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.peers[host] = peer
+	t.mu.Unlock()
+	return peer, nil
+}
+
+// handleQuery sends parts of request bodies requested by servers with http.getNextPayloadPart.
+func (t *HTTPTransport) handleQuery(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+	var req HttpGetNextPayloadPartC
+	if err := unmarshalBoxed(query, magicHttpGetNextPayloadPart, &req); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	payload, ok := t.payloads[req.Id]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown request")
+	}
+	if req.Seqno != payload.seqno {
+		return nil, fmt.Errorf("unexpected seqno")
+	}
+	payload.seqno++
+	buf := make([]byte, min(req.MaxChunkSize, maxChunkSize))
+	n, err := io.ReadFull(payload.body, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	part := HttpPayloadPartC{Data: buf[:n], Last: err != nil}
+	return marshalBoxed(magicHttpPayloadPart, part)
+}
+
+// payloadReader reads a response body with http.getNextPayloadPart queries.
+type payloadReader struct {
+	ctx       context.Context
+	transport *Transport
+	peer      *adnl.Peer
+	id        tl.Int256
+	response  *http.Response
+	seqno     uint32
+	buf       []byte
+	last      bool
+}
+
+func (r *payloadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.last {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *payloadReader) next() error {
+	query, err := marshalBoxed(magicHttpGetNextPayloadPart, HttpGetNextPayloadPartC{Id: r.id, Seqno: r.seqno, MaxChunkSize: maxChunkSize})
+	if err != nil {
+		return err
+	}
+	answer, err := r.transport.Query(r.ctx, r.peer, query, maxChunkSize+maxResponseHeaderSize)
+	if err != nil {
+		return err
+	}
+	var part HttpPayloadPartC
+	if err := unmarshalBoxed(answer, magicHttpPayloadPart, &part); err != nil {
+		return fmt.Errorf("invalid http.payloadPart: %w", err)
+	}
+	r.seqno++
+	r.buf = part.Data
+	r.last = part.Last
+	if r.last && len(part.Trailer) > 0 {
+		r.response.Trailer = http.Header{}
+		for _, h := range part.Trailer {
+			r.response.Trailer.Add(h.Name, h.Value)
+		}
+	}
+	return nil
+}
+
+func (r *payloadReader) Close() error {
+	return nil
+}
+
+func marshalBoxed(tag uint32, v any) ([]byte, error) {
+	b, err := tl.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, tag), b...), nil
+}
+
+func unmarshalBoxed(b []byte, tag uint32, v any) error {
+	if len(b) < 4 || binary.LittleEndian.Uint32(b[:4]) != tag {
+		return fmt.Errorf("invalid tag")
+	}
+	return tl.Unmarshal(bytes.NewReader(b[4:]), v)
+}
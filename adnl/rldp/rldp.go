@@ -0,0 +1,470 @@
+// Package rldp implements RLDP, a reliable large datagram protocol on top of ADNL over UDP,
+// and HTTP over RLDP used by TON Sites.
+//
+// Messages are encoded with RaptorQ and sent as adnl.message.custom, so they are not limited by the UDP packet size.
+//
+// HTTPTransport fetches TON Sites served by an ADNL address.
+// Sites hosted in TON Storage bags are out of scope of this package:
+// fetching a bag requires the TON Storage protocol on top of RLDP and overlays,
+// so requests to such sites fail with ErrStorageSiteNotSupported.
+package rldp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xssnick/raptorq"
+
+	"github.com/tonkeeper/tongo/adnl"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	// symbolSize is a size of a RaptorQ symbol, so a rldp.messagePart fits into one ADNL packet.
+	symbolSize = 768
+	// maxPartSize is a maximum size of data encoded in one part of a transfer.
+	maxPartSize = 1 << 20
+	// DefaultQueryTimeout is a time to wait for an answer to a query if its context has no deadline.
+	DefaultQueryTimeout = time.Minute
+	// DefaultMaxAnswerSize limits the size of an answer to a query.
+	DefaultMaxAnswerSize = 10 << 20
+	// maxTransferSize limits the size of an incoming transfer regardless of answer sizes requested by queries.
+	maxTransferSize = 128 << 20
+	// maxInboundTransfers limits the number of incoming transfers in progress.
+	maxInboundTransfers = 256
+	// confirmInterval is a number of received symbols after which rldp.confirm is sent.
+	confirmInterval = 10
+	// sendInterval is a pause between batches of symbols.
+	sendInterval = 10 * time.Millisecond
+	// transferTTL is a time to wait for an incoming transfer to complete
+	// and to remember completed transfers to ignore late symbols.
+	transferTTL = time.Minute
+)
+
+var (
+	ErrTransportClosed = errors.New("rldp: transport closed")
+	ErrAnswerTooLarge  = errors.New("rldp: answer is too large")
+	ErrUnsupportedFEC  = errors.New("rldp: unsupported fec type")
+)
+
+// QueryHandler processes rldp.query received from a peer and returns an answer.
+type QueryHandler func(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error)
+
+// Transport sends and receives RLDP transfers through an ADNL gateway.
+type Transport struct {
+	gateway *adnl.Gateway
+
+	mu        sync.Mutex
+	inbound   map[tl.Int256]*inboundTransfer
+	outbound  map[tl.Int256]*outboundTransfer
+	completed map[tl.Int256]time.Time
+	queries   map[tl.Int256]*pendingQuery
+	handler   QueryHandler
+	// queryTimeout is used by queries without a deadline.
+	queryTimeout time.Duration
+	closed       chan struct{}
+	closeOnce    sync.Once
+}
+
+type pendingQuery struct {
+	maxAnswerSize uint64
+	answer        chan []byte
+}
+
+type inboundTransfer struct {
+	peer      *adnl.Peer
+	totalSize uint64
+	part      uint32
+	data      []byte
+	decoder   *raptorq.Decoder
+	fec       FecType
+	received  int
+	maxSize   uint64
+	startedAt time.Time
+}
+
+type outboundTransfer struct {
+	complete chan uint32
+}
+
+// NewTransport returns a transport that receives RLDP transfers as custom messages of the gateway.
+// The transport replaces a custom handler of the gateway.
+func NewTransport(gateway *adnl.Gateway) *Transport {
+	t := &Transport{
+		gateway:      gateway,
+		inbound:      map[tl.Int256]*inboundTransfer{},
+		outbound:     map[tl.Int256]*outboundTransfer{},
+		completed:    map[tl.Int256]time.Time{},
+		queries:      map[tl.Int256]*pendingQuery{},
+		queryTimeout: DefaultQueryTimeout,
+		closed:       make(chan struct{}),
+	}
+	gateway.SetCustomHandler(t.processCustom)
+	return t
+}
+
+// SetQueryHandler sets a handler of queries received from peers.
+func (t *Transport) SetQueryHandler(handler QueryHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handler = handler
+}
+
+// SetQueryTimeout sets a time to wait for an answer to a query whose context has no deadline.
+func (t *Transport) SetQueryTimeout(timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queryTimeout = timeout
+}
+
+// Close stops transfers in flight, the gateway is not closed.
+func (t *Transport) Close() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
+
+// Query sends rldp.query to the peer and waits for an answer not larger than maxAnswerSize.
+// If ctx has no deadline, Query waits for DefaultQueryTimeout or a timeout set with SetQueryTimeout.
+func (t *Transport) Query(ctx context.Context, peer *adnl.Peer, query []byte, maxAnswerSize uint64) ([]byte, error) {
+	var msg RldpMessage
+	msg.SumType = "RldpQuery"
+	if _, err := rand.Read(msg.RldpQuery.QueryId[:]); err != nil {
+		return nil, err
+	}
+	msg.RldpQuery.MaxAnswerSize = maxAnswerSize
+	msg.RldpQuery.Data = query
+	if _, ok := ctx.Deadline(); !ok {
+		t.mu.Lock()
+		timeout := t.queryTimeout
+		t.mu.Unlock()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+	msg.RldpQuery.Timeout = uint32(deadline.Unix())
+
+	id := msg.RldpQuery.QueryId
+	ch := make(chan []byte, 1)
+	t.mu.Lock()
+	t.queries[id] = &pendingQuery{maxAnswerSize: maxAnswerSize, answer: ch}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.queries, id)
+		t.mu.Unlock()
+	}()
+
+	b, err := tl.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var transferID tl.Int256
+	if _, err := rand.Read(transferID[:]); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go t.send(ctx, peer, transferID, b)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.closed:
+		return nil, ErrTransportClosed
+	case answer := <-ch:
+		if answer == nil {
+			return nil, ErrAnswerTooLarge
+		}
+		return answer, nil
+	}
+}
+
+// send sends data to the peer part by part,
+// each part is encoded with RaptorQ and sent until the peer confirms that it is complete.
+func (t *Transport) send(ctx context.Context, peer *adnl.Peer, transferID tl.Int256, data []byte) error {
+	transfer := &outboundTransfer{complete: make(chan uint32, 1)}
+	t.mu.Lock()
+	t.outbound[transferID] = transfer
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.outbound, transferID)
+		t.mu.Unlock()
+	}()
+
+	for part := uint32(0); uint64(part)*maxPartSize < uint64(len(data)); part++ {
+		chunk := data[part*maxPartSize : min(int(part+1)*maxPartSize, len(data))]
+		if err := t.sendPart(ctx, peer, transferID, transfer, part, uint64(len(data)), chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Transport) sendPart(ctx context.Context, peer *adnl.Peer, transferID tl.Int256, transfer *outboundTransfer, part uint32, totalSize uint64, data []byte) error {
+	encoder, err := raptorq.NewRaptorQ(symbolSize).CreateEncoder(data)
+	if err != nil {
+		return err
+	}
+	var fec FecType
+	fec.SumType = "FecRaptorQ"
+	fec.FecRaptorQ.DataSize = uint32(len(data))
+	fec.FecRaptorQ.SymbolSize = symbolSize
+	fec.FecRaptorQ.SymbolsCount = encoder.BaseSymbolsNum()
+
+	// the first batch contains all source symbols and some repair symbols,
+	// then repair symbols are sent until the peer completes the part.
+	batch := fec.FecRaptorQ.SymbolsCount + fec.FecRaptorQ.SymbolsCount/10 + 1
+	ticker := time.NewTicker(sendInterval)
+	defer ticker.Stop()
+	for seqno := uint32(0); ; {
+		for end := seqno + batch; seqno < end; seqno++ {
+			var msg RldpMessagePart
+			msg.SumType = "RldpMessagePart"
+			msg.RldpMessagePart.TransferId = transferID
+			msg.RldpMessagePart.FecType = fec
+			msg.RldpMessagePart.Part = part
+			msg.RldpMessagePart.TotalSize = totalSize
+			msg.RldpMessagePart.Seqno = seqno
+			msg.RldpMessagePart.Data = encoder.GenSymbol(seqno)
+			b, err := tl.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if err := peer.SendCustom(b); err != nil {
+				return err
+			}
+		}
+		batch = fec.FecRaptorQ.SymbolsCount/20 + 1
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.closed:
+			return ErrTransportClosed
+		case completed := <-transfer.complete:
+			if completed >= part {
+				return nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *Transport) processCustom(peer *adnl.Peer, data []byte) {
+	var msg RldpMessagePart
+	if err := tl.Unmarshal(bytes.NewReader(data), &msg); err != nil {
+		return
+	}
+	switch msg.SumType {
+	case "RldpMessagePart":
+		t.processMessagePart(peer, msg)
+	case "RldpConfirm":
+		// transfers are sent at a constant rate, so confirmations are ignored.
+	case "RldpComplete":
+		t.mu.Lock()
+		transfer, ok := t.outbound[msg.RldpComplete.TransferId]
+		t.mu.Unlock()
+		if ok {
+			select {
+			case transfer.complete <- msg.RldpComplete.Part:
+			default:
+			}
+		}
+	}
+}
+
+func (t *Transport) processMessagePart(peer *adnl.Peer, msg RldpMessagePart) {
+	part := msg.RldpMessagePart
+	t.mu.Lock()
+	if _, ok := t.completed[part.TransferId]; ok {
+		t.mu.Unlock()
+		t.sendComplete(peer, part.TransferId, part.Part)
+		return
+	}
+	transfer, ok := t.inbound[part.TransferId]
+	if !ok {
+		now := time.Now()
+		for id, tr := range t.inbound {
+			if now.Sub(tr.startedAt) > transferTTL {
+				delete(t.inbound, id)
+			}
+		}
+		if len(t.inbound) >= maxInboundTransfers {
+			t.mu.Unlock()
+			return
+		}
+		transfer = &inboundTransfer{peer: peer, totalSize: part.TotalSize, maxSize: t.maxTransferSizeLocked(), startedAt: now}
+		t.inbound[part.TransferId] = transfer
+	}
+	t.mu.Unlock()
+
+	data, done, err := transfer.add(part.FecType, part.Part, part.TotalSize, part.Seqno, part.Data)
+	if err != nil {
+		t.mu.Lock()
+		delete(t.inbound, part.TransferId)
+		t.mu.Unlock()
+		return
+	}
+	if transfer.received%confirmInterval == 0 {
+		t.sendConfirm(peer, part.TransferId, part.Part, part.Seqno)
+	}
+	if part.Part < transfer.part || done {
+		t.sendComplete(peer, part.TransferId, part.Part)
+	}
+	if !done {
+		return
+	}
+	t.mu.Lock()
+	delete(t.inbound, part.TransferId)
+	now := time.Now()
+	t.completed[part.TransferId] = now
+	for id, completedAt := range t.completed {
+		if now.Sub(completedAt) > transferTTL {
+			delete(t.completed, id)
+		}
+	}
+	t.mu.Unlock()
+	t.processMessage(peer, part.TransferId, data)
+}
+
+// maxTransferSizeLocked returns the maximum size of an incoming transfer,
+// which is the largest answer size requested by queries in flight, or DefaultMaxAnswerSize,
+// but not more than maxTransferSize.
+func (t *Transport) maxTransferSizeLocked() uint64 {
+	size := uint64(DefaultMaxAnswerSize)
+	for _, q := range t.queries {
+		size = max(size, q.maxAnswerSize)
+	}
+	return min(size, maxTransferSize)
+}
+
+func (t *Transport) processMessage(peer *adnl.Peer, transferID tl.Int256, data []byte) {
+	var msg RldpMessage
+	if err := tl.Unmarshal(bytes.NewReader(data), &msg); err != nil {
+		return
+	}
+	switch msg.SumType {
+	case "RldpAnswer":
+		t.mu.Lock()
+		q, ok := t.queries[msg.RldpAnswer.QueryId]
+		t.mu.Unlock()
+		if !ok {
+			return
+		}
+		answer := msg.RldpAnswer.Data
+		if uint64(len(answer)) > q.maxAnswerSize {
+			answer = nil
+		}
+		select {
+		case q.answer <- answer:
+		default:
+		}
+	case "RldpQuery":
+		t.mu.Lock()
+		handler := t.handler
+		t.mu.Unlock()
+		if handler == nil {
+			return
+		}
+		go t.answer(peer, transferID, msg, handler)
+	}
+}
+
+func (t *Transport) answer(peer *adnl.Peer, transferID tl.Int256, query RldpMessage, handler QueryHandler) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(int64(query.RldpQuery.Timeout), 0))
+	defer cancel()
+	res, err := handler(ctx, peer, query.RldpQuery.Data)
+	if err != nil || uint64(len(res)) > query.RldpQuery.MaxAnswerSize {
+		return
+	}
+	var msg RldpMessage
+	msg.SumType = "RldpAnswer"
+	msg.RldpAnswer.QueryId = query.RldpQuery.QueryId
+	msg.RldpAnswer.Data = res
+	b, err := tl.Marshal(msg)
+	if err != nil {
+		return
+	}
+	// an answer is sent in a transfer with an inverted ID of the query transfer.
+	for i := range transferID {
+		transferID[i] ^= 0xff
+	}
+	t.send(ctx, peer, transferID, b)
+}
+
+func (t *Transport) sendConfirm(peer *adnl.Peer, transferID tl.Int256, part, seqno uint32) {
+	var msg RldpMessagePart
+	msg.SumType = "RldpConfirm"
+	msg.RldpConfirm.TransferId = transferID
+	msg.RldpConfirm.Part = part
+	msg.RldpConfirm.Seqno = seqno
+	if b, err := tl.Marshal(msg); err == nil {
+		peer.SendCustom(b)
+	}
+}
+
+func (t *Transport) sendComplete(peer *adnl.Peer, transferID tl.Int256, part uint32) {
+	var msg RldpMessagePart
+	msg.SumType = "RldpComplete"
+	msg.RldpComplete.TransferId = transferID
+	msg.RldpComplete.Part = part
+	if b, err := tl.Marshal(msg); err == nil {
+		peer.SendCustom(b)
+	}
+}
+
+// add adds a symbol of the given part to the transfer.
+// It returns the data of the transfer once all parts are decoded.
+func (tr *inboundTransfer) add(fec FecType, part uint32, totalSize uint64, seqno uint32, symbol []byte) ([]byte, bool, error) {
+	if totalSize != tr.totalSize || totalSize > tr.maxSize {
+		return nil, false, ErrAnswerTooLarge
+	}
+	if part != tr.part {
+		// symbols of completed parts are late and symbols of next parts arrive too early.
+		return nil, false, nil
+	}
+	if fec.SumType != "FecRaptorQ" {
+		return nil, false, ErrUnsupportedFEC
+	}
+	if tr.decoder == nil {
+		// a decoder allocates memory proportional to these parameters, so they are checked before creating it.
+		if fec.FecRaptorQ.SymbolSize != symbolSize {
+			return nil, false, fmt.Errorf("%w: symbol size %v", ErrUnsupportedFEC, fec.FecRaptorQ.SymbolSize)
+		}
+		dataSize := uint64(fec.FecRaptorQ.DataSize)
+		if dataSize == 0 || dataSize > tr.maxSize || uint64(len(tr.data))+dataSize > totalSize {
+			return nil, false, fmt.Errorf("invalid part size")
+		}
+		decoder, err := raptorq.NewRaptorQ(fec.FecRaptorQ.SymbolSize).CreateDecoder(fec.FecRaptorQ.DataSize)
+		if err != nil {
+			return nil, false, err
+		}
+		tr.decoder = decoder
+		tr.fec = fec
+	} else if fec != tr.fec {
+		return nil, false, fmt.Errorf("fec type changed")
+	}
+	tr.received++
+	ready, err := tr.decoder.AddSymbol(seqno, symbol)
+	if err != nil || !ready {
+		return nil, false, err
+	}
+	ok, data, err := tr.decoder.Decode()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	tr.data = append(tr.data, data...)
+	tr.decoder = nil
+	tr.part++
+	if uint64(len(tr.data)) < tr.totalSize {
+		return nil, false, nil
+	}
+	return tr.data, true, nil
+}
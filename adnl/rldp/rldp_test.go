@@ -0,0 +1,190 @@
+package rldp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/adnl"
+)
+
+func newTestTransport(t *testing.T) (*Transport, *adnl.Gateway, ed25519.PublicKey) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	g, err := adnl.NewGateway(key)
+	if err != nil {
+		t.Fatalf("NewGateway() failed: %v", err)
+	}
+	if err := g.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	tr := NewTransport(g)
+	t.Cleanup(func() {
+		tr.Close()
+		g.Close()
+	})
+	return tr, g, pub
+}
+
+func TestTransport_Query(t *testing.T) {
+	server, serverGateway, serverKey := newTestTransport(t)
+	client, clientGateway, _ := newTestTransport(t)
+
+	answer := make([]byte, 300<<10)
+	rand.Read(answer)
+	server.SetQueryHandler(func(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+		if string(query) != "get" {
+			return nil, errors.New("unknown query")
+		}
+		return answer, nil
+	})
+	peer, err := clientGateway.Peer(serverGateway.Addr().String(), serverKey)
+	if err != nil {
+		t.Fatalf("Peer() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.Query(ctx, peer, []byte("get"), DefaultMaxAnswerSize)
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if !bytes.Equal(res, answer) {
+		t.Fatalf("answer mismatch")
+	}
+	// the server doesn't answer if the answer is larger than requested.
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = client.Query(ctx, peer, []byte("get"), 1024)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want DeadlineExceeded, got %v", err)
+	}
+	// a query without a deadline doesn't wait forever.
+	client.SetQueryTimeout(500 * time.Millisecond)
+	_, err = client.Query(context.Background(), peer, []byte("get"), 1024)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want DeadlineExceeded, got %v", err)
+	}
+}
+
+// serveHTTP answers http.request and http.getNextPayloadPart queries with a fixed body split into chunks.
+func serveHTTP(t *testing.T, server *Transport, body []byte) {
+	server.SetQueryHandler(func(ctx context.Context, peer *adnl.Peer, query []byte) ([]byte, error) {
+		var req HttpRequestC
+		if err := unmarshalBoxed(query, magicHttpRequest, &req); err == nil {
+			if req.Method != http.MethodGet || !strings.HasSuffix(req.Url, "/index.html") {
+				t.Errorf("unexpected request: %v %v", req.Method, req.Url)
+			}
+			return marshalBoxed(magicHttpResponse, HttpResponseC{
+				HttpVersion: "HTTP/1.1",
+				StatusCode:  200,
+				Reason:      "OK",
+				Headers:     []HttpHeaderC{{Name: "Content-Type", Value: "text/html"}},
+			})
+		}
+		var part HttpGetNextPayloadPartC
+		if err := unmarshalBoxed(query, magicHttpGetNextPayloadPart, &part); err != nil {
+			return nil, err
+		}
+		offset := int(part.Seqno) * int(part.MaxChunkSize)
+		end := min(offset+int(part.MaxChunkSize), len(body))
+		return marshalBoxed(magicHttpPayloadPart, HttpPayloadPartC{Data: body[offset:end], Last: end == len(body)})
+	})
+}
+
+func TestHTTPTransport(t *testing.T) {
+	server, serverGateway, serverKey := newTestTransport(t)
+	client, clientGateway, _ := newTestTransport(t)
+
+	body := bytes.Repeat([]byte("<p>hello</p>"), 30000)
+	serveHTTP(t, server, body)
+	peer, err := clientGateway.Peer(serverGateway.Addr().String(), serverKey)
+	if err != nil {
+		t.Fatalf("Peer() failed: %v", err)
+	}
+	transport := NewHTTPTransport(client, nil, nil)
+	transport.resolvePeer = func(ctx context.Context, host string) (*adnl.Peer, error) {
+		if host != "example.ton" {
+			t.Errorf("unexpected host: %v", host)
+		}
+		return peer, nil
+	}
+	cli := http.Client{Transport: transport, Timeout: 10 * time.Second}
+
+	res, err := cli.Get("http://example.ton/index.html")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/html" {
+		t.Fatalf("unexpected response: %v %v", res.Status, res.Header)
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if !bytes.Equal(b, body) {
+		t.Fatalf("body mismatch: got %v bytes, want %v", len(b), len(body))
+	}
+}
+
+func TestInboundTransfer_InvalidFEC(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbolSize uint32
+		dataSize   uint32
+	}{
+		{name: "huge symbol", symbolSize: 1 << 31, dataSize: 100},
+		{name: "small symbol", symbolSize: 1, dataSize: 100},
+		{name: "empty part", symbolSize: symbolSize, dataSize: 0},
+		{name: "part larger than transfer", symbolSize: symbolSize, dataSize: 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &inboundTransfer{totalSize: 1000, maxSize: DefaultMaxAnswerSize}
+			var fec FecType
+			fec.SumType = "FecRaptorQ"
+			fec.FecRaptorQ.SymbolSize = tt.symbolSize
+			fec.FecRaptorQ.DataSize = tt.dataSize
+			fec.FecRaptorQ.SymbolsCount = 1
+			if _, _, err := tr.add(fec, 0, 1000, 0, make([]byte, 10)); err == nil {
+				t.Fatalf("add() must fail")
+			}
+			if tr.decoder != nil {
+				t.Fatalf("decoder must not be created")
+			}
+		})
+	}
+}
+
+func TestTransport_MaxInboundTransfers(t *testing.T) {
+	tr, _, _ := newTestTransport(t)
+	var fec FecType
+	fec.SumType = "FecRaptorQ"
+	fec.FecRaptorQ.SymbolSize = symbolSize
+	fec.FecRaptorQ.DataSize = 1000
+	fec.FecRaptorQ.SymbolsCount = 2
+	for i := 0; i < maxInboundTransfers+10; i++ {
+		var msg RldpMessagePart
+		msg.SumType = "RldpMessagePart"
+		rand.Read(msg.RldpMessagePart.TransferId[:])
+		msg.RldpMessagePart.FecType = fec
+		msg.RldpMessagePart.TotalSize = 1000
+		msg.RldpMessagePart.Data = make([]byte, symbolSize)
+		tr.processMessagePart(nil, msg)
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.inbound) != maxInboundTransfers {
+		t.Fatalf("want %v transfers in progress, got %v", maxInboundTransfers, len(tr.inbound))
+	}
+}
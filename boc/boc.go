@@ -291,6 +291,12 @@ func SerializeBoc(cell *Cell, idx bool, hasCrc32 bool, cacheBits bool, flags uin
 	return bag.serializeBoc([]*Cell{cell}, idx, hasCrc32, cacheBits, flags)
 }
 
+// SerializeBocRoots works like SerializeBoc but puts several root cells into one boc.
+func SerializeBocRoots(roots []*Cell, idx bool, hasCrc32 bool, cacheBits bool, flags uint) ([]byte, error) {
+	bag := newBagOfCells()
+	return bag.serializeBoc(roots, idx, hasCrc32, cacheBits, flags)
+}
+
 // bagOfCells serializes cells to a boc.
 //
 // the serialization algorithms is a golang version of
@@ -3,7 +3,6 @@ package boc
 import (
 	"crypto/sha256"
 	"encoding/binary"
-	"fmt"
 )
 
 // immutableCell provides a convenient way to calculate a cell's hash and depth.
@@ -162,11 +161,8 @@ func (ic *immutableCell) Depth(level int) int {
 
 // pruneCells return the current subtree (which this cell represents) with pruned cells.
 // if this cell is pruned, "pruneCells" returns a new pruned branch cell instead of this cell.
-// As of now, this function doesn't work with MerkleProofCell and MerkleUpdateCell.
+// As of now, cells inside MerkleProofCell and MerkleUpdateCell can't be pruned, such subtrees are kept as is.
 func (ic *immutableCell) pruneCells(pruned map[*immutableCell]struct{}) (*Cell, error) {
-	if ic.cellType == MerkleProofCell || ic.cellType == MerkleUpdateCell {
-		return nil, fmt.Errorf("unsupported cell type: %v", ic.cellType)
-	}
 	if _, ok := pruned[ic]; ok {
 		// we are pruned
 		// let's replace this cell with a pruned branch cell
@@ -202,12 +198,17 @@ func (ic *immutableCell) pruneCells(pruned map[*immutableCell]struct{}) (*Cell,
 		mask:     ic.mask,
 	}
 	mask := ic.mask
+	isMerkle := ic.cellType == MerkleProofCell || ic.cellType == MerkleUpdateCell
+	if isMerkle {
+		// pruning below a merkle cell requires pruned branches of higher levels.
+		pruned = nil
+	}
 	for i, ref := range ic.refs {
 		cell, err := ref.pruneCells(pruned)
 		if err != nil {
 			return nil, err
 		}
-		if cell.mask > 0 {
+		if cell.mask > 0 && !isMerkle {
 			mask |= cell.mask
 		}
 		res.refs[i] = cell
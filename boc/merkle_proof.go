@@ -1,5 +1,11 @@
 package boc
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
 type MerkleProver struct {
 	root *immutableCell
 }
@@ -51,3 +57,43 @@ func (c *Cursor) Prune() {
 func (c *Cursor) Ref(ref int) *Cursor {
 	return &Cursor{cell: c.cell.refs[ref], pruned: c.pruned}
 }
+
+var ErrInvalidMerkleProof = errors.New("invalid merkle proof")
+
+// VerifyMerkleProof checks that the given cell is a merkle proof of a cell tree with the given root hash.
+// It returns the virtual root of the proof.
+// The virtual root can be decoded as the original tree, keeping in mind that some of its branches can be pruned.
+func VerifyMerkleProof(proof *Cell, rootHash [32]byte) (*Cell, error) {
+	if proof.CellType() != MerkleProofCell || proof.RefsSize() != 1 {
+		return nil, ErrInvalidMerkleProof
+	}
+	if proof.bits.len < 8+256 {
+		return nil, ErrInvalidMerkleProof
+	}
+	virtualRoot := proof.refs[0]
+	hash, err := virtualRoot.VirtualHash()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(proof.bits.buf[1:33], hash[:]) {
+		return nil, fmt.Errorf("%w: declared hash doesn't match the virtual root", ErrInvalidMerkleProof)
+	}
+	if hash != rootHash {
+		return nil, fmt.Errorf("%w: root hash mismatch", ErrInvalidMerkleProof)
+	}
+	virtualRoot.ResetCounters()
+	return virtualRoot, nil
+}
+
+// VirtualHash returns a hash of the original cell.
+// For a pruned branch cell or a cell containing pruned branches, it is a hash of the cell that was pruned.
+// For an ordinary cell without pruned branches, it is equal to Hash().
+func (c *Cell) VirtualHash() ([32]byte, error) {
+	imc, err := newImmutableCell(c, map[*Cell]*immutableCell{})
+	if err != nil {
+		return [32]byte{}, err
+	}
+	var h [32]byte
+	copy(h[:], imc.Hash(0))
+	return h, nil
+}
@@ -0,0 +1,64 @@
+package boc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyMerkleProof(t *testing.T) {
+	root := NewCell()
+	_ = root.WriteUint(1, 32)
+	for i := 0; i < 2; i++ {
+		ref := NewCell()
+		_ = ref.WriteUint(uint64(i+100), 64)
+		_ = root.AddRef(ref)
+	}
+	rootHash, err := root.Hash256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prover, err := NewMerkleProver(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := prover.Cursor()
+	cursor.Ref(1).Prune()
+	proofBoc, err := prover.CreateProof(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells, err := DeserializeBoc(proofBoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	virtualRoot, err := VerifyMerkleProof(cells[0], rootHash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	if virtualRoot.Refs()[1].CellType() != PrunedBranchCell {
+		t.Fatalf("second ref must be pruned")
+	}
+	if virtualRoot.Refs()[0].CellType() != OrdinaryCell {
+		t.Fatalf("first ref must be kept")
+	}
+	value, err := virtualRoot.Refs()[0].ReadUint(64)
+	if err != nil || value != 100 {
+		t.Fatalf("unexpected value: %v, %v", value, err)
+	}
+	prunedHash, err := virtualRoot.Refs()[1].VirtualHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalHash, _ := root.Refs()[1].Hash256()
+	if prunedHash != originalHash {
+		t.Fatalf("pruned cell must keep the hash of the original cell")
+	}
+
+	var wrongHash [32]byte
+	if _, err := VerifyMerkleProof(cells[0], wrongHash); !errors.Is(err, ErrInvalidMerkleProof) {
+		t.Fatalf("want ErrInvalidMerkleProof, got %v", err)
+	}
+	if _, err := VerifyMerkleProof(root, rootHash); !errors.Is(err, ErrInvalidMerkleProof) {
+		t.Fatalf("want ErrInvalidMerkleProof, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+
+	"github.com/tonkeeper/tongo/tl/parser"
+)
+
+func main() {
+	input := flag.String("input", "adnl/adnl_api.tl", "path to a TL scheme")
+	outputDir := flag.String("output", "adnl", "directory to write generated files")
+	pkg := flag.String("package", "adnl", "name of the generated package")
+	flag.Parse()
+
+	scheme, err := os.ReadFile(*input)
+	if err != nil {
+		panic(err)
+	}
+	parsed, err := parser.Parse(string(scheme))
+	if err != nil {
+		panic(err)
+	}
+	knownTypes := map[string]parser.DefaultType{
+		"#":      {Name: "uint32"},
+		"int":    {Name: "uint32"},
+		"int128": {Name: "tl.Int128"},
+		"int256": {Name: "tl.Int256"},
+		"long":   {Name: "uint64"},
+		"bytes":  {Name: "[]byte", IsPointerType: true},
+		"Bool":   {Name: "bool"},
+		"string": {Name: "string"},
+	}
+	g := parser.NewGenerator(knownTypes, "")
+	types, err := g.LoadTypes(parsed.Declarations)
+	if err != nil {
+		panic(err)
+	}
+
+	src := `// Code generated - DO NOT EDIT.
+
+package ` + *pkg + `
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/tonkeeper/tongo/tl"
+	"io"
+)
+` + types
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		panic(err)
+	}
+	outPath := filepath.Join(*outputDir, "generated.go")
+	if err := os.WriteFile(outPath, formatted, 0644); err != nil {
+		panic(err)
+	}
+	fmt.Println(outPath)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/tonkeeper/tongo/ton"
 )
 
 type liteServerConfig struct {
@@ -19,15 +21,81 @@ type liteServerId struct {
 	Key  string `json:"key"`
 }
 
+type blockIDConfig struct {
+	Workchain int32  `json:"workchain"`
+	Shard     int64  `json:"shard"`
+	Seqno     uint32 `json:"seqno"`
+	RootHash  string `json:"root_hash"`
+	FileHash  string `json:"file_hash"`
+}
+
+type validatorConfig struct {
+	ZeroState blockIDConfig   `json:"zero_state"`
+	InitBlock *blockIDConfig  `json:"init_block"`
+	Hardforks []blockIDConfig `json:"hardforks"`
+}
+
+type dhtNodeConfig struct {
+	ID       liteServerId `json:"id"`
+	AddrList struct {
+		Addrs []struct {
+			Type string `json:"@type"`
+			Ip   int64  `json:"ip"`
+			Port int64  `json:"port"`
+		} `json:"addrs"`
+	} `json:"addr_list"`
+	Version   int32  `json:"version"`
+	Signature []byte `json:"signature"`
+}
+
+type dhtConfig struct {
+	K           int `json:"k"`
+	A           int `json:"a"`
+	StaticNodes struct {
+		Nodes []dhtNodeConfig `json:"nodes"`
+	} `json:"static_nodes"`
+}
+
 type configGlobal struct {
 	LiteServers []liteServerConfig `json:"liteservers"`
-	//Validator   ValidatorConfig  `json:"validator"`
+	Validator   *validatorConfig   `json:"validator"`
+	DHT         *dhtConfig         `json:"dht"`
 }
 
 // GlobalConfigurationFile contains global configuration of the TON Blockchain.
 // It is shared by all nodes and includes information about network, init block, hardforks, etc.
 type GlobalConfigurationFile struct {
 	LiteServers []LiteServer
+	Validator   ValidatorConfig
+	DHT         DHTConfig
+}
+
+// DHTConfig contains parameters of the DHT and its nodes used to bootstrap a DHT client.
+type DHTConfig struct {
+	// K is a number of nodes a DHT value is stored at.
+	K int
+	// A is a number of nodes queried in parallel during a lookup.
+	A           int
+	StaticNodes []DHTNode
+}
+
+// DHTNode is a DHT node signed by its key.
+type DHTNode struct {
+	// Key is a base64-encoded ed25519 public key of the node.
+	Key string
+	// Hosts are UDP addresses of the node in the "ip:port" form.
+	Hosts     []string
+	Version   int32
+	Signature []byte
+}
+
+// ValidatorConfig contains blocks which are trusted by all nodes of the network.
+type ValidatorConfig struct {
+	ZeroState ton.BlockIDExt
+	// InitBlock is a key block which can be used as a starting point for proof checks.
+	// If the configuration file doesn't contain init_block, InitBlock is equal to ZeroState.
+	InitBlock ton.BlockIDExt
+	Hardforks []ton.BlockIDExt
 }
 
 // LiteServer TODO: clarify struct
@@ -52,14 +120,41 @@ func convertToLiteServerOptions(server liteServerConfig) (LiteServer, error) {
 	if server.Ip > 0xFFFF_FFFF {
 		return LiteServer{}, fmt.Errorf("only IPv4 supported")
 	}
-	ipBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(ipBytes, uint32(server.Ip))
 	return LiteServer{
-		Host: fmt.Sprintf("%v.%v.%v.%v:%d", ipBytes[0], ipBytes[1], ipBytes[2], ipBytes[3], server.Port),
+		Host: hostFromIP(uint32(server.Ip), server.Port),
 		Key:  server.ID.Key,
 	}, nil
 }
 
+func hostFromIP(ip uint32, port int64) string {
+	ipBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(ipBytes, ip)
+	return fmt.Sprintf("%v.%v.%v.%v:%d", ipBytes[0], ipBytes[1], ipBytes[2], ipBytes[3], port)
+}
+
+func convertDHTConfig(conf dhtConfig) DHTConfig {
+	res := DHTConfig{K: conf.K, A: conf.A}
+	for _, node := range conf.StaticNodes.Nodes {
+		if node.ID.Type != "pub.ed25519" {
+			continue
+		}
+		dhtNode := DHTNode{
+			Key:       node.ID.Key,
+			Version:   node.Version,
+			Signature: node.Signature,
+		}
+		for _, addr := range node.AddrList.Addrs {
+			if addr.Type != "adnl.address.udp" {
+				continue
+			}
+			// ip is a signed 32-bit integer in the config.
+			dhtNode.Hosts = append(dhtNode.Hosts, hostFromIP(uint32(addr.Ip), addr.Port))
+		}
+		res.StaticNodes = append(res.StaticNodes, dhtNode)
+	}
+	return res
+}
+
 func ParseConfig(data io.Reader) (*GlobalConfigurationFile, error) {
 	var conf configGlobal
 	err := json.NewDecoder(data).Decode(&conf)
@@ -77,5 +172,56 @@ func ParseConfig(data io.Reader) (*GlobalConfigurationFile, error) {
 	if len(options.LiteServers) == 0 {
 		return nil, fmt.Errorf("no one supported liteservers")
 	}
+	if conf.Validator != nil {
+		validator, err := convertValidatorConfig(*conf.Validator)
+		if err != nil {
+			return nil, err
+		}
+		options.Validator = validator
+	}
+	if conf.DHT != nil {
+		options.DHT = convertDHTConfig(*conf.DHT)
+	}
 	return &options, nil
 }
+
+func convertValidatorConfig(conf validatorConfig) (ValidatorConfig, error) {
+	var res ValidatorConfig
+	var err error
+	res.ZeroState, err = convertBlockID(conf.ZeroState)
+	if err != nil {
+		return ValidatorConfig{}, fmt.Errorf("invalid zero_state: %w", err)
+	}
+	res.InitBlock = res.ZeroState
+	if conf.InitBlock != nil {
+		res.InitBlock, err = convertBlockID(*conf.InitBlock)
+		if err != nil {
+			return ValidatorConfig{}, fmt.Errorf("invalid init_block: %w", err)
+		}
+	}
+	for _, hardfork := range conf.Hardforks {
+		blockID, err := convertBlockID(hardfork)
+		if err != nil {
+			return ValidatorConfig{}, fmt.Errorf("invalid hardfork: %w", err)
+		}
+		res.Hardforks = append(res.Hardforks, blockID)
+	}
+	return res, nil
+}
+
+func convertBlockID(block blockIDConfig) (ton.BlockIDExt, error) {
+	res := ton.BlockIDExt{
+		BlockID: ton.BlockID{
+			Workchain: block.Workchain,
+			Shard:     uint64(block.Shard),
+			Seqno:     block.Seqno,
+		},
+	}
+	if err := res.RootHash.FromBase64(block.RootHash); err != nil {
+		return ton.BlockIDExt{}, err
+	}
+	if err := res.FileHash.FromBase64(block.FileHash); err != nil {
+		return ton.BlockIDExt{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestParseConfig(t *testing.T) {
+	data := `{
+  "liteservers": [
+    {"ip": 84478511, "port": 19949, "id": {"@type": "pub.ed25519", "key": "n4VDnSCUuSpjnCyUk9e3QOOd6o0ItSWYbTnW3Wnn8wk="}}
+  ],
+  "validator": {
+    "@type": "validator.config.global",
+    "zero_state": {
+      "workchain": -1,
+      "shard": -9223372036854775808,
+      "seqno": 0,
+      "root_hash": "F6OpKZKqvqeFp6CQmFomXNMfMj2EnaUSOXN+Mh+wVWk=",
+      "file_hash": "XplPz01CXAps5qeSWUtxcyBfdAo5zVb1N979KLSKD24="
+    },
+    "init_block": {
+      "workchain": -1,
+      "shard": -9223372036854775808,
+      "seqno": 34835953,
+      "root_hash": "E92nMvvvsGeeChuKQ8xzPJMYJ+4SGDQDzAdFeA0Ftas=",
+      "file_hash": "CCYHHoT+bILoFstrUEZpoOY/zWOW8ZVYjWcvfgNQuNA="
+    },
+    "hardforks": []
+  },
+  "dht": {
+    "@type": "dht.config.global",
+    "k": 6,
+    "a": 3,
+    "static_nodes": {
+      "@type": "dht.nodes",
+      "nodes": [
+        {
+          "@type": "dht.node",
+          "id": {"@type": "pub.ed25519", "key": "6PGkPQSbyFp12esf1NqmDOaLoFA8i9+Mp5+cAx5wtTU="},
+          "addr_list": {
+            "@type": "adnl.addressList",
+            "addrs": [{"@type": "adnl.address.udp", "ip": -1185526007, "port": 22096}],
+            "version": 0, "reinit_date": 0, "priority": 0, "expire_at": 0
+          },
+          "version": -1,
+          "signature": "L4N1+dzXLlkmT5iPnvsmsixzXU0L6kPKApqMdcrGP5d9ssMhn69SzHFK+yIzvG6zQ9oRb4TnqPBaKShjjj2OBg=="
+        }
+      ]
+    }
+  }
+}`
+	conf, err := ParseConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseConfig() failed: %v", err)
+	}
+	if len(conf.LiteServers) != 1 || conf.LiteServers[0].Host != "5.9.10.47:19949" {
+		t.Fatalf("unexpected lite servers: %v", conf.LiteServers)
+	}
+	zeroState := ton.MustParseBlockID("(-1,8000000000000000,0)")
+	if conf.Validator.ZeroState.BlockID != zeroState {
+		t.Fatalf("unexpected zero state: %v", conf.Validator.ZeroState)
+	}
+	initBlock := conf.Validator.InitBlock
+	if initBlock.Workchain != -1 || initBlock.Seqno != 34835953 || initBlock.Shard != 0x8000000000000000 {
+		t.Fatalf("unexpected init block: %v", initBlock)
+	}
+	if initBlock.RootHash.Base64() != "E92nMvvvsGeeChuKQ8xzPJMYJ+4SGDQDzAdFeA0Ftas=" {
+		t.Fatalf("unexpected init block root hash: %v", initBlock.RootHash.Base64())
+	}
+	if conf.DHT.K != 6 || len(conf.DHT.StaticNodes) != 1 {
+		t.Fatalf("unexpected dht config: %v", conf.DHT)
+	}
+	node := conf.DHT.StaticNodes[0]
+	if len(node.Hosts) != 1 || node.Hosts[0] != "185.86.79.9:22096" || node.Version != -1 || len(node.Signature) != 64 {
+		t.Fatalf("unexpected dht node: %v", node)
+	}
+}
@@ -0,0 +1,386 @@
+package multisig
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/tonkeeper/tongo/abi"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/wallet"
+)
+
+// code of multisig-contract-v2, see https://github.com/ton-blockchain/multisig-contract-v2.
+const multisigCode = "te6ccgECEgEABJUAART/APSkE/S88sgLAQIBYgIDAsrQM9DTAwFxsJJfA+D6QDAi10nAAJJfA+AC0x8BIMAAkl8E4AHTPwHtRNDT/wEB0wcBAdTTBwEB9ATSAAEB0SiCEPcYUQ+64w8FREPIUAYBy/9QBAHLBxLMAQHLB/QAAQHKAMntVAQFAgEgDA0BnjgG0/8BKLOOEiCE/7qSMCSWUwW68uPw4gWkBd4B0gABAdMHAQHTLwEB1NEjkSaRKuJSMHj0Dm+h8uPvHscF8uPvIPgjvvLgbyD4I6FUbXAGApo2OCaCEHUJf126jroGghCjLFm/uo6p+CgYxwXy4GUD1NEQNBA2RlD4AH+OjSF49HxvpSCRMuMNAbPmWxA1UDSSNDbiUFQT4w1AFVAzBAoJAdT4BwODDPlBMAODCPlBMPgHUAahgSf4AaBw+DaBEgZw+DaggSvscPg2oIEdmHD4NqAipgYioIEFOSagJ6Bw+DgjpIECmCegcPg4oAOmBliggQbgUAWgUAWgQwNw+DdZoAGgHL7y4GT4KFADBwK4AXACyFjPFgEBy//JiCLIywH0APQAywDJcCH5AHTIywISygfL/8nQyIIQnHP7olgKAssfyz8mAcsHUlDMUAsByy8bzCoBygAKlRkBywcIkTDiECRwQImAGIBQ2zwRCACSjkXIWAHLBVAFzxZQA/oCVHEjI+1E7UXtR59byFADzxfJE3dQA8trzMztZ+1l7WR0f+0RmHYBy2vMAc8X7UHt8QHy/8kB+wDbBgLiNgTT/wEB0y8BAdMHAQHT/wEB1NH4KFAFAXACyFjPFgEBy//JiCLIywH0APQAywDJcAH5AHTIywISygfL/8nQG8cF8uBlJvkAGrpRk74ZsPLgZgf4I77y4G9EFFBW+AB/jo0hePR8b6UgkTLjDQGz5lsRCgH6AtdM0NMfASCCEPE4Hlu6jmqCEB0M+9O6jl5sRNMHAQHUIX9wjhdREnj0fG+lMiGZUwK68uBnAqQC3gGzEuZsISDCAPLgbiPCAPLgbVMwu/LgbQH0BCF/cI4XURJ49HxvpTIhmVMCuvLgZwKkAt4BsxLmbCEw0VUjkTDi4w0LABAw0wfUAvsA0QFDv3T/aiaGn/gIDpg4CA6mmDgID6AmkAAIDoiBqvgoD8EdDA4CAWYPEADC+AcDgwz5QTADgwj5QTD4B1AGoYEn+AGgcPg2gRIGcPg2oIEr7HD4NqCBHZhw+DagIqYGIqCBBTkmoCegcPg4I6SBApgnoHD4OKADpgZYoIEG4FAFoFAFoEMDcPg3WaABoADxsMr7UTQ0/8BAdMHAQHU0wcBAfQE0gABAdEjf3COF1ESePR8b6UyIZlTArry4GcCpALeAbMS5mwhUjC68uBsIX9wjhdREnj0fG+lMiGZUwK68uBnAqQC3gGzEuZsITAiwgDy4G4kwgDy4G1SQ7vy4G0BkjN/kQPiA4AFZsMn+CgBAXACyFjPFgEBy//JiCLIywH0APQAywDJcAH5AHTIywISygfL/8nQgEQhCAmMFqAYchWwszwXcsN9YFccUdYcFZ8q18EnjQLz1klHz"
+
+// orderCodeHash is a hash of the order contract's code.
+// The multisig deploys orders with a library cell pointing to this code.
+const orderCodeHash = "6305a8061c856c2ccf05dcb0df5815c71475870567cab5f049e340bcf59251f3"
+
+const (
+	// DefaultNewOrderAmount is attached to a new order message to pay for deploying the order contract.
+	DefaultNewOrderAmount = ton.OneGRAM / 5
+	// DefaultApproveAmount is attached to an approve message.
+	DefaultApproveAmount = ton.OneGRAM / 10
+	// MaxSigners is the maximum number of signers and proposers of a multisig.
+	MaxSigners = 255
+)
+
+var (
+	ErrNotInitialized  = errors.New("multisig: contract is not initialized")
+	ErrInvalidConfig   = errors.New("multisig: invalid config")
+	ErrOrderNotCreated = errors.New("multisig: order is not created yet")
+)
+
+type blockchain interface {
+	GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error)
+}
+
+// Config describes signers and proposers of a new multisig.
+type Config struct {
+	// Threshold is a number of signers' approvals required to execute an order.
+	Threshold uint8
+	// Signers can create and approve orders.
+	Signers []ton.AccountID
+	// Proposers can only create orders.
+	Proposers []ton.AccountID
+	// AllowArbitraryOrderSeqno allows creating orders with any seqno instead of the next one.
+	AllowArbitraryOrderSeqno bool
+}
+
+// Data represents data of a multisig contract.
+type Data struct {
+	NextOrderSeqno           tlb.Uint256
+	Threshold                uint8
+	Signers                  tlb.Hashmap[tlb.Uint8, tlb.MsgAddress] `tlb:"^"`
+	SignersNum               uint8
+	Proposers                tlb.HashmapE[tlb.Uint8, tlb.MsgAddress]
+	AllowArbitraryOrderSeqno bool
+}
+
+// SignerIndex returns an index of the given account in the list of signers.
+func (d Data) SignerIndex(account ton.AccountID) (uint8, bool) {
+	return indexOf(d.Signers.Items(), account)
+}
+
+// ProposerIndex returns an index of the given account in the list of proposers.
+func (d Data) ProposerIndex(account ton.AccountID) (uint8, bool) {
+	return indexOf(d.Proposers.Items(), account)
+}
+
+func indexOf(items []tlb.HashmapItem[tlb.Uint8, tlb.MsgAddress], account ton.AccountID) (uint8, bool) {
+	for _, item := range items {
+		addr, err := ton.AccountIDFromTlb(item.Value)
+		if err == nil && addr != nil && *addr == account {
+			return uint8(item.Key), true
+		}
+	}
+	return 0, false
+}
+
+// OrderData represents data of an order contract.
+// Fields after OrderSeqno are only set once the order is initialized by the multisig.
+type OrderData struct {
+	Multisig         tlb.MsgAddress
+	OrderSeqno       tlb.Uint256
+	Threshold        uint8
+	SentForExecution bool
+	Signers          tlb.Hashmap[tlb.Uint8, tlb.MsgAddress] `tlb:"^"`
+	ApprovalsMask    tlb.Uint256
+	ApprovalsNum     uint8
+	ExpirationDate   tlb.Uint48
+	Order            abi.MultisigOrder `tlb:"^"`
+}
+
+// IsApprovedBy reports whether the signer with the given index has approved the order.
+func (o OrderData) IsApprovedBy(index uint8) bool {
+	mask := big.Int(o.ApprovalsMask)
+	return mask.Bit(int(index)) == 1
+}
+
+func addressList(accounts []ton.AccountID) ([]tlb.Uint8, []tlb.MsgAddress) {
+	keys := make([]tlb.Uint8, len(accounts))
+	values := make([]tlb.MsgAddress, len(accounts))
+	for i, account := range accounts {
+		keys[i] = tlb.Uint8(i)
+		values[i] = account.ToMsgAddress()
+	}
+	return keys, values
+}
+
+// StateInit returns a state init of a multisig with the given config.
+func StateInit(config Config) (tlb.StateInit, error) {
+	if len(config.Signers) == 0 || len(config.Signers) > MaxSigners || len(config.Proposers) > MaxSigners {
+		return tlb.StateInit{}, fmt.Errorf("%w: number of signers must be in range [1, %v]", ErrInvalidConfig, MaxSigners)
+	}
+	if config.Threshold == 0 || int(config.Threshold) > len(config.Signers) {
+		return tlb.StateInit{}, fmt.Errorf("%w: threshold must be in range [1, %v]", ErrInvalidConfig, len(config.Signers))
+	}
+	data := Data{
+		Threshold:                config.Threshold,
+		Signers:                  tlb.NewHashmap(addressList(config.Signers)),
+		SignersNum:               uint8(len(config.Signers)),
+		Proposers:                tlb.NewHashmapE(addressList(config.Proposers)),
+		AllowArbitraryOrderSeqno: config.AllowArbitraryOrderSeqno,
+	}
+	dataCell := boc.NewCell()
+	if err := tlb.Marshal(dataCell, data); err != nil {
+		return tlb.StateInit{}, err
+	}
+	code, err := boc.DeserializeSinglRootBase64(multisigCode)
+	if err != nil {
+		return tlb.StateInit{}, err
+	}
+	return newStateInit(code, dataCell), nil
+}
+
+// Address returns an address of a multisig with the given config.
+func Address(workchain int32, config Config) (ton.AccountID, error) {
+	stateInit, err := StateInit(config)
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	return stateInitAddress(workchain, stateInit)
+}
+
+// DeployMessage returns a message which deploys a multisig with the given config.
+func DeployMessage(workchain int32, config Config, amount tlb.Grams) (wallet.Message, error) {
+	stateInit, err := StateInit(config)
+	if err != nil {
+		return wallet.Message{}, err
+	}
+	address, err := stateInitAddress(workchain, stateInit)
+	if err != nil {
+		return wallet.Message{}, err
+	}
+	return wallet.Message{
+		Amount:  amount,
+		Address: address,
+		Init:    &stateInit,
+		Mode:    wallet.DefaultMessageMode,
+	}, nil
+}
+
+// OrderAddress returns an address of an order with the given seqno created by the multisig.
+func OrderAddress(multisig ton.AccountID, orderSeqno *big.Int) (ton.AccountID, error) {
+	data := boc.NewCell()
+	if err := tlb.Marshal(data, multisig.ToMsgAddress()); err != nil {
+		return ton.AccountID{}, err
+	}
+	if err := data.WriteBigUint(orderSeqno, 256); err != nil {
+		return ton.AccountID{}, err
+	}
+	hash, err := hex.DecodeString(orderCodeHash)
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	code := boc.NewCellExotic(boc.LibraryCell)
+	if err := code.WriteUint(uint64(boc.LibraryCell), 8); err != nil {
+		return ton.AccountID{}, err
+	}
+	if err := code.WriteBytes(hash); err != nil {
+		return ton.AccountID{}, err
+	}
+	return stateInitAddress(multisig.Workchain, newStateInit(code, data))
+}
+
+func newStateInit(code, data *boc.Cell) tlb.StateInit {
+	var init tlb.StateInit
+	init.Code.Exists = true
+	init.Code.Value.Value = *code
+	init.Data.Exists = true
+	init.Data.Value.Value = *data
+	return init
+}
+
+func stateInitAddress(workchain int32, stateInit tlb.StateInit) (ton.AccountID, error) {
+	c := boc.NewCell()
+	if err := tlb.Marshal(c, stateInit); err != nil {
+		return ton.AccountID{}, err
+	}
+	hash, err := c.Hash256()
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	return ton.AccountID{Workchain: workchain, Address: hash}, nil
+}
+
+// SendMessage returns an order action which makes the multisig send the given message.
+func SendMessage(message wallet.Sendable) (abi.MultisigSendMessageAction, error) {
+	raw, err := wallet.ToRawMessage(message)
+	if err != nil {
+		return abi.MultisigSendMessageAction{}, err
+	}
+	var msg abi.MessageRelaxed
+	if err := tlb.Unmarshal(raw.Message, &msg); err != nil {
+		return abi.MultisigSendMessageAction{}, err
+	}
+	action := abi.MultisigSendMessageAction{SumType: "SendMessage"}
+	action.SendMessage.Field0 = abi.SendMessageAction{Mode: raw.Mode, Message: msg}
+	return action, nil
+}
+
+// UpdateParams returns an order action which replaces the multisig's threshold, signers and proposers.
+func UpdateParams(threshold uint8, signers, proposers []ton.AccountID) abi.MultisigSendMessageAction {
+	action := abi.MultisigSendMessageAction{SumType: "UpdateMultisigParam"}
+	action.UpdateMultisigParam.Threshold = threshold
+	action.UpdateMultisigParam.Signers = tlb.NewHashmap(addressList(signers))
+	action.UpdateMultisigParam.Proposers = tlb.NewHashmapE(addressList(proposers))
+	return action
+}
+
+// NewOrderMessage is sent by a signer or a proposer to a multisig to create an order.
+// If it is sent by a signer, the order is approved by the signer at once.
+type NewOrderMessage struct {
+	Multisig   ton.AccountID
+	OrderSeqno *big.Int
+	// IsSigner is true if the sender is a signer and false if it is a proposer.
+	IsSigner bool
+	// Index is an index of the sender in the list of signers or proposers.
+	Index          uint8
+	ExpirationDate time.Time
+	Actions        []abi.MultisigSendMessageAction
+	// Amount defaults to DefaultNewOrderAmount.
+	Amount tlb.Grams
+}
+
+func (m NewOrderMessage) ToInternal() (tlb.Message, uint8, error) {
+	if len(m.Actions) == 0 || len(m.Actions) > MaxSigners {
+		return tlb.Message{}, 0, fmt.Errorf("order must contain from 1 to %v actions", MaxSigners)
+	}
+	if m.OrderSeqno == nil {
+		return tlb.Message{}, 0, errors.New("order seqno must be set")
+	}
+	keys := make([]tlb.Uint8, len(m.Actions))
+	actions := make([]tlb.Ref[abi.MultisigSendMessageAction], len(m.Actions))
+	for i, action := range m.Actions {
+		keys[i] = tlb.Uint8(i)
+		actions[i] = tlb.Ref[abi.MultisigSendMessageAction]{Value: action}
+	}
+	body := abi.MultisigNewOrderMsgBody{
+		QueryId:        uint64(time.Now().UnixNano()),
+		OrderSeqno:     tlb.Uint256(*m.OrderSeqno),
+		Index:          m.Index,
+		ExpirationDate: tlb.Uint48(m.ExpirationDate.Unix()),
+		Order:          abi.MultisigOrder{Field0: tlb.NewHashmap(keys, actions)},
+	}
+	if m.IsSigner {
+		body.Signer = 1
+	}
+	return internalMessage(m.Multisig, 0xf718510f, body, m.Amount, DefaultNewOrderAmount)
+}
+
+// ApproveMessage is sent by a signer to an order to approve it.
+type ApproveMessage struct {
+	Order       ton.AccountID
+	SignerIndex uint8
+	// Amount defaults to DefaultApproveAmount.
+	Amount tlb.Grams
+}
+
+func (m ApproveMessage) ToInternal() (tlb.Message, uint8, error) {
+	body := abi.MultisigApproveMsgBody{
+		QueryId:     uint64(time.Now().UnixNano()),
+		SignerIndex: m.SignerIndex,
+	}
+	return internalMessage(m.Order, 0xa762230f, body, m.Amount, DefaultApproveAmount)
+}
+
+func internalMessage(destination ton.AccountID, opcode uint64, body any, amount, defaultAmount tlb.Grams) (tlb.Message, uint8, error) {
+	c := boc.NewCell()
+	if err := c.WriteUint(opcode, 32); err != nil {
+		return tlb.Message{}, 0, err
+	}
+	if err := tlb.Marshal(c, body); err != nil {
+		return tlb.Message{}, 0, err
+	}
+	if amount == 0 {
+		amount = defaultAmount
+	}
+	m := wallet.Message{
+		Amount:  amount,
+		Address: destination,
+		Bounce:  true,
+		Mode:    wallet.DefaultMessageMode,
+		Body:    c,
+	}
+	return m.ToInternal()
+}
+
+// Multisig reads the state of a deployed multisig and its orders.
+type Multisig struct {
+	Address    ton.AccountID
+	blockchain blockchain
+}
+
+func New(address ton.AccountID, blockchain blockchain) *Multisig {
+	return &Multisig{
+		Address:    address,
+		blockchain: blockchain,
+	}
+}
+
+// GetData returns the current data of the multisig.
+func (m *Multisig) GetData(ctx context.Context) (*Data, error) {
+	cell, err := m.accountData(ctx, m.Address)
+	if err != nil {
+		return nil, err
+	}
+	var data Data
+	if err := tlb.Unmarshal(cell, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// OrderAddress returns an address of the multisig's order with the given seqno.
+func (m *Multisig) OrderAddress(orderSeqno *big.Int) (ton.AccountID, error) {
+	return OrderAddress(m.Address, orderSeqno)
+}
+
+// GetOrder returns the current data of the multisig's order with the given seqno.
+func (m *Multisig) GetOrder(ctx context.Context, orderSeqno *big.Int) (*OrderData, error) {
+	address, err := m.OrderAddress(orderSeqno)
+	if err != nil {
+		return nil, err
+	}
+	cell, err := m.accountData(ctx, address)
+	if errors.Is(err, ErrNotInitialized) {
+		return nil, ErrOrderNotCreated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cell.BitSize() <= 267+256 {
+		return nil, ErrOrderNotCreated
+	}
+	var order OrderData
+	if err := tlb.Unmarshal(cell, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (m *Multisig) accountData(ctx context.Context, address ton.AccountID) (*boc.Cell, error) {
+	if m.blockchain == nil {
+		return nil, errors.New("blockchain interface is nil")
+	}
+	state, err := m.blockchain.GetAccountState(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if state.Account.Status() != tlb.AccountActive {
+		return nil, ErrNotInitialized
+	}
+	data := state.Account.Account.Storage.State.AccountActive.StateInit.Data
+	if !data.Exists {
+		return nil, ErrNotInitialized
+	}
+	cell := boc.Cell(data.Value.Value)
+	return &cell, nil
+}
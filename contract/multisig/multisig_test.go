@@ -0,0 +1,174 @@
+package multisig
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/abi"
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tontest"
+	"github.com/tonkeeper/tongo/wallet"
+)
+
+var signers = []ton.AccountID{
+	ton.MustParseAccountID("0:eb0b83bd6ceaeafe6d90ebebbc2ac09146513c53cbb323a58765bbd1ae101741"),
+	ton.MustParseAccountID("0:3c0ad094bb4e04c3b512470881940c59be570b106a25a0a284f89f9c7f75f86a"),
+	ton.MustParseAccountID("0:9b04f4785708a0e919a1dfbad1375fc717097120312279fb084452327c7906ba"),
+	ton.MustParseAccountID("0:51342aeccbf59f6af68b48feb9319ae1f398b244b03d1db2a8baf3d9402d39cb"),
+}
+
+type mockBlockchain map[ton.AccountID]tlb.ShardAccount
+
+func (m mockBlockchain) GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error) {
+	if state, ok := m[accountID]; ok {
+		return state, nil
+	}
+	return tontest.Account().Address(accountID).State(tlb.AccountNone).ShardAccount()
+}
+
+func TestAddress(t *testing.T) {
+	stateInit, err := StateInit(Config{Threshold: 2, Signers: signers, AllowArbitraryOrderSeqno: true})
+	if err != nil {
+		t.Fatalf("StateInit() failed: %v", err)
+	}
+	code := stateInit.Code.Value.Value
+	hash, err := code.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if fmt.Sprintf("%x", hash) != "d3d14da9a627f0ec3533341829762af92b9540b21bf03665fac09c2b46eabbac" {
+		t.Fatalf("unexpected code hash: %x", hash)
+	}
+	// data of the multisig used in abi tests of get_multisig_data
+	data := stateInit.Data.Value.Value
+	if hash, err = data.Hash256(); err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	if fmt.Sprintf("%x", hash) != "00798855db68fc24b2d84de59245266f9a2a44b486b27f432d5a56b27bf25fee" {
+		t.Fatalf("unexpected data hash: %x", hash)
+	}
+	if _, err := Address(0, Config{Threshold: 5, Signers: signers}); err == nil {
+		t.Fatalf("threshold above the number of signers must fail")
+	}
+}
+
+func TestMultisig_Orders(t *testing.T) {
+	config := Config{Threshold: 2, Signers: signers[:3], Proposers: signers[3:]}
+	stateInit, err := StateInit(config)
+	if err != nil {
+		t.Fatalf("StateInit() failed: %v", err)
+	}
+	address, err := Address(0, config)
+	if err != nil {
+		t.Fatalf("Address() failed: %v", err)
+	}
+	code, data := stateInit.Code.Value.Value, stateInit.Data.Value.Value
+	blockchain := mockBlockchain{
+		address: tontest.Account().Address(address).State(tlb.AccountActive).StateInit(&code, &data).MustShardAccount(),
+	}
+	m := New(address, blockchain)
+	msData, err := m.GetData(context.Background())
+	if err != nil {
+		t.Fatalf("GetData() failed: %v", err)
+	}
+	index, ok := msData.SignerIndex(signers[1])
+	if !ok || index != 1 {
+		t.Fatalf("unexpected signer index: %v %v", index, ok)
+	}
+	if index, ok := msData.ProposerIndex(signers[3]); !ok || index != 0 {
+		t.Fatalf("unexpected proposer index: %v %v", index, ok)
+	}
+
+	seqno := big.NewInt(0)
+	action, err := SendMessage(wallet.SimpleTransfer{Amount: ton.OneGRAM, Address: signers[0], Comment: "payout"})
+	if err != nil {
+		t.Fatalf("SendMessage() failed: %v", err)
+	}
+	newOrder := NewOrderMessage{
+		Multisig:       address,
+		OrderSeqno:     seqno,
+		IsSigner:       true,
+		Index:          index,
+		ExpirationDate: time.Now().Add(time.Hour),
+		Actions:        []abi.MultisigSendMessageAction{action, UpdateParams(1, signers[:1], nil)},
+	}
+	msg, _, err := newOrder.ToInternal()
+	if err != nil {
+		t.Fatalf("ToInternal() failed: %v", err)
+	}
+	body := boc.Cell(msg.Body.Value)
+	_, opName, value, err := abi.InternalMessageDecoder(&body, nil)
+	if err != nil || opName == nil || *opName != abi.MultisigNewOrderMsgOp {
+		t.Fatalf("InternalMessageDecoder() failed: %v %v", opName, err)
+	}
+	decoded := value.(abi.MultisigNewOrderMsgBody)
+	if decoded.Signer != 1 || decoded.Index != 1 || len(decoded.Order.Field0.Keys()) != 2 {
+		t.Fatalf("unexpected new order: %+v", decoded)
+	}
+
+	orderAddress, err := m.OrderAddress(seqno)
+	if err != nil {
+		t.Fatalf("OrderAddress() failed: %v", err)
+	}
+	if _, err := m.GetOrder(context.Background(), seqno); err != ErrOrderNotCreated {
+		t.Fatalf("want ErrOrderNotCreated, got %v", err)
+	}
+	orderData := OrderData{
+		Multisig:       address.ToMsgAddress(),
+		OrderSeqno:     tlb.Uint256(*seqno),
+		Threshold:      2,
+		Signers:        tlb.NewHashmap(addressList(signers[:3])),
+		ApprovalsMask:  tlb.Uint256(*big.NewInt(0b10)),
+		ApprovalsNum:   1,
+		ExpirationDate: decoded.ExpirationDate,
+		Order:          decoded.Order,
+	}
+	orderCell := boc.NewCell()
+	if err := tlb.Marshal(orderCell, orderData); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	blockchain[orderAddress] = tontest.Account().Address(orderAddress).State(tlb.AccountActive).StateInit(boc.NewCell(), orderCell).MustShardAccount()
+	order, err := m.GetOrder(context.Background(), seqno)
+	if err != nil {
+		t.Fatalf("GetOrder() failed: %v", err)
+	}
+	if !order.IsApprovedBy(1) || order.IsApprovedBy(0) || order.ApprovalsNum != 1 {
+		t.Fatalf("unexpected approvals: %+v", order)
+	}
+
+	approve, _, err := ApproveMessage{Order: orderAddress, SignerIndex: 0}.ToInternal()
+	if err != nil {
+		t.Fatalf("ToInternal() failed: %v", err)
+	}
+	if approve.Info.IntMsgInfo.Value.Grams != DefaultApproveAmount {
+		t.Fatalf("unexpected amount: %v", approve.Info.IntMsgInfo.Value.Grams)
+	}
+}
+
+func TestOrderCode(t *testing.T) {
+	code := boc.NewCellExotic(boc.LibraryCell)
+	if err := code.WriteUint(uint64(boc.LibraryCell), 8); err != nil {
+		t.Fatalf("WriteUint() failed: %v", err)
+	}
+	hash, err := hex.DecodeString(orderCodeHash)
+	if err != nil {
+		t.Fatalf("DecodeString() failed: %v", err)
+	}
+	if err := code.WriteBytes(hash); err != nil {
+		t.Fatalf("WriteBytes() failed: %v", err)
+	}
+	libraryHash, err := code.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	// code_hash of multisig_order_v2 in abi/schemas/multisig.xml
+	if fmt.Sprintf("%x", libraryHash) != "a01e057fbd4288402b9898d78d67bd4e90254c93c5866879bc2d1d12865436bc" {
+		t.Fatalf("unexpected order code hash: %x", libraryHash)
+	}
+}
@@ -5,4 +5,6 @@ package tongo
 //go:generate go run ./cmd/codegen/integers -output tlb
 //go:generate go run ./cmd/codegen/tensor -output tlb
 //go:generate go run ./cmd/codegen/liteclient -input liteclient/lite_api.tl -output liteclient
+//go:generate go run ./cmd/codegen/adnl -input adnl/adnl_api.tl -output adnl
+//go:generate go run ./cmd/codegen/adnl -input adnl/rldp/rldp_api.tl -output adnl/rldp -package rldp
 //go:generate go run ./cmd/codegen/abiTolk
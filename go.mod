@@ -8,6 +8,8 @@ require (
 	github.com/alecthomas/participle/v2 v2.0.0-beta.5
 	github.com/oasisprotocol/curve25519-voi v0.0.0-20220328075252-7dd334e3daae
 	github.com/snksoft/crc v1.1.0
+	github.com/stretchr/testify v1.11.1
+	github.com/xssnick/raptorq v1.1.0
 	golang.org/x/crypto v0.45.0
 	golang.org/x/exp v0.0.0-20230116083435-1de6713980de
 	google.golang.org/grpc v1.77.0
@@ -18,7 +20,6 @@ require (
 	github.com/alecthomas/repr v0.1.1 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.11.1 // indirect
 	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
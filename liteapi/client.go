@@ -3,11 +3,14 @@ package liteapi
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,6 +32,14 @@ const (
 	// This is a limitation of lite server:
 	// https://github.com/ton-blockchain/ton/blob/v2023.06/validator/impl/liteserver.hpp#L70
 	maxTransactionCount = 16
+
+	// maxBlockTransactionCount specifies a maximum number of transactions
+	// that can be requested from a lite server with a single listBlockTransactionsExt call.
+	maxBlockTransactionCount = 256
+
+	// maxDispatchQueueAccounts specifies a maximum number of accounts
+	// that can be requested from a lite server with a single getDispatchQueueInfo call.
+	maxDispatchQueueAccounts = 64
 )
 
 var (
@@ -37,15 +48,29 @@ var (
 )
 
 // ProofPolicy specifies a policy for proof checks.
+// Policies are ordered by strictness, each one includes the checks of the previous ones.
 // This feature is experimental and can be changed or removed in the future.
 type ProofPolicy uint32
 
 const (
 	// ProofPolicyUnsafe disables proof checks.
 	ProofPolicyUnsafe ProofPolicy = iota
+	// ProofPolicyFast checks that responses match the requested blocks
+	// but doesn't check that the blocks themselves belong to the blockchain.
 	ProofPolicyFast
+	// ProofPolicyStateProofs additionally checks shard and state proofs of account states.
+	ProofPolicyStateProofs
+	// ProofPolicySecure additionally proves every masterchain block used in a response
+	// with a chain of block links starting from a trusted key block.
+	// Take a look at WithTrustedBlock() option.
+	ProofPolicySecure
 )
 
+// checksStateProofs reports whether account states are checked against shard and state proofs.
+func (p ProofPolicy) checksStateProofs() bool {
+	return p >= ProofPolicyStateProofs
+}
+
 // Client provides a convenient way to interact with TON blockchain.
 //
 // By default, it uses a single connection to a lite server.
@@ -65,10 +90,21 @@ type Client struct {
 	pool *pool.ConnPool
 	// proofPolicy specifies a policy for proof checks.
 	proofPolicy ProofPolicy
+	// prover is used to prove masterchain blocks when proofPolicy is ProofPolicySecure.
+	prover *blockProver
 
 	// archiveDetectionEnabled specifies whether
 	// the underlying connections pool maintains information about which nodes are archive nodes.
 	archiveDetectionEnabled bool
+	// hedger is used to send hedged requests if enabled.
+	hedger *hedger
+	// retryPolicy is applied to idempotent requests if configured.
+	retryPolicy *RetryPolicy
+
+	// cancel stops background goroutines of the client.
+	cancel context.CancelFunc
+	// replayServer serves requests if the client replays a recording.
+	replayServer *liteclient.Server
 
 	// mu protects targetBlockID and networkGlobalID.
 	mu              sync.RWMutex
@@ -87,6 +123,9 @@ type Options struct {
 	InitCtx context.Context
 	// ProofPolicy specifies a policy for proof checks.
 	ProofPolicy ProofPolicy
+	// TrustedBlock is a masterchain key block to start proof chains from when ProofPolicy is ProofPolicySecure.
+	// Configuration options like Mainnet() set it to the init block of the network config.
+	TrustedBlock *ton.BlockIDExt
 	// DetectArchiveNodes specifies if a liteapi connection to a node
 	// should detect if its node is an archive node.
 	DetectArchiveNodes bool
@@ -94,6 +133,24 @@ type Options struct {
 	SyncConnectionsInitialization bool
 	PoolStrategy                  pool.Strategy
 	Observer                      liteclient.RequestObserver
+	// QuorumSize and QuorumThreshold are used with pool.QuorumStrategy.
+	QuorumSize      int
+	QuorumThreshold int
+	// HedgedRequests enables hedged requests.
+	HedgedRequests bool
+	// HedgeDelay is used as a hedge delay until p95 latency is known.
+	HedgeDelay time.Duration
+	// RetryPolicy is applied to idempotent requests.
+	RetryPolicy *RetryPolicy
+	// LiteServersSource is a URL or a path to a global config file
+	// to reload lite servers from every LiteServersRefreshInterval.
+	LiteServersSource          string
+	LiteServersRefreshInterval time.Duration
+	// Recorder captures raw requests and responses of all connections.
+	Recorder *Recorder
+
+	// replayServer is started by WithReplay and closed with a client.
+	replayServer *liteclient.Server
 }
 
 type Option func(o *Options) error
@@ -130,9 +187,61 @@ func WithAsyncConnectionsInit() Option {
 	}
 }
 
+// WithPoolStrategy configures a strategy of the connections pool.
+// pool.QuorumStrategy without WithQuorum requires a single lite server to respond,
+// use WithQuorum to cross-check responses.
 func WithPoolStrategy(strategy pool.Strategy) Option {
 	return func(o *Options) error {
 		o.PoolStrategy = strategy
+		if strategy == pool.QuorumStrategy {
+			o.QuorumThreshold = max(o.QuorumThreshold, 1)
+			o.QuorumSize = max(o.QuorumSize, o.QuorumThreshold)
+		}
+		return nil
+	}
+}
+
+// WithQuorum switches the connections pool to pool.QuorumStrategy.
+// Read requests like GetAccountState, RunSmcMethod and GetSeqno are sent to size lite servers
+// at the same target block and succeed only when threshold of them return identical responses.
+// Lite servers that disagree with the quorum are reported to the observer configured with WithObserver.
+// size must not exceed a number of connections configured with WithMaxConnectionsNumber.
+func WithQuorum(size, threshold int) Option {
+	return func(o *Options) error {
+		if threshold < 1 || threshold > size {
+			return fmt.Errorf("quorum threshold must be between 1 and %v", size)
+		}
+		o.PoolStrategy = pool.QuorumStrategy
+		o.QuorumSize = size
+		o.QuorumThreshold = threshold
+		return nil
+	}
+}
+
+// WithHedgedRequests enables hedged requests:
+// if an idempotent request to the best lite server takes longer than p95 latency of previous requests,
+// a duplicate is sent to the second-best connection and the first successful response is used.
+// initialDelay is used as a hedge delay until enough requests are made to estimate p95 latency.
+// Hedging makes sense only with WithMaxConnectionsNumber() > 1.
+func WithHedgedRequests(initialDelay time.Duration) Option {
+	return func(o *Options) error {
+		if initialDelay <= 0 {
+			return fmt.Errorf("hedge delay must be positive")
+		}
+		o.HedgedRequests = true
+		o.HedgeDelay = initialDelay
+		return nil
+	}
+}
+
+// WithRetryPolicy configures retries of idempotent requests.
+// Take a look at DefaultRetryPolicy().
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *Options) error {
+		if policy.MaxAttempts < 1 {
+			return fmt.Errorf("max attempts must be at least 1")
+		}
+		o.RetryPolicy = &policy
 		return nil
 	}
 }
@@ -160,6 +269,15 @@ func WithProofPolicy(policy ProofPolicy) Option {
 	}
 }
 
+// WithTrustedBlock specifies a masterchain key block that is trusted by the client.
+// It is used as a starting point of proof chains when ProofPolicySecure is enabled.
+func WithTrustedBlock(block ton.BlockIDExt) Option {
+	return func(o *Options) error {
+		o.TrustedBlock = &block
+		return nil
+	}
+}
+
 func WithDetectArchiveNodes() Option {
 	return func(o *Options) error {
 		o.DetectArchiveNodes = true
@@ -261,6 +379,56 @@ func WithConfigurationUrl(url string) Option {
 func WithConfigurationFile(file config.GlobalConfigurationFile) Option {
 	return func(o *Options) error {
 		o.LiteServers = file.LiteServers
+		setTrustedBlockFromConfig(file, o)
+		return nil
+	}
+}
+
+// WithLiteServersRefresh configures a client to reload lite servers periodically
+// from a global config located at the given URL or file path.
+// Connections to new lite servers are opened,
+// connections to lite servers that disappeared from the config are closed once their in-flight requests finish.
+// Refreshing stops when the client is closed.
+func WithLiteServersRefresh(location string, interval time.Duration) Option {
+	return func(o *Options) error {
+		if interval <= 0 {
+			return fmt.Errorf("refresh interval must be positive")
+		}
+		o.LiteServersSource = location
+		o.LiteServersRefreshInterval = interval
+		return nil
+	}
+}
+
+// WithRecorder configures a client to capture every raw request and response with the given recorder.
+// Use Recorder.Save to write them to a file which can be replayed with WithReplay().
+func WithRecorder(recorder *Recorder) Option {
+	return func(o *Options) error {
+		o.Recorder = recorder
+		return nil
+	}
+}
+
+// WithReplay configures a client to serve requests from a file saved by Recorder.Save
+// instead of lite servers.
+// Requests that are not in the file fail with a lite server error.
+// The replay server is stopped when the client is closed.
+func WithReplay(path string) Option {
+	return func(o *Options) error {
+		recording, err := LoadRecording(path)
+		if err != nil {
+			return err
+		}
+		server, liteServer, err := startReplayServer(recording)
+		if err != nil {
+			return err
+		}
+		if o.replayServer != nil {
+			o.replayServer.Close()
+		}
+		o.replayServer = server
+		o.LiteServers = []config.LiteServer{liteServer}
+		o.MaxConnections = 1
 		return nil
 	}
 }
@@ -275,7 +443,7 @@ func NewClientWithDefaultTestnet() (*Client, error) {
 
 // NewClient
 // Get options and create new lite client. If no options provided - download public config for mainnet from ton.org.
-func NewClient(options ...Option) (*Client, error) {
+func NewClient(options ...Option) (_ *Client, err error) {
 	opts := &Options{
 		Timeout:                       60 * time.Second,
 		MaxConnections:                defaultMaxConnectionsNumber,
@@ -286,6 +454,11 @@ func NewClient(options ...Option) (*Client, error) {
 		PoolStrategy:                  pool.BestPingStrategy,
 		WorkersPerConnection:          1,
 	}
+	defer func() {
+		if err != nil && opts.replayServer != nil {
+			opts.replayServer.Close()
+		}
+	}()
 	for _, o := range options {
 		if err := o(opts); err != nil {
 			return nil, err
@@ -294,10 +467,32 @@ func NewClient(options ...Option) (*Client, error) {
 	if len(opts.LiteServers) == 0 {
 		return nil, fmt.Errorf("server list empty")
 	}
+	var prover *blockProver
+	if opts.ProofPolicy == ProofPolicySecure {
+		if opts.TrustedBlock == nil {
+			return nil, fmt.Errorf("trusted block is required for ProofPolicySecure")
+		}
+		if opts.TrustedBlock.Workchain != -1 {
+			return nil, fmt.Errorf("trusted block must be a masterchain block")
+		}
+		prover = newBlockProver(*opts.TrustedBlock)
+	}
 	poolOptions := []pool.Option{}
 	if opts.Observer != nil {
 		poolOptions = append(poolOptions, pool.WithObserver(opts.Observer))
 	}
+	if opts.Recorder != nil {
+		poolOptions = append(poolOptions, pool.WithRecorder(opts.Recorder))
+	}
+	if opts.PoolStrategy == pool.QuorumStrategy {
+		if opts.QuorumThreshold < 1 || opts.QuorumThreshold > opts.QuorumSize {
+			return nil, fmt.Errorf("quorum threshold must be between 1 and %v", opts.QuorumSize)
+		}
+		if opts.QuorumSize > opts.MaxConnections {
+			return nil, fmt.Errorf("quorum size %v exceeds max connections number %v", opts.QuorumSize, opts.MaxConnections)
+		}
+		poolOptions = append(poolOptions, pool.WithQuorum(opts.QuorumSize, opts.QuorumThreshold))
+	}
 	connPool := pool.New(opts.PoolStrategy, poolOptions...)
 	initCh := connPool.InitializeConnections(opts.InitCtx, opts.Timeout, opts.MaxConnections, opts.WorkersPerConnection, opts.DetectArchiveNodes, opts.LiteServers)
 	if opts.SyncConnectionsInitialization {
@@ -308,12 +503,85 @@ func NewClient(options ...Option) (*Client, error) {
 	client := Client{
 		pool:                    connPool,
 		proofPolicy:             opts.ProofPolicy,
+		prover:                  prover,
 		archiveDetectionEnabled: opts.DetectArchiveNodes,
+		retryPolicy:             opts.RetryPolicy,
+		replayServer:            opts.replayServer,
+	}
+	if opts.HedgedRequests {
+		client.hedger = &hedger{delay: opts.HedgeDelay, latencies: newLatencyTracker()}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client.cancel = cancel
+	go client.pool.Run(ctx)
+	if opts.LiteServersSource != "" {
+		go client.refreshLiteServers(ctx, opts.LiteServersSource, opts.LiteServersRefreshInterval, opts.Timeout)
 	}
-	go client.pool.Run(context.TODO())
 	return &client, nil
 }
 
+// Close stops background goroutines of the client such as refreshing lite servers
+// and the replay server started by WithReplay, and closes connections to lite servers.
+// Requests that are in flight are interrupted.
+func (c *Client) Close() {
+	c.cancel()
+	if c.pool != nil {
+		c.pool.Close()
+	}
+	if c.replayServer != nil {
+		c.replayServer.Close()
+	}
+}
+
+// UpdateLiteServers replaces lite servers used by the client with the given ones.
+// Requests that are in flight are not interrupted.
+// If none of the given lite servers is available, the client keeps using the current ones.
+func (c *Client) UpdateLiteServers(servers []config.LiteServer) error {
+	if len(servers) == 0 {
+		return fmt.Errorf("server list empty")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return c.pool.UpdateLiteServers(ctx, servers)
+}
+
+func (c *Client) refreshLiteServers(ctx context.Context, location string, interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		file, err := loadConfig(location, timeout)
+		if err != nil {
+			slog.Warn("liteapi: failed to load lite servers", "location", location, "error", err.Error())
+			continue
+		}
+		if err := c.UpdateLiteServers(file.LiteServers); err != nil {
+			slog.Warn("liteapi: failed to update lite servers", "location", location, "error", err.Error())
+		}
+	}
+}
+
+// loadConfig reads a global config from the given URL or file path bypassing the config cache.
+func loadConfig(location string, timeout time.Duration) (*config.GlobalConfigurationFile, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return config.ParseConfigFile(location)
+	}
+	httpClient := http.Client{Timeout: timeout}
+	resp, err := httpClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download config: %v", resp.Status)
+	}
+	return config.ParseConfig(resp.Body)
+}
+
 func (c *Client) targetBlockOr(blockID ton.BlockIDExt) ton.BlockIDExt {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -325,8 +593,15 @@ func (c *Client) targetBlockOr(blockID ton.BlockIDExt) ton.BlockIDExt {
 
 func (c *Client) WithBlock(block ton.BlockIDExt) *Client {
 	return &Client{
-		pool:          c.pool,
-		targetBlockID: &block,
+		pool:                    c.pool,
+		proofPolicy:             c.proofPolicy,
+		prover:                  c.prover,
+		archiveDetectionEnabled: c.archiveDetectionEnabled,
+		hedger:                  c.hedger,
+		retryPolicy:             c.retryPolicy,
+		cancel:                  c.cancel,
+		replayServer:            c.replayServer,
+		targetBlockID:           &block,
 	}
 }
 
@@ -393,19 +668,18 @@ func (c *Client) GetBlock(ctx context.Context, blockID ton.BlockIDExt) (tlb.Bloc
 	if !bytes.Equal(hash[:], blockID.RootHash[:]) {
 		return tlb.Block{}, fmt.Errorf("block hash mismatch")
 	}
+	if c.proofPolicy == ProofPolicySecure && blockID.Workchain == -1 {
+		if err := c.VerifyMasterchainBlock(ctx, blockID); err != nil {
+			return tlb.Block{}, err
+		}
+	}
 	return block, nil
 }
 
 func (c *Client) GetBlockRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerBlockDataC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
-	if err != nil {
-		return liteclient.LiteServerBlockDataC{}, err
-	}
-	res, err := client.LiteServerGetBlock(ctx, liteclient.LiteServerGetBlockRequest{liteclient.BlockIDExt(blockID)})
-	if err != nil {
-		return liteclient.LiteServerBlockDataC{}, err
-	}
-	return res, err
+	return do(ctx, c, func(ctx context.Context, client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockDataC, error) {
+		return client.LiteServerGetBlock(ctx, liteclient.LiteServerGetBlockRequest{liteclient.BlockIDExt(blockID)})
+	})
 }
 
 func (c *Client) GetState(ctx context.Context, blockID ton.BlockIDExt) ([]byte, ton.Bits256, ton.Bits256, error) {
@@ -413,20 +687,88 @@ func (c *Client) GetState(ctx context.Context, blockID ton.BlockIDExt) ([]byte,
 	if err != nil {
 		return nil, ton.Bits256{}, ton.Bits256{}, err
 	}
-	// TODO: implement state tlb decoding
 	return res.Data, ton.Bits256(res.RootHash), ton.Bits256(res.FileHash), nil
 }
 
-func (c *Client) GetStateRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerBlockStateC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
+// GetShardState returns a full state of the shard at the given block.
+// Accounts of the state are not decoded until tlb.ShardState.Accounts is called,
+// so even a state with millions of accounts is cheap to get.
+// The Accounts field of the returned state is left empty, use tlb.ShardStateUnsplitData.ShardAccounts instead.
+// Unless the proof policy is ProofPolicyUnsafe, the state is checked against the block header.
+func (c *Client) GetShardState(ctx context.Context, blockID ton.BlockIDExt) (tlb.ShardState, error) {
+	res, err := c.GetStateRaw(ctx, blockID)
 	if err != nil {
-		return liteclient.LiteServerBlockStateC{}, err
+		return tlb.ShardState{}, err
 	}
-	res, err := client.LiteServerGetState(ctx, liteclient.LiteServerGetStateRequest{Id: liteclient.BlockIDExt(blockID)})
+	if res.Id.ToBlockIdExt() != blockID {
+		return tlb.ShardState{}, fmt.Errorf("state is returned for another block")
+	}
+	cell, err := deserializeSingleRoot(res.Data)
 	if err != nil {
-		return liteclient.LiteServerBlockStateC{}, err
+		return tlb.ShardState{}, err
 	}
-	return res, nil
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := c.verifyShardState(ctx, blockID, cell); err != nil {
+			return tlb.ShardState{}, proofError("GetShardState", err)
+		}
+	}
+	var state tlb.ShardState
+	if err := tlb.NewDecoder().WithLazyShardAccounts().Unmarshal(cell, &state); err != nil {
+		return tlb.ShardState{}, err
+	}
+	return state, nil
+}
+
+// verifyShardState checks that the given cell is the state of the block
+// by comparing its hash with the new state hash from the block's state update.
+func (c *Client) verifyShardState(ctx context.Context, blockID ton.BlockIDExt, state *boc.Cell) error {
+	header, err := c.GetBlockHeaderRaw(ctx, blockID, 1)
+	if err != nil {
+		return err
+	}
+	proof, err := deserializeSingleRoot(header.HeaderProof)
+	if err != nil {
+		return err
+	}
+	block, err := verifyBlockProof(proof, blockID)
+	if err != nil {
+		return err
+	}
+	hash, err := state.Hash256()
+	if err != nil {
+		return err
+	}
+	if tlb.Bits256(hash) != block.StateUpdate.ToHash {
+		return fmt.Errorf("state hash mismatch")
+	}
+	return nil
+}
+
+// IterateShardAccounts calls fn for every account of the shard at the given block
+// in ascending order of addresses until fn returns false.
+// It is meant for snapshotting balances at a given block:
+//
+//	err := client.IterateShardAccounts(ctx, blockID, func(accountID ton.AccountID, shardAccount tlb.ShardAccount) bool {
+//		balance, ok := shardAccount.Account.CurrencyCollection()
+//		...
+//		return true
+//	})
+//
+// Accounts are decoded one by one, so the whole state is never decoded at once.
+func (c *Client) IterateShardAccounts(ctx context.Context, blockID ton.BlockIDExt, fn func(accountID ton.AccountID, shardAccount tlb.ShardAccount) bool) error {
+	state, err := c.GetShardState(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	return state.Accounts(func(address tlb.Bits256, shardAccount tlb.ShardAccount) bool {
+		return fn(ton.AccountID{Workchain: blockID.Workchain, Address: address}, shardAccount)
+	})
+}
+
+func (c *Client) GetStateRaw(ctx context.Context, blockID ton.BlockIDExt) (liteclient.LiteServerBlockStateC, error) {
+	return do(ctx, c, func(ctx context.Context, client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockStateC, error) {
+		return client.LiteServerGetState(ctx, liteclient.LiteServerGetStateRequest{Id: liteclient.BlockIDExt(blockID)})
+	})
 }
 
 func (c *Client) GetBlockHeader(ctx context.Context, blockID ton.BlockIDExt, mode uint32) (tlb.BlockInfo, error) {
@@ -439,34 +781,26 @@ func (c *Client) GetBlockHeader(ctx context.Context, blockID ton.BlockIDExt, mod
 }
 
 func (c *Client) GetBlockHeaderRaw(ctx context.Context, blockID ton.BlockIDExt, mode uint32) (liteclient.LiteServerBlockHeaderC, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
-	if err != nil {
-		return liteclient.LiteServerBlockHeaderC{}, err
-	}
-	res, err := client.LiteServerGetBlockHeader(ctx, liteclient.LiteServerGetBlockHeaderRequest{
-		Id:   liteclient.BlockIDExt(blockID),
-		Mode: mode,
+	return do(ctx, c, func(ctx context.Context, client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockHeaderC, error) {
+		return client.LiteServerGetBlockHeader(ctx, liteclient.LiteServerGetBlockHeaderRequest{
+			Id:   liteclient.BlockIDExt(blockID),
+			Mode: mode,
+		})
 	})
-	if err != nil {
-		return liteclient.LiteServerBlockHeaderC{}, err
-	}
-	return res, nil
 }
 
 func (c *Client) LookupBlock(ctx context.Context, blockID ton.BlockID, mode uint32, lt *uint64, utime *uint32) (ton.BlockIDExt, tlb.BlockInfo, error) {
-	client, err := c.pool.BestClientByBlockID(ctx, blockID)
-	if err != nil {
-		return ton.BlockIDExt{}, tlb.BlockInfo{}, err
-	}
-	res, err := client.LiteServerLookupBlock(ctx, liteclient.LiteServerLookupBlockRequest{
-		Mode: mode,
-		Id: liteclient.TonNodeBlockIdC{
-			Workchain: uint32(blockID.Workchain),
-			Shard:     blockID.Shard,
-			Seqno:     blockID.Seqno,
-		},
-		Lt:    lt,
-		Utime: utime,
+	res, err := do(ctx, c, func(ctx context.Context, client *liteclient.Client, _ ton.BlockIDExt) (liteclient.LiteServerBlockHeaderC, error) {
+		return client.LiteServerLookupBlock(ctx, liteclient.LiteServerLookupBlockRequest{
+			Mode: mode,
+			Id: liteclient.TonNodeBlockIdC{
+				Workchain: uint32(blockID.Workchain),
+				Shard:     blockID.Shard,
+				Seqno:     blockID.Seqno,
+			},
+			Lt:    lt,
+			Utime: utime,
+		})
 	})
 	if err != nil {
 		return ton.BlockIDExt{}, tlb.BlockInfo{}, err
@@ -492,6 +826,70 @@ func decodeBlockHeader(header liteclient.LiteServerBlockHeaderC) (ton.BlockIDExt
 	return header.Id.ToBlockIdExt(), proof.Proof.VirtualRoot.Info, nil // TODO: maybe decode more
 }
 
+// LookupBlockWithProof looks up a block like LookupBlock does
+// and additionally checks that the returned header belongs to the found block id.
+// mcBlockID is a masterchain block known to the caller, the found block must not be newer than it.
+// Shard links and the previous block header are not checked yet,
+// so the result doesn't prove that the found block is referenced by mcBlockID.
+func (c *Client) LookupBlockWithProof(
+	ctx context.Context,
+	blockID ton.BlockID,
+	mcBlockID ton.BlockIDExt,
+	mode uint32,
+	lt *uint64,
+	utime *uint32,
+) (ton.BlockIDExt, tlb.BlockInfo, error) {
+	res, err := c.LookupBlockWithProofRaw(ctx, blockID, mcBlockID, mode, lt, utime)
+	if err != nil {
+		return ton.BlockIDExt{}, tlb.BlockInfo{}, err
+	}
+	id, info, err := decodeBlockHeader(liteclient.LiteServerBlockHeaderC{Id: res.Id, HeaderProof: res.Header})
+	if err != nil {
+		return ton.BlockIDExt{}, tlb.BlockInfo{}, err
+	}
+	if c.proofPolicy == ProofPolicyUnsafe {
+		return id, info, nil
+	}
+	if err := verifyLookupBlockResult(res, blockID, mcBlockID, mode, info, lt); err != nil {
+		return ton.BlockIDExt{}, tlb.BlockInfo{}, proofError("LookupBlockWithProof", err)
+	}
+	if c.proofPolicy == ProofPolicySecure {
+		if err := c.VerifyMasterchainBlock(ctx, mcBlockID); err != nil {
+			return ton.BlockIDExt{}, tlb.BlockInfo{}, err
+		}
+	}
+	return id, info, nil
+}
+
+func (c *Client) LookupBlockWithProofRaw(
+	ctx context.Context,
+	blockID ton.BlockID,
+	mcBlockID ton.BlockIDExt,
+	mode uint32,
+	lt *uint64,
+	utime *uint32,
+) (liteclient.LiteServerLookupBlockResultC, error) {
+	client, err := c.pool.BestClientByBlockID(ctx, blockID)
+	if err != nil {
+		return liteclient.LiteServerLookupBlockResultC{}, err
+	}
+	res, err := client.LiteServerLookupBlockWithProof(ctx, liteclient.LiteServerLookupBlockWithProofRequest{
+		Mode: mode,
+		Id: liteclient.TonNodeBlockIdC{
+			Workchain: uint32(blockID.Workchain),
+			Shard:     blockID.Shard,
+			Seqno:     blockID.Seqno,
+		},
+		McBlockId: liteclient.BlockIDExt(mcBlockID),
+		Lt:        lt,
+		Utime:     utime,
+	})
+	if err != nil {
+		return liteclient.LiteServerLookupBlockResultC{}, err
+	}
+	return res, nil
+}
+
 // SendMessage verifies that the given payload contains an external message and sends it to a lite server.
 func (c *Client) SendMessage(ctx context.Context, payload []byte) (uint32, error) {
 	if err := VerifySendMessagePayload(payload); err != nil {
@@ -515,18 +913,7 @@ func (c *Client) RunSmcMethodByID(ctx context.Context, accountID ton.AccountID,
 	if err != nil {
 		return 0, tlb.VmStack{}, err
 	}
-	client, masterHead, err := c.pool.BestClientByAccountID(ctx, accountID, false)
-	if err != nil {
-		return 0, tlb.VmStack{}, err
-	}
-	req := liteclient.LiteServerRunSmcMethodRequest{
-		Mode:     4,
-		Id:       liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
-		Account:  liteclient.AccountID(accountID),
-		MethodId: uint64(methodID),
-		Params:   b,
-	}
-	res, err := client.LiteServerRunSmcMethod(ctx, req)
+	res, err := c.runSmcMethodRaw(ctx, accountID, methodID, b)
 	if err != nil {
 		return 0, tlb.VmStack{}, err
 	}
@@ -545,6 +932,30 @@ func (c *Client) RunSmcMethodByID(ctx context.Context, accountID ton.AccountID,
 	return res.ExitCode, result, err
 }
 
+func (c *Client) runSmcMethodRaw(ctx context.Context, accountID ton.AccountID, methodID int, params []byte) (liteclient.LiteServerRunMethodResultC, error) {
+	request := func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerRunMethodResultC, []byte, error) {
+		res, err := client.LiteServerRunSmcMethod(ctx, liteclient.LiteServerRunSmcMethodRequest{
+			Mode:     4,
+			Id:       liteclient.BlockIDExt(c.targetBlockOr(masterHead)),
+			Account:  liteclient.AccountID(accountID),
+			MethodId: uint64(methodID),
+			Params:   params,
+		})
+		if err != nil {
+			return liteclient.LiteServerRunMethodResultC{}, nil, err
+		}
+		key := binary.LittleEndian.AppendUint32(nil, res.ExitCode)
+		return res, append(key, res.Result...), nil
+	}
+	if c.pool.Strategy() == pool.QuorumStrategy {
+		return pool.Quorum(ctx, c.pool, liteclient.LiteServerRunSmcMethodRequestName, request)
+	}
+	return do(ctx, c, func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (liteclient.LiteServerRunMethodResultC, error) {
+		res, _, err := request(ctx, client, masterHead)
+		return res, err
+	})
+}
+
 func (c *Client) RunSmcMethod(
 	ctx context.Context,
 	accountID ton.AccountID,
@@ -555,10 +966,25 @@ func (c *Client) RunSmcMethod(
 }
 
 func (c *Client) GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error) {
-	res, err := c.GetAccountStateRaw(ctx, accountID)
+	res, blockID, err := c.getAccountStateRaw(ctx, accountID)
 	if err != nil {
 		return tlb.ShardAccount{}, err
 	}
+	var (
+		lastLt   uint64
+		lastHash tlb.Bits256
+	)
+	if c.proofPolicy.checksStateProofs() {
+		lastLt, lastHash, err = verifyAccountState(res, blockID, accountID)
+		if err != nil {
+			return tlb.ShardAccount{}, proofError("GetAccountState", err)
+		}
+	}
+	if c.proofPolicy == ProofPolicySecure {
+		if err := c.VerifyMasterchainBlock(ctx, blockID); err != nil {
+			return tlb.ShardAccount{}, err
+		}
+	}
 	if len(res.State) == 0 {
 		return tlb.ShardAccount{Account: tlb.Account{SumType: "AccountNone"}}, nil
 	}
@@ -574,24 +1000,53 @@ func (c *Client) GetAccountState(ctx context.Context, accountID ton.AccountID) (
 	if err != nil {
 		return tlb.ShardAccount{}, err
 	}
+	if c.proofPolicy.checksStateProofs() {
+		return tlb.ShardAccount{Account: acc, LastTransHash: lastHash, LastTransLt: lastLt}, nil
+	}
 	lt, hash, err := decodeAccountDataFromProof(res.Proof, accountID)
 	return tlb.ShardAccount{Account: acc, LastTransHash: hash, LastTransLt: lt}, err
 }
 
 func (c *Client) GetAccountStateRaw(ctx context.Context, accountID ton.AccountID) (liteclient.LiteServerAccountStateC, error) {
-	client, masterHead, err := c.pool.BestClientByAccountID(ctx, accountID, false)
-	if err != nil {
-		return liteclient.LiteServerAccountStateC{}, err
+	res, _, err := c.getAccountStateRaw(ctx, accountID)
+	return res, err
+}
+
+// getAccountStateRaw returns an account state along with the masterchain block it was requested for.
+func (c *Client) getAccountStateRaw(ctx context.Context, accountID ton.AccountID) (liteclient.LiteServerAccountStateC, ton.BlockIDExt, error) {
+	type accountState struct {
+		res     liteclient.LiteServerAccountStateC
+		blockID ton.BlockIDExt
+	}
+	request := func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (accountState, []byte, error) {
+		blockID := c.targetBlockOr(masterHead)
+		res, err := client.LiteServerGetAccountState(ctx, liteclient.LiteServerGetAccountStateRequest{
+			Account: liteclient.AccountID(accountID),
+			Id:      liteclient.BlockIDExt(blockID),
+		})
+		if err != nil {
+			return accountState{}, nil, err
+		}
+		// proofs are not compared because their serialization can differ between lite servers.
+		key := append(res.Shardblk.RootHash[:], res.State...)
+		return accountState{res: res, blockID: blockID}, key, nil
+	}
+	var (
+		state accountState
+		err   error
+	)
+	if c.pool.Strategy() == pool.QuorumStrategy {
+		state, err = pool.Quorum(ctx, c.pool, liteclient.LiteServerGetAccountStateRequestName, request)
+	} else {
+		state, err = do(ctx, c, func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (accountState, error) {
+			state, _, err := request(ctx, client, masterHead)
+			return state, err
+		})
 	}
-	blockID := c.targetBlockOr(masterHead)
-	res, err := client.LiteServerGetAccountState(ctx, liteclient.LiteServerGetAccountStateRequest{
-		Account: liteclient.AccountID(accountID),
-		Id:      liteclient.BlockIDExt(blockID),
-	})
 	if err != nil {
-		return liteclient.LiteServerAccountStateC{}, err
+		return liteclient.LiteServerAccountStateC{}, ton.BlockIDExt{}, err
 	}
-	return res, nil
+	return state.res, state.blockID, nil
 }
 
 func decodeAccountDataFromProof(bocBytes []byte, account ton.AccountID) (uint64, tlb.Bits256, error) {
@@ -630,6 +1085,17 @@ func (c *Client) GetShardInfo(
 	if err != nil {
 		return ton.BlockIDExt{}, err
 	}
+	if c.proofPolicy == ProofPolicyUnsafe {
+		return res.Id.ToBlockIdExt(), nil
+	}
+	if err := verifyShardInfo(res, blockID, int32(workchain)); err != nil {
+		return ton.BlockIDExt{}, proofError("GetShardInfo", err)
+	}
+	if c.proofPolicy == ProofPolicySecure {
+		if err := c.VerifyMasterchainBlock(ctx, blockID); err != nil {
+			return ton.BlockIDExt{}, err
+		}
+	}
 	return res.Id.ToBlockIdExt(), nil
 }
 
@@ -667,6 +1133,16 @@ func (c *Client) GetAllShardsInfo(ctx context.Context, blockID ton.BlockIDExt) (
 	if err != nil {
 		return nil, err
 	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := verifyAllShardsInfo(res, cells[0], blockID); err != nil {
+			return nil, proofError("GetAllShardsInfo", err)
+		}
+	}
+	if c.proofPolicy == ProofPolicySecure {
+		if err := c.VerifyMasterchainBlock(ctx, blockID); err != nil {
+			return nil, err
+		}
+	}
 	var shards []ton.BlockIDExt
 	for i, v := range inf.ShardHashes.Values() {
 		wc := inf.ShardHashes.Keys()[i]
@@ -754,6 +1230,11 @@ func (c *Client) GetTransactions(
 			BlockID:     r.Ids[i].ToBlockIdExt(),
 		})
 	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := verifyTransactionChain(cells, res, lt, hash); err != nil {
+			return nil, proofError("GetTransactions", err)
+		}
+	}
 	return res, nil
 }
 
@@ -862,6 +1343,99 @@ func (c *Client) ListBlockTransactionsRaw(ctx context.Context, blockID ton.Block
 	return res, nil
 }
 
+// ListBlockTransactionsExt returns up to count transactions of the given block starting after the given transaction.
+// The second returned value reports whether there are more transactions in the block.
+// Unless ProofPolicyUnsafe is used, transactions are checked against a merkle proof of the block,
+// which must also prove that no transaction is skipped and, for a complete list, that none follows.
+func (c *Client) ListBlockTransactionsExt(
+	ctx context.Context,
+	blockID ton.BlockIDExt,
+	count uint32,
+	after *liteclient.LiteServerTransactionId3C,
+) ([]ton.Transaction, bool, error) {
+	var mode uint32
+	if after != nil {
+		mode |= 1 << 7
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		mode |= 1 << 5
+	}
+	res, err := c.ListBlockTransactionsExtRaw(ctx, blockID, mode, count, after)
+	if err != nil {
+		return nil, false, err
+	}
+	if res.Id.ToBlockIdExt() != blockID {
+		return nil, false, fmt.Errorf("transactions are returned for another block")
+	}
+	var cells []*boc.Cell
+	if len(res.Transactions) > 0 {
+		cells, err = boc.DeserializeBoc(res.Transactions)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	txs := make([]ton.Transaction, 0, len(cells))
+	for _, cell := range cells {
+		var tx tlb.Transaction
+		if err := tlb.Unmarshal(cell, &tx); err != nil {
+			return nil, false, err
+		}
+		txs = append(txs, ton.Transaction{Transaction: tx, BlockID: blockID})
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := verifyBlockTransactions(res.Proof, blockID, cells, txs, after, res.Incomplete); err != nil {
+			return nil, false, proofError("ListBlockTransactionsExt", err)
+		}
+	}
+	if c.proofPolicy == ProofPolicySecure && blockID.Workchain == -1 {
+		if err := c.VerifyMasterchainBlock(ctx, blockID); err != nil {
+			return nil, false, err
+		}
+	}
+	return txs, res.Incomplete, nil
+}
+
+func (c *Client) ListBlockTransactionsExtRaw(ctx context.Context, blockID ton.BlockIDExt, mode, count uint32, after *liteclient.LiteServerTransactionId3C) (liteclient.LiteServerBlockTransactionsExtC, error) {
+	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
+	if err != nil {
+		return liteclient.LiteServerBlockTransactionsExtC{}, err
+	}
+	res, err := client.LiteServerListBlockTransactionsExt(ctx, liteclient.LiteServerListBlockTransactionsExtRequest{
+		Id:    liteclient.BlockIDExt(blockID),
+		Mode:  mode,
+		Count: count,
+		After: after,
+	})
+	if err != nil {
+		return liteclient.LiteServerBlockTransactionsExtC{}, err
+	}
+	return res, nil
+}
+
+// GetBlockTransactions returns all transactions of the given block
+// paginating over ListBlockTransactionsExt.
+func (c *Client) GetBlockTransactions(ctx context.Context, blockID ton.BlockIDExt) ([]ton.Transaction, error) {
+	var (
+		res   []ton.Transaction
+		after *liteclient.LiteServerTransactionId3C
+	)
+	for {
+		txs, incomplete, err := c.ListBlockTransactionsExt(ctx, blockID, maxBlockTransactionCount, after)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, txs...)
+		if !incomplete || len(txs) == 0 {
+			return res, nil
+		}
+		last := txs[len(txs)-1]
+		after = &liteclient.LiteServerTransactionId3C{
+			Account: tl.Int256(last.AccountAddr),
+			Lt:      last.Lt,
+		}
+	}
+}
+
 func (c *Client) GetBlockProof(
 	ctx context.Context,
 	knownBlock ton.BlockIDExt,
@@ -1019,6 +1593,65 @@ func (c *Client) GetLibraries(ctx context.Context, libraryList []ton.Bits256) (m
 	return libs, nil
 }
 
+// GetLibrariesWithProof returns libraries from the state of the given masterchain block.
+// Unless ProofPolicyUnsafe is used, it checks that the libraries are present in the state of the block.
+func (c *Client) GetLibrariesWithProof(ctx context.Context, blockID ton.BlockIDExt, libraryList []ton.Bits256) (map[ton.Bits256]*boc.Cell, error) {
+	res, err := c.GetLibrariesWithProofRaw(ctx, blockID, libraryList)
+	if err != nil {
+		return nil, err
+	}
+	if res.Id.ToBlockIdExt() != blockID {
+		return nil, fmt.Errorf("libraries are returned for another block")
+	}
+	libs := make(map[ton.Bits256]*boc.Cell, len(res.Result))
+	for _, lib := range res.Result {
+		cell, err := deserializeSingleRoot(lib.Data)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := cell.Hash256()
+		if err != nil {
+			return nil, err
+		}
+		if hash != lib.Hash {
+			return nil, fmt.Errorf("library hash mismatch")
+		}
+		cell.ResetCounters()
+		libs[ton.Bits256(lib.Hash)] = cell
+	}
+	if c.proofPolicy == ProofPolicyUnsafe {
+		return libs, nil
+	}
+	if err := verifyLibraries(res, blockID, libraryList); err != nil {
+		return nil, proofError("GetLibrariesWithProof", err)
+	}
+	if c.proofPolicy == ProofPolicySecure {
+		if err := c.VerifyMasterchainBlock(ctx, blockID); err != nil {
+			return nil, err
+		}
+	}
+	return libs, nil
+}
+
+func (c *Client) GetLibrariesWithProofRaw(ctx context.Context, blockID ton.BlockIDExt, libraryList []ton.Bits256) (liteclient.LiteServerLibraryResultWithProofC, error) {
+	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
+	if err != nil {
+		return liteclient.LiteServerLibraryResultWithProofC{}, err
+	}
+	ll := make([]tl.Int256, 0, len(libraryList))
+	for _, l := range libraryList {
+		ll = append(ll, tl.Int256(l))
+	}
+	res, err := client.LiteServerGetLibrariesWithProof(ctx, liteclient.LiteServerGetLibrariesWithProofRequest{
+		Id:          liteclient.BlockIDExt(blockID),
+		LibraryList: ll,
+	})
+	if err != nil {
+		return liteclient.LiteServerLibraryResultWithProofC{}, err
+	}
+	return res, nil
+}
+
 func (c *Client) GetShardBlockProof(ctx context.Context) (liteclient.LiteServerShardBlockProofC, error) {
 	res, err := c.GetShardBlockProofRaw(ctx)
 	if err != nil {
@@ -1056,6 +1689,91 @@ func (c *Client) GetOutMsgQueueSizes(ctx context.Context) (liteclient.LiteServer
 	return res, nil
 }
 
+// AccountDispatchQueueInfo describes messages waiting in a dispatch queue of an account.
+type AccountDispatchQueueInfo struct {
+	Account ton.AccountID
+	Size    uint64
+	MinLt   uint64
+	MaxLt   uint64
+}
+
+// GetDispatchQueueInfo returns dispatch queues of up to maxAccounts accounts of the given block
+// starting after the given account address.
+// The second returned value reports whether the list is complete.
+// Unless ProofPolicyUnsafe is used, the queues are checked against the proven state of the block.
+func (c *Client) GetDispatchQueueInfo(ctx context.Context, blockID ton.BlockIDExt, afterAddr *ton.Bits256, maxAccounts uint32) ([]AccountDispatchQueueInfo, bool, error) {
+	var mode uint32
+	var after *tl.Int256
+	if afterAddr != nil {
+		mode |= 1 << 1
+		a := tl.Int256(*afterAddr)
+		after = &a
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		mode |= 1
+	}
+	res, err := c.GetDispatchQueueInfoRaw(ctx, blockID, mode, after, maxAccounts)
+	if err != nil {
+		return nil, false, err
+	}
+	if res.Id.ToBlockIdExt() != blockID {
+		return nil, false, fmt.Errorf("dispatch queue is returned for another block")
+	}
+	if c.proofPolicy != ProofPolicyUnsafe {
+		if err := verifyDispatchQueueInfo(res, blockID, afterAddr); err != nil {
+			return nil, false, proofError("GetDispatchQueueInfo", err)
+		}
+	}
+	queues := make([]AccountDispatchQueueInfo, 0, len(res.AccountDispatchQueues))
+	for _, q := range res.AccountDispatchQueues {
+		queues = append(queues, AccountDispatchQueueInfo{
+			Account: ton.AccountID{Workchain: blockID.Workchain, Address: q.Addr},
+			Size:    q.Size,
+			MinLt:   q.MinLt,
+			MaxLt:   q.MaxLt,
+		})
+	}
+	return queues, res.Complete, nil
+}
+
+func (c *Client) GetDispatchQueueInfoRaw(ctx context.Context, blockID ton.BlockIDExt, mode uint32, afterAddr *tl.Int256, maxAccounts uint32) (liteclient.LiteServerDispatchQueueInfoC, error) {
+	client, err := c.pool.BestClientByBlockID(ctx, blockID.BlockID)
+	if err != nil {
+		return liteclient.LiteServerDispatchQueueInfoC{}, err
+	}
+	res, err := client.LiteServerGetDispatchQueueInfo(ctx, liteclient.LiteServerGetDispatchQueueInfoRequest{
+		Mode:        mode,
+		Id:          liteclient.BlockIDExt(blockID),
+		AfterAddr:   afterAddr,
+		MaxAccounts: maxAccounts,
+	})
+	if err != nil {
+		return liteclient.LiteServerDispatchQueueInfoC{}, err
+	}
+	return res, nil
+}
+
+// GetAllDispatchQueueInfo returns dispatch queues of all accounts of the given block
+// paginating over GetDispatchQueueInfo.
+func (c *Client) GetAllDispatchQueueInfo(ctx context.Context, blockID ton.BlockIDExt) ([]AccountDispatchQueueInfo, error) {
+	var (
+		res   []AccountDispatchQueueInfo
+		after *ton.Bits256
+	)
+	for {
+		queues, complete, err := c.GetDispatchQueueInfo(ctx, blockID, after, maxDispatchQueueAccounts)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, queues...)
+		if complete || len(queues) == 0 {
+			return res, nil
+		}
+		last := ton.Bits256(queues[len(queues)-1].Account.Address)
+		after = &last
+	}
+}
+
 var configCache = make(map[string]*config.GlobalConfigurationFile)
 var configCacheMutex sync.RWMutex
 
@@ -1092,9 +1810,20 @@ func setLiteServersFromURL(url string, o *Options) error {
 		return err
 	}
 	o.LiteServers = file.LiteServers
+	setTrustedBlockFromConfig(*file, o)
 	return nil
 }
 
+// setTrustedBlockFromConfig sets the init block of the config as a trusted block
+// unless a trusted block has already been provided.
+func setTrustedBlockFromConfig(file config.GlobalConfigurationFile, o *Options) {
+	if o.TrustedBlock != nil || file.Validator.InitBlock.RootHash == (ton.Bits256{}) {
+		return
+	}
+	block := file.Validator.InitBlock
+	o.TrustedBlock = &block
+}
+
 func (c *Client) getNetworkGlobalID() *int32 {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
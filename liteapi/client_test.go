@@ -496,6 +496,54 @@ func TestGetLibraries(t *testing.T) {
 	}
 }
 
+func TestGetLibrariesWithProof(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("hangs in CI")
+	}
+	tongoClient, err := NewClient(Mainnet(), FromEnvs(), WithProofPolicy(ProofPolicyFast))
+	if err != nil {
+		log.Fatalf("Unable to create tongo client: %v", err)
+	}
+	info, err := tongoClient.GetMasterchainInfo(context.Background())
+	if err != nil {
+		log.Fatalf("GetMasterchainInfo() failed: %v", err)
+	}
+	hash := ton.MustParseHash("587CC789EFF1C84F46EC3797E45FC809A14FF5AE24F1E0C7A6A99CC9DC9061FF")
+	libs, err := tongoClient.GetLibrariesWithProof(context.Background(), info.Last.ToBlockIdExt(), []ton.Bits256{hash})
+	if err != nil {
+		t.Fatalf("GetLibrariesWithProof() failed: %v", err)
+	}
+	if _, ok := libs[hash]; !ok {
+		t.Fatalf("expected lib is not found")
+	}
+}
+
+func TestGetBlockTransactions(t *testing.T) {
+	tongoClient, err := NewClient(Mainnet(), FromEnvs(), WithProofPolicy(ProofPolicyFast))
+	if err != nil {
+		log.Fatalf("Unable to create tongo client: %v", err)
+	}
+	info, err := tongoClient.GetMasterchainInfo(context.Background())
+	if err != nil {
+		log.Fatalf("GetMasterchainInfo() failed: %v", err)
+	}
+	blockID := info.Last.ToBlockIdExt()
+	txs, err := tongoClient.GetBlockTransactions(context.Background(), blockID)
+	if err != nil {
+		t.Fatalf("GetBlockTransactions() failed: %v", err)
+	}
+	if len(txs) == 0 {
+		t.Fatalf("masterchain block must contain transactions")
+	}
+	id, _, err := tongoClient.LookupBlockWithProof(context.Background(), blockID.BlockID, blockID, 1, nil, nil)
+	if err != nil {
+		t.Fatalf("LookupBlockWithProof() failed: %v", err)
+	}
+	if id != blockID {
+		t.Fatalf("want: %v, got: %v", blockID, id)
+	}
+}
+
 func TestGetJettonWallet(t *testing.T) {
 	if os.Getenv("CI") != "" {
 		t.Skip("hangs in CI")
@@ -698,3 +746,20 @@ func TestWaitMasterchainBlock(t *testing.T) {
 	}
 	fmt.Printf("Next block seqno    : %v\n", bl.Seqno)
 }
+
+func TestClient_refreshLiteServersStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{cancel: cancel}
+	done := make(chan struct{})
+	go func() {
+		c.refreshLiteServers(ctx, "testdata/missing-config.json", time.Millisecond, time.Second)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("refreshing lite servers must stop when the client is closed")
+	}
+}
@@ -0,0 +1,228 @@
+package liteapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// latencySamples is a number of the latest request durations used to estimate p95 latency.
+	latencySamples = 256
+	// minLatencySamples is a number of samples required before p95 latency is used as a hedge delay.
+	minLatencySamples = 20
+)
+
+// RetryPolicy configures retries of idempotent requests.
+//
+// Errors are handled depending on their origin:
+//  1. transport errors and liteclient client errors (like a request timeout) are retried on another connection,
+//  2. a "block is not applied" error means that a lite server is behind the requested block,
+//     it is retried after Backoff if RetryNotApplied is set,
+//  3. other lite server errors are returned as is because a lite server would return them again.
+type RetryPolicy struct {
+	// MaxAttempts is a maximum number of attempts including the first one.
+	MaxAttempts int
+	// Backoff is a delay before the second attempt. It doubles after every attempt.
+	Backoff time.Duration
+	// RetryNotApplied enables retries of "block is not applied" errors.
+	RetryNotApplied bool
+}
+
+// DefaultRetryPolicy returns a retry policy suitable for most applications.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     3,
+		Backoff:         200 * time.Millisecond,
+		RetryNotApplied: true,
+	}
+}
+
+// retryDecision describes how a failed request has to be retried.
+type retryDecision int
+
+const (
+	noRetry retryDecision = iota
+	// retrySameConnection means that a request can be retried with the best connection.
+	retrySameConnection
+	// retryAnotherConnection means that a connection is in trouble and a request has to be sent to another one.
+	retryAnotherConnection
+)
+
+func (p RetryPolicy) decide(ctx context.Context, err error) retryDecision {
+	if ctx.Err() != nil {
+		return noRetry
+	}
+	var liteServerErr liteclient.LiteServerErrorC
+	switch {
+	case errors.As(err, &liteServerErr):
+		if liteServerErr.IsNotApplied() && p.RetryNotApplied {
+			return retrySameConnection
+		}
+		return noRetry
+	case liteclient.IsClientError(err):
+		return retryAnotherConnection
+	case errors.Is(err, ErrAccountNotFound), IsProofError(err):
+		return noRetry
+	default:
+		// transport errors
+		return retryAnotherConnection
+	}
+}
+
+// latencyTracker keeps durations of the latest requests to estimate p95 latency.
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{samples: make([]time.Duration, 0, latencySamples)}
+}
+
+func (t *latencyTracker) observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < latencySamples {
+		t.samples = append(t.samples, d)
+		return
+	}
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % latencySamples
+}
+
+// p95 returns p95 latency and false if there are not enough samples yet.
+func (t *latencyTracker) p95() (time.Duration, bool) {
+	t.mu.Lock()
+	samples := slices.Clone(t.samples)
+	t.mu.Unlock()
+	if len(samples) < minLatencySamples {
+		return 0, false
+	}
+	slices.Sort(samples)
+	return samples[len(samples)*95/100], true
+}
+
+// hedger sends a duplicate of a slow request to the second-best connection.
+type hedger struct {
+	// delay is used until enough latency samples are collected.
+	delay     time.Duration
+	latencies *latencyTracker
+}
+
+func (h *hedger) hedgeDelay() time.Duration {
+	if d, ok := h.latencies.p95(); ok {
+		return d
+	}
+	return h.delay
+}
+
+// connRequest is an idempotent request sent to a particular lite server.
+type connRequest[T any] func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (T, error)
+
+type connResult[T any] struct {
+	value T
+	err   error
+	// client is a liteclient the result was received from.
+	client *liteclient.Client
+}
+
+// do sends an idempotent request to the best connection
+// applying the hedging and retry policy configured for the client.
+func do[T any](ctx context.Context, c *Client, request connRequest[T]) (T, error) {
+	client, masterHead, err := c.pool.BestMasterchainClient(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	policy := RetryPolicy{MaxAttempts: 1}
+	if c.retryPolicy != nil {
+		policy = *c.retryPolicy
+	}
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		res := hedge(ctx, c, client, masterHead, request)
+		if res.err == nil || attempt >= policy.MaxAttempts {
+			return res.value, res.err
+		}
+		switch policy.decide(ctx, res.err) {
+		case noRetry:
+			return res.value, res.err
+		case retrySameConnection:
+			if err := sleep(ctx, backoff); err != nil {
+				return res.value, res.err
+			}
+			backoff *= 2
+			client, masterHead, err = c.pool.BestMasterchainClient(ctx)
+		case retryAnotherConnection:
+			client, _, err = c.pool.AlternativeClient(res.client)
+		}
+		if err != nil {
+			return res.value, res.err
+		}
+	}
+}
+
+// hedge sends the request to the given client and,
+// if the request takes longer than the hedge delay, sends a duplicate to another connection.
+// The first successful result wins.
+func hedge[T any](ctx context.Context, c *Client, client *liteclient.Client, masterHead ton.BlockIDExt, request connRequest[T]) connResult[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan connResult[T], 2)
+	send := func(client *liteclient.Client) {
+		start := time.Now()
+		value, err := request(ctx, client, masterHead)
+		if err == nil && c.hedger != nil {
+			c.hedger.latencies.observe(time.Since(start))
+		}
+		results <- connResult[T]{value: value, err: err, client: client}
+	}
+	go send(client)
+	if c.hedger == nil {
+		return <-results
+	}
+	timer := time.NewTimer(c.hedger.hedgeDelay())
+	defer timer.Stop()
+
+	inflight := 1
+	var first *connResult[T]
+	for {
+		select {
+		case <-timer.C:
+			if second, _, err := c.pool.AlternativeClient(client); err == nil {
+				inflight++
+				go send(second)
+			}
+		case res := <-results:
+			inflight--
+			if res.err == nil {
+				return res
+			}
+			if first == nil {
+				first = &res
+			}
+			if inflight == 0 {
+				return *first
+			}
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+package liteapi
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestRetryPolicy_decide(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		policy RetryPolicy
+		err    error
+		want   retryDecision
+	}{
+		{
+			name:   "not applied",
+			policy: DefaultRetryPolicy(),
+			err:    liteclient.LiteServerErrorC{Code: 651, Message: "block is not applied"},
+			want:   retrySameConnection,
+		},
+		{
+			name:   "not applied retries disabled",
+			policy: RetryPolicy{MaxAttempts: 3},
+			err:    liteclient.LiteServerErrorC{Code: 651, Message: "block is not applied"},
+			want:   noRetry,
+		},
+		{
+			name:   "lite server error",
+			policy: DefaultRetryPolicy(),
+			err:    liteclient.LiteServerErrorC{Code: 400, Message: "invalid request"},
+			want:   noRetry,
+		},
+		{
+			name:   "transport error",
+			policy: DefaultRetryPolicy(),
+			err:    errors.New("connection reset by peer"),
+			want:   retryAnotherConnection,
+		},
+		{
+			name:   "proof error",
+			policy: DefaultRetryPolicy(),
+			err:    proofError("GetAccountState", errors.New("bad proof")),
+			want:   noRetry,
+		},
+		{
+			name:   "context is done",
+			ctx:    cancelled,
+			policy: DefaultRetryPolicy(),
+			err:    errors.New("connection reset by peer"),
+			want:   noRetry,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := tt.policy.decide(ctx, tt.err); got != tt.want {
+				t.Fatalf("decide() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyTracker_p95(t *testing.T) {
+	tracker := newLatencyTracker()
+	for i := 1; i < minLatencySamples; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := tracker.p95(); ok {
+		t.Fatalf("p95 must not be available with few samples")
+	}
+	for i := 0; i < 2*latencySamples; i++ {
+		tracker.observe(time.Duration(i%100+1) * time.Millisecond)
+	}
+	p95, ok := tracker.p95()
+	if !ok || p95 < 90*time.Millisecond || p95 > 100*time.Millisecond {
+		t.Fatalf("unexpected p95: %v", p95)
+	}
+}
+
+func TestHedgedRequests_circuitBreaker(t *testing.T) {
+	info := liteclient.LiteServerMasterchainInfoC{
+		Last: liteclient.TonNodeBlockIdExtC{Workchain: 0xffffffff, Shard: 0x8000000000000000, Seqno: 100},
+	}
+	// every first request of a pair is slow, so it is always outrun by a hedged request.
+	var calls atomic.Int64
+	handler := liteclient.HandlerFunc(func(ctx context.Context, name liteclient.RequestName, request any) (any, error) {
+		switch name {
+		case liteclient.LiteServerGetMasterchainInfoRequestName:
+			return info, nil
+		case liteclient.LiteServerGetBlockRequestName:
+			if calls.Add(1)%2 == 1 {
+				time.Sleep(300 * time.Millisecond)
+			}
+			return liteclient.LiteServerBlockDataC{}, nil
+		}
+		return nil, liteclient.LiteServerErrorC{Code: 651, Message: "not found"}
+	})
+	var servers []config.LiteServer
+	for i := 0; i < 2; i++ {
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey() failed: %v", err)
+		}
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen() failed: %v", err)
+		}
+		server := liteclient.NewServer(key, handler)
+		go server.Serve(l)
+		defer server.Close()
+		servers = append(servers, config.LiteServer{Host: l.Addr().String(), Key: base64.StdEncoding.EncodeToString(pub)})
+	}
+	client, err := NewClient(WithLiteServers(servers), WithMaxConnectionsNumber(2), WithHedgedRequests(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 10; i++ {
+		if _, err := client.GetBlockRaw(context.Background(), ton.BlockIDExt{}); err != nil {
+			t.Fatalf("GetBlockRaw() failed: %v", err)
+		}
+	}
+	// let cancelled requests be observed.
+	time.Sleep(100 * time.Millisecond)
+	for _, status := range client.pool.Status().Connections {
+		if status.Health.Breaker != pool.BreakerClosed || status.Health.ErrorRate != 0 {
+			t.Fatalf("cancelled hedged requests must not count as failures, got: %+v", status.Health)
+		}
+	}
+}
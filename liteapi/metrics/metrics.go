@@ -0,0 +1,263 @@
+// Package metrics exports liteapi metrics through a small set of interfaces,
+// so they can be wired to Prometheus, OpenTelemetry or any other metrics library
+// without adding its dependency to tongo.
+//
+// The interfaces mirror label-based vectors of Prometheus, for example:
+//
+//	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "liteclient_requests_total"}, metrics.RequestLabels)
+//	observer := metrics.NewObserver(metrics.ObserverMetrics{
+//		Requests: metrics.CounterFunc(func(labels ...string) { requests.WithLabelValues(labels...).Inc() }),
+//	})
+//	client, err := liteapi.NewClient(liteapi.Mainnet(), liteapi.WithObserver(observer))
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec interface {
+	Inc(labels ...string)
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec interface {
+	Observe(value float64, labels ...string)
+}
+
+// GaugeVec is a set of gauges partitioned by label values.
+type GaugeVec interface {
+	Set(value float64, labels ...string)
+}
+
+// GaugeVecDeleter can be implemented by GaugeVec to remove a gauge with the given label values.
+// PoolExporter uses it to stop reporting lite servers removed from a pool.
+type GaugeVecDeleter interface {
+	Delete(labels ...string)
+}
+
+// Gauge is a single gauge without labels, prometheus.Gauge implements it.
+type Gauge interface {
+	Set(value float64)
+}
+
+// CounterFunc is an adapter to use a function as CounterVec.
+type CounterFunc func(labels ...string)
+
+func (f CounterFunc) Inc(labels ...string) {
+	f(labels...)
+}
+
+// HistogramFunc is an adapter to use a function as HistogramVec.
+type HistogramFunc func(value float64, labels ...string)
+
+func (f HistogramFunc) Observe(value float64, labels ...string) {
+	f(value, labels...)
+}
+
+// GaugeFunc is an adapter to use a function as GaugeVec.
+type GaugeFunc func(value float64, labels ...string)
+
+func (f GaugeFunc) Set(value float64, labels ...string) {
+	f(value, labels...)
+}
+
+var (
+	// RequestLabels are labels of ObserverMetrics.Requests and ObserverMetrics.Duration.
+	RequestLabels = []string{"host", "method", "status"}
+	// ErrorLabels are labels of ObserverMetrics.Errors.
+	ErrorLabels = []string{"host", "method", "kind"}
+	// ConnectionLabels are labels of PoolMetrics gauges except Connections.
+	ConnectionLabels = []string{"host"}
+)
+
+// Request statuses.
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Error kinds.
+const (
+	// ErrorKindLiteServer is an error returned by a lite server like "account not found".
+	ErrorKindLiteServer = "liteserver"
+	// ErrorKindClient is an error of liteclient like a request timeout.
+	ErrorKindClient = "client"
+	// ErrorKindQuorumMismatch is reported when a lite server's response differs from the quorum.
+	ErrorKindQuorumMismatch = "quorum_mismatch"
+	// ErrorKindTransport is any other error, usually a network one.
+	ErrorKindTransport = "transport"
+)
+
+// ObserverMetrics holds metrics updated by Observer.
+// Nil metrics are skipped.
+type ObserverMetrics struct {
+	// Requests counts requests labeled with RequestLabels.
+	Requests CounterVec
+	// Duration observes request durations in seconds labeled with RequestLabels.
+	Duration HistogramVec
+	// Errors counts failed requests labeled with ErrorLabels.
+	Errors CounterVec
+}
+
+// Observer implements liteclient.RequestObserver.
+type Observer struct {
+	metrics ObserverMetrics
+}
+
+var _ liteclient.RequestObserver = &Observer{}
+
+func NewObserver(metrics ObserverMetrics) *Observer {
+	return &Observer{metrics: metrics}
+}
+
+func (o *Observer) ObserveRequest(host string, method liteclient.RequestName, duration time.Duration, err error) {
+	status := StatusOK
+	if err != nil {
+		status = StatusError
+	}
+	if o.metrics.Requests != nil {
+		o.metrics.Requests.Inc(host, method, status)
+	}
+	if o.metrics.Duration != nil {
+		o.metrics.Duration.Observe(duration.Seconds(), host, method, status)
+	}
+	if err != nil && o.metrics.Errors != nil {
+		o.metrics.Errors.Inc(host, method, errorKind(err))
+	}
+}
+
+func errorKind(err error) string {
+	var liteServerErr liteclient.LiteServerErrorC
+	switch {
+	case errors.As(err, &liteServerErr):
+		return ErrorKindLiteServer
+	case errors.Is(err, pool.ErrQuorumMismatch):
+		return ErrorKindQuorumMismatch
+	case liteclient.IsClientError(err):
+		return ErrorKindClient
+	default:
+		return ErrorKindTransport
+	}
+}
+
+// PoolMetrics holds gauges updated by ExportPoolStatus.
+// All gauges except Connections are labeled with ConnectionLabels, nil gauges are skipped.
+type PoolMetrics struct {
+	// Connections is a total number of connections in a pool.
+	Connections Gauge
+	// Connected is 1 if a connection works, 0 otherwise.
+	Connected GaugeVec
+	// Archive is 1 if a lite server is an archive node, 0 otherwise.
+	Archive GaugeVec
+	// MasterSeqno is a seqno of the latest masterchain block known to a lite server.
+	MasterSeqno GaugeVec
+	// MasterSeqnoLag is a number of masterchain blocks a lite server is behind the best one in a pool.
+	MasterSeqnoLag GaugeVec
+	// RoundTrip is an average round trip time in seconds.
+	RoundTrip GaugeVec
+	// HealthScore is a health score of a connection between 0 and 1.
+	HealthScore GaugeVec
+	// RemainingRequests is a number of requests a lite proxy allows to send right now.
+	RemainingRequests GaugeVec
+}
+
+// ExportPoolStatus updates the given gauges with the pool status.
+// Gauges of lite servers removed from the pool are left as is, take a look at PoolExporter.
+func ExportPoolStatus(status pool.Status, metrics PoolMetrics) {
+	if metrics.Connections != nil {
+		metrics.Connections.Set(float64(len(status.Connections)))
+	}
+	var maxSeqno uint32
+	for _, c := range status.Connections {
+		maxSeqno = max(maxSeqno, c.MasterSeqno)
+	}
+	for _, c := range status.Connections {
+		set(metrics.Connected, boolToFloat(c.Connected), c.ServerHost)
+		set(metrics.Archive, boolToFloat(c.Archive), c.ServerHost)
+		set(metrics.MasterSeqno, float64(c.MasterSeqno), c.ServerHost)
+		set(metrics.MasterSeqnoLag, float64(maxSeqno-c.MasterSeqno), c.ServerHost)
+		set(metrics.RoundTrip, c.AverageRoundTrip.Seconds(), c.ServerHost)
+		set(metrics.HealthScore, c.Health.Score, c.ServerHost)
+		set(metrics.RemainingRequests, float64(c.RateLimit.Remaining), c.ServerHost)
+	}
+}
+
+// PoolExporter updates PoolMetrics with a pool status like ExportPoolStatus
+// and additionally removes gauges of lite servers that are not in the pool anymore
+// if the gauges implement GaugeVecDeleter.
+type PoolExporter struct {
+	metrics PoolMetrics
+	mu      sync.Mutex
+	// hosts are lite servers reported by the previous Export.
+	hosts map[string]struct{}
+}
+
+func NewPoolExporter(metrics PoolMetrics) *PoolExporter {
+	return &PoolExporter{metrics: metrics, hosts: map[string]struct{}{}}
+}
+
+// Export updates the gauges with the pool status.
+func (e *PoolExporter) Export(status pool.Status) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ExportPoolStatus(status, e.metrics)
+	hosts := make(map[string]struct{}, len(status.Connections))
+	for _, c := range status.Connections {
+		hosts[c.ServerHost] = struct{}{}
+	}
+	for host := range e.hosts {
+		if _, ok := hosts[host]; ok {
+			continue
+		}
+		for _, gauge := range []GaugeVec{
+			e.metrics.Connected,
+			e.metrics.Archive,
+			e.metrics.MasterSeqno,
+			e.metrics.MasterSeqnoLag,
+			e.metrics.RoundTrip,
+			e.metrics.HealthScore,
+			e.metrics.RemainingRequests,
+		} {
+			if deleter, ok := gauge.(GaugeVecDeleter); ok {
+				deleter.Delete(host)
+			}
+		}
+	}
+	e.hosts = hosts
+}
+
+// RunPoolExporter exports the pool status with PoolExporter every interval till ctx is done.
+// status is usually liteapi.Client.GetPoolStatus.
+func RunPoolExporter(ctx context.Context, interval time.Duration, status func() pool.Status, metrics PoolMetrics) {
+	exporter := NewPoolExporter(metrics)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		exporter.Export(status())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func set(gauge GaugeVec, value float64, labels ...string) {
+	if gauge != nil {
+		gauge.Set(value, labels...)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+type mockVec struct {
+	values map[string]float64
+}
+
+func newMockVec() *mockVec {
+	return &mockVec{values: map[string]float64{}}
+}
+
+func (m *mockVec) Inc(labels ...string) {
+	m.values[strings.Join(labels, ",")]++
+}
+
+func (m *mockVec) Observe(value float64, labels ...string) {
+	m.values[strings.Join(labels, ",")] += value
+}
+
+func (m *mockVec) Set(value float64, labels ...string) {
+	m.values[strings.Join(labels, ",")] = value
+}
+
+func (m *mockVec) Delete(labels ...string) {
+	delete(m.values, strings.Join(labels, ","))
+}
+
+type mockGauge struct {
+	value float64
+}
+
+func (m *mockGauge) Set(value float64) {
+	m.value = value
+}
+
+func TestObserver_ObserveRequest(t *testing.T) {
+	requests, duration, errs := newMockVec(), newMockVec(), newMockVec()
+	o := NewObserver(ObserverMetrics{Requests: requests, Duration: duration, Errors: errs})
+
+	const method = "liteServer.getMasterchainInfo"
+	o.ObserveRequest("host", method, time.Second, nil)
+	o.ObserveRequest("host", method, 2*time.Second, liteclient.LiteServerErrorC{Code: 651, Message: "not found"})
+	o.ObserveRequest("host", method, time.Second, fmt.Errorf("wrapped: %w", pool.ErrQuorumMismatch))
+	o.ObserveRequest("host", method, time.Second, errors.New("connection reset"))
+
+	wantRequests := map[string]float64{
+		"host," + method + ",ok":    1,
+		"host," + method + ",error": 3,
+	}
+	wantErrors := map[string]float64{
+		"host," + method + ",liteserver":      1,
+		"host," + method + ",quorum_mismatch": 1,
+		"host," + method + ",transport":       1,
+	}
+	for key, want := range wantRequests {
+		if got := requests.values[key]; got != want {
+			t.Errorf("requests[%v] = %v, want %v", key, got, want)
+		}
+	}
+	for key, want := range wantErrors {
+		if got := errs.values[key]; got != want {
+			t.Errorf("errors[%v] = %v, want %v", key, got, want)
+		}
+	}
+	if got := duration.values["host,"+method+",error"]; got != 4 {
+		t.Errorf("duration = %v, want 4", got)
+	}
+}
+
+func TestExportPoolStatus(t *testing.T) {
+	connections := &mockGauge{}
+	connected, lag, rtt := newMockVec(), newMockVec(), newMockVec()
+	status := pool.Status{
+		Connections: []pool.ConnStatus{
+			{ServerHost: "a", Connected: true, MasterSeqno: 100, AverageRoundTrip: 200 * time.Millisecond},
+			{ServerHost: "b", Connected: false, MasterSeqno: 95},
+		},
+	}
+	// nil gauges must be skipped.
+	ExportPoolStatus(status, PoolMetrics{
+		Connections:    connections,
+		Connected:      connected,
+		MasterSeqnoLag: lag,
+		RoundTrip:      rtt,
+	})
+	if connections.value != 2 {
+		t.Errorf("connections = %v, want 2", connections.value)
+	}
+	if connected.values["a"] != 1 || connected.values["b"] != 0 {
+		t.Errorf("unexpected connected: %v", connected.values)
+	}
+	if lag.values["a"] != 0 || lag.values["b"] != 5 {
+		t.Errorf("unexpected lag: %v", lag.values)
+	}
+	if rtt.values["a"] != 0.2 {
+		t.Errorf("unexpected round trip: %v", rtt.values)
+	}
+}
+
+func TestPoolExporter_RemovesStaleHosts(t *testing.T) {
+	connections := &mockGauge{}
+	connected := newMockVec()
+	exporter := NewPoolExporter(PoolMetrics{Connections: connections, Connected: connected})
+	exporter.Export(pool.Status{Connections: []pool.ConnStatus{
+		{ServerHost: "a", Connected: true},
+		{ServerHost: "b", Connected: true},
+	}})
+	// lite server b is dropped from the pool by UpdateLiteServers.
+	exporter.Export(pool.Status{Connections: []pool.ConnStatus{
+		{ServerHost: "a", Connected: true},
+		{ServerHost: "c", Connected: false},
+	}})
+	if _, ok := connected.values["b"]; ok {
+		t.Errorf("gauge of the dropped lite server must be removed")
+	}
+	if connected.values["a"] != 1 || connected.values["c"] != 0 || len(connected.values) != 2 {
+		t.Errorf("unexpected connected: %v", connected.values)
+	}
+	if connections.value != 2 {
+		t.Errorf("connections = %v, want 2", connections.value)
+	}
+}
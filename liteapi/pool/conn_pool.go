@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -26,18 +27,26 @@ type Strategy string
 const (
 	BestPingStrategy       = "best-ping"
 	FirstWorkingConnection = "first-working"
+	QuorumStrategy         = "quorum"
 )
 
 // ConnPool is a pool of connections to lite servers
-// that implements two different strategy:
+// that implements three different strategies:
 //  1. BestPingStrategy - it'll switch to a connection with the best ping.
 //  2. FirstWorkingConnection - it'll switch to the first working connection.
+//  3. QuorumStrategy - it works as BestPingStrategy, but additionally
+//     read requests sent with Quorum are cross-checked by several connections.
 //
-// For both strategies, a connection has to be not more than 1 block behind the head of masterchain to be considered as working.
+// For all strategies, a connection has to be not more than 1 block behind the head of masterchain to be considered as working.
 type ConnPool struct {
 	strategy           Strategy
 	updateBestInterval time.Duration
 	observer           liteclient.RequestObserver
+	recorder           liteclient.Recorder
+	// quorumSize is a number of connections a read request is sent to with QuorumStrategy.
+	quorumSize int
+	// quorumThreshold is a number of connections that must agree on a response with QuorumStrategy.
+	quorumThreshold int
 
 	masterHeadUpdatedCh chan masterHeadUpdated
 
@@ -46,12 +55,24 @@ type ConnPool struct {
 	bestConn   conn
 	waitListID uint64
 	waitList   map[uint64]chan ton.BlockIDExt
+	// nextConnID is an ID of the next connection added by UpdateLiteServers.
+	nextConnID int
+	// settings stores parameters of InitializeConnections to open new connections later.
+	settings connSettings
+}
+
+type connSettings struct {
+	timeout              time.Duration
+	maxConnections       int
+	workersPerConnection int
+	detectArchiveNodes   bool
 }
 
 // conn contains all methods needed by a pool.
 // used to implement tests.
 type conn interface {
 	ID() int
+	Host() string
 	MasterHead() ton.BlockIDExt
 	SetMasterHead(ton.BlockIDExt)
 	IsOK() bool
@@ -59,11 +80,21 @@ type conn interface {
 	Run(ctx context.Context, detectArchive bool)
 	IsArchiveNode() bool
 	AverageRoundTrip() time.Duration
+	ServerKey() string
+	Drain(timeout time.Duration)
+	Close()
+	HasBudget() bool
+	CheckHealth(maxSeqno uint32)
 	Status() ConnStatus
 }
 
 type Options struct {
 	Observer liteclient.RequestObserver
+	// Recorder gets raw requests and responses of all connections.
+	Recorder liteclient.Recorder
+	// QuorumSize and QuorumThreshold configure QuorumStrategy.
+	QuorumSize      int
+	QuorumThreshold int
 }
 
 type Option func(*Options)
@@ -74,6 +105,22 @@ func WithObserver(observer liteclient.RequestObserver) Option {
 	}
 }
 
+func WithRecorder(recorder liteclient.Recorder) Option {
+	return func(o *Options) {
+		o.Recorder = recorder
+	}
+}
+
+// WithQuorum configures QuorumStrategy to send a read request to size connections
+// and to wait for threshold of them to agree.
+// A threshold below 1 is replaced with 1.
+func WithQuorum(size, threshold int) Option {
+	return func(o *Options) {
+		o.QuorumSize = size
+		o.QuorumThreshold = threshold
+	}
+}
+
 // New returns a new instance of a connections pool.
 func New(strategy Strategy, options ...Option) *ConnPool {
 	opts := Options{}
@@ -83,6 +130,9 @@ func New(strategy Strategy, options ...Option) *ConnPool {
 	return &ConnPool{
 		strategy:            strategy,
 		observer:            opts.Observer,
+		recorder:            opts.Recorder,
+		quorumSize:          max(opts.QuorumSize, opts.QuorumThreshold, 1),
+		quorumThreshold:     max(opts.QuorumThreshold, 1),
 		updateBestInterval:  updateBestConnectionInterval,
 		waitList:            map[uint64]chan ton.BlockIDExt{},
 		masterHeadUpdatedCh: make(chan masterHeadUpdated, 10),
@@ -90,18 +140,22 @@ func New(strategy Strategy, options ...Option) *ConnPool {
 }
 
 func (p *ConnPool) InitializeConnections(ctx context.Context, timeout time.Duration, maxConnections int, workersPerConnection int, detectArchiveNodes bool, servers []config.LiteServer) chan error {
+	p.mu.Lock()
+	p.settings = connSettings{
+		timeout:              timeout,
+		maxConnections:       maxConnections,
+		workersPerConnection: workersPerConnection,
+		detectArchiveNodes:   detectArchiveNodes,
+	}
+	p.nextConnID = len(servers)
+	p.mu.Unlock()
+
 	ch := make(chan error, 1)
 	go func() {
 		clientsCh := make(chan clientWrapper, len(servers))
 		for connID, server := range servers {
 			go func(connID int, server config.LiteServer) {
-				cli, _ := connect(ctx, timeout, server, workersPerConnection, p.observer)
-				// TODO: log error
-				clientsCh <- clientWrapper{
-					connID:     connID,
-					cli:        cli,
-					serverHost: server.Host,
-				}
+				clientsCh <- p.dial(ctx, connID, server)
 			}(connID, server)
 		}
 
@@ -119,8 +173,9 @@ func (p *ConnPool) InitializeConnections(ctx context.Context, timeout time.Durat
 					continue
 				}
 				if p.ConnectionsNumber() < maxConnections {
-					c := p.addConnection(wrapper.connID, wrapper.cli, wrapper.serverHost)
-					go c.Run(context.TODO(), detectArchiveNodes)
+					p.addConnection(wrapper)
+				} else {
+					wrapper.cli.Close()
 				}
 				if p.ConnectionsNumber() == maxConnections {
 					processedConnections = len(servers)
@@ -137,7 +192,31 @@ func (p *ConnPool) InitializeConnections(ctx context.Context, timeout time.Durat
 	return ch
 }
 
-func connect(ctx context.Context, timeout time.Duration, server config.LiteServer, n int, observer liteclient.RequestObserver) (*liteclient.Client, error) {
+// dial opens a connection to the given lite server.
+// If the lite server is unavailable, the returned wrapper contains no client.
+func (p *ConnPool) dial(ctx context.Context, connID int, server config.LiteServer) clientWrapper {
+	p.mu.RLock()
+	settings := p.settings
+	p.mu.RUnlock()
+
+	limiter := newRateLimiter()
+	h := newHealth()
+	var extra []liteclient.Options
+	if p.recorder != nil {
+		extra = append(extra, liteclient.OptionRecorder(p.recorder))
+	}
+	cli, _ := connect(ctx, settings.timeout, server, settings.workersPerConnection, &healthObserver{health: h, next: p.observer}, limiter, extra...)
+	// TODO: log error
+	return clientWrapper{
+		connID:  connID,
+		cli:     cli,
+		limiter: limiter,
+		health:  h,
+		server:  server,
+	}
+}
+
+func connect(ctx context.Context, timeout time.Duration, server config.LiteServer, n int, observer liteclient.RequestObserver, limiter *rateLimiter, extra ...liteclient.Options) (*liteclient.Client, error) {
 	serverPubkey, err := base64.StdEncoding.DecodeString(server.Key)
 	if err != nil {
 		return nil, err
@@ -149,30 +228,44 @@ func connect(ctx context.Context, timeout time.Duration, server config.LiteServe
 	opts := []liteclient.Options{
 		liteclient.OptionTimeout(timeout),
 		liteclient.OptionWorkersPerConnection(n),
+		liteclient.OptionRateLimiter(limiter),
+		liteclient.OptionObserver(observer),
 	}
-	if observer != nil {
-		opts = append(opts, liteclient.OptionObserver(observer))
-	}
+	opts = append(opts, extra...)
 	cli := liteclient.NewClient(c, opts...)
 	if _, err := cli.LiteServerGetMasterchainInfo(ctx); err != nil {
+		cli.Close()
 		return nil, err
 	}
 	return cli, nil
 }
 
 type clientWrapper struct {
-	connID     int
-	cli        *liteclient.Client
-	serverHost string
+	connID  int
+	cli     *liteclient.Client
+	limiter *rateLimiter
+	health  *health
+	server  config.LiteServer
 }
 
-func (p *ConnPool) addConnection(connID int, cli *liteclient.Client, serverHost string) *connection {
+// addConnection adds a new connection to the pool and starts it.
+func (p *ConnPool) addConnection(wrapper clientWrapper) *connection {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.addConnectionLocked(wrapper)
+}
+
+// addConnectionLocked must be called with the mutex held.
+func (p *ConnPool) addConnectionLocked(wrapper clientWrapper) *connection {
+	ctx, cancel := context.WithCancel(context.Background())
 	c := &connection{
-		id:                  connID,
-		serverHost:          serverHost,
-		client:              cli,
+		id:                  wrapper.connID,
+		serverHost:          wrapper.server.Host,
+		serverKey:           wrapper.server.Key,
+		client:              wrapper.cli,
+		limiter:             wrapper.limiter,
+		health:              wrapper.health,
+		cancel:              cancel,
 		masterHeadUpdatedCh: p.masterHeadUpdatedCh,
 	}
 	p.conns = append(p.conns, c)
@@ -182,9 +275,108 @@ func (p *ConnPool) addConnection(connID int, cli *liteclient.Client, serverHost
 	if len(p.conns) == 1 {
 		p.bestConn = c
 	}
+	go c.Run(ctx, p.settings.detectArchiveNodes)
 	return c
 }
 
+func serverKey(server config.LiteServer) string {
+	return server.Host + "/" + server.Key
+}
+
+// UpdateLiteServers replaces lite servers of the pool with the given ones.
+// Connections to new lite servers are opened while the number of connections doesn't exceed MaxConnections.
+// Connections to lite servers that are not in the list anymore are removed from the pool immediately,
+// so no new requests are sent to them, and closed after a timeout to let in-flight requests finish.
+// If none of the given lite servers is available, the pool is left intact and an error is returned.
+func (p *ConnPool) UpdateLiteServers(ctx context.Context, servers []config.LiteServer) error {
+	wanted := make(map[string]struct{}, len(servers))
+	for _, server := range servers {
+		wanted[serverKey(server)] = struct{}{}
+	}
+	p.mu.Lock()
+	existing := map[string]struct{}{}
+	kept := 0
+	for _, c := range p.conns {
+		key := c.ServerKey()
+		existing[key] = struct{}{}
+		if _, ok := wanted[key]; ok {
+			kept++
+		}
+	}
+	settings := p.settings
+	var newServers []config.LiteServer
+	for _, server := range servers {
+		if _, ok := existing[serverKey(server)]; !ok {
+			newServers = append(newServers, server)
+		}
+	}
+	firstID := p.nextConnID
+	p.nextConnID += len(newServers)
+	p.mu.Unlock()
+
+	// connect to new lite servers without holding the lock.
+	wrappers := make([]clientWrapper, len(newServers))
+	var wg sync.WaitGroup
+	for i, server := range newServers {
+		wg.Add(1)
+		go func(i int, server config.LiteServer) {
+			defer wg.Done()
+			wrappers[i] = p.dial(ctx, firstID+i, server)
+		}(i, server)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	available := kept
+	for _, w := range wrappers {
+		if w.cli != nil {
+			available++
+		}
+	}
+	if available == 0 {
+		return fmt.Errorf("all liteservers are unavailable")
+	}
+	var removed []conn
+	conns := make([]conn, 0, len(p.conns))
+	for _, c := range p.conns {
+		if _, ok := wanted[c.ServerKey()]; ok {
+			conns = append(conns, c)
+		} else {
+			removed = append(removed, c)
+		}
+	}
+	p.conns = conns
+	for _, w := range wrappers {
+		if w.cli == nil {
+			continue
+		}
+		if len(p.conns) >= settings.maxConnections {
+			w.cli.Close()
+			continue
+		}
+		p.addConnectionLocked(w)
+	}
+	if len(removed) > 0 && !slices.Contains(p.conns, p.bestConn) {
+		p.bestConn = p.conns[0]
+		p.updateBestLocked()
+	}
+	for _, c := range removed {
+		go c.Drain(settings.timeout)
+	}
+	return nil
+}
+
+// Close stops all connections of the pool and closes them.
+// Requests that are in flight are interrupted.
+func (p *ConnPool) Close() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, c := range p.conns {
+		c.Close()
+	}
+}
+
 func (p *ConnPool) Run(ctx context.Context) {
 	tickTock := time.NewTicker(p.updateBestInterval)
 	defer tickTock.Stop()
@@ -205,7 +397,11 @@ func (p *ConnPool) Run(ctx context.Context) {
 func (p *ConnPool) updateBest() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.updateBestLocked()
+}
 
+// updateBestLocked must be called with the mutex held.
+func (p *ConnPool) updateBestLocked() {
 	if len(p.conns) == 0 {
 		return
 	}
@@ -217,9 +413,12 @@ func (p *ConnPool) updateBest() {
 			maxSeqno = masterSeqno
 		}
 	}
+	for _, c := range p.conns {
+		c.CheckHealth(maxSeqno)
+	}
 
 	switch p.strategy {
-	case BestPingStrategy:
+	case BestPingStrategy, QuorumStrategy:
 		if bestConn := p.findBestPingConnection(maxSeqno); bestConn != nil {
 			p.bestConn = bestConn
 		}
@@ -298,7 +497,27 @@ func (p *ConnPool) BestMasterchainInfoClient() *MasterchainInfoClient {
 	}
 }
 
+// withBudget returns the given connection if it has remaining rate limit budget.
+// Otherwise, it looks for another working connection that is not behind the given one and has budget.
+// If there is no such connection, the given one is returned and a request waits for its turn.
+// The given connection is also replaced if it has been evicted by its circuit breaker since the last update of the best connection.
+func (p *ConnPool) withBudget(c conn) conn {
+	if c.HasBudget() && c.IsOK() {
+		return c
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	seqno := c.MasterHead().Seqno
+	for _, other := range p.conns {
+		if other.IsOK() && other.MasterHead().Seqno >= seqno && other.HasBudget() {
+			return other
+		}
+	}
+	return c
+}
+
 // BestMasterchainClient returns a liteclient and its known masterchain head.
+// Connections that ran out of their rate limit budget are avoided if possible.
 func (p *ConnPool) BestMasterchainClient(ctx context.Context) (*liteclient.Client, ton.BlockIDExt, error) {
 	bestConnection := p.bestConnection()
 	if bestConnection == nil {
@@ -306,7 +525,8 @@ func (p *ConnPool) BestMasterchainClient(ctx context.Context) (*liteclient.Clien
 	}
 	masterHead := bestConnection.MasterHead()
 	if masterHead.Seqno > 0 {
-		return bestConnection.Client(), bestConnection.MasterHead(), nil
+		c := p.withBudget(bestConnection)
+		return c.Client(), c.MasterHead(), nil
 	}
 	// so this client is not initialized yet,
 	// let's wait for it to be initialized.
@@ -321,14 +541,53 @@ func (p *ConnPool) BestMasterchainClient(ctx context.Context) (*liteclient.Clien
 	}
 }
 func (p *ConnPool) BestArchiveClient(ctx context.Context) (*liteclient.Client, ton.BlockIDExt, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var archive conn
 	for _, c := range p.conns {
 		if c.IsOK() && c.IsArchiveNode() {
-			return c.Client(), c.MasterHead(), nil
+			if c.HasBudget() {
+				return c.Client(), c.MasterHead(), nil
+			}
+			if archive == nil {
+				archive = c
+			}
 		}
 	}
+	if archive != nil {
+		return archive.Client(), archive.MasterHead(), nil
+	}
 	return nil, ton.BlockIDExt{}, fmt.Errorf("no archive nodes available")
 }
 
+// AlternativeClient returns a liteclient of a working connection other than the given one.
+// Connections with remaining rate limit budget and the best ping are preferred.
+// It is used to hedge and retry requests.
+func (p *ConnPool) AlternativeClient(exclude *liteclient.Client) (*liteclient.Client, ton.BlockIDExt, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var maxSeqno uint32
+	for _, c := range p.conns {
+		maxSeqno = max(maxSeqno, c.MasterHead().Seqno)
+	}
+	var best conn
+	for _, c := range p.conns {
+		if c.Client() == exclude || !c.IsOK() || c.MasterHead().Seqno == 0 || c.MasterHead().Seqno+1 < maxSeqno {
+			continue
+		}
+		if best == nil || (c.HasBudget() && !best.HasBudget()) ||
+			(c.HasBudget() == best.HasBudget() && c.AverageRoundTrip() < best.AverageRoundTrip()) {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, ton.BlockIDExt{}, ErrNoConnections
+	}
+	return best.Client(), best.MasterHead(), nil
+}
+
 // BestClientByAccountID returns a liteclient and its known masterchain head.
 func (p *ConnPool) BestClientByAccountID(ctx context.Context, accountID ton.AccountID, archiveRequired bool) (*liteclient.Client, ton.BlockIDExt, error) {
 	if archiveRequired {
@@ -343,6 +602,11 @@ func (p *ConnPool) BestClientByBlockID(ctx context.Context, blockID ton.BlockID)
 	return server, err
 }
 
+// Strategy returns a strategy of this pool.
+func (p *ConnPool) Strategy() Strategy {
+	return p.strategy
+}
+
 // ConnectionsNumber returns a number of connections in this pool.
 func (p *ConnPool) ConnectionsNumber() int {
 	p.mu.RLock()
@@ -2,12 +2,14 @@ package pool
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/tonkeeper/tongo/config"
 	"github.com/tonkeeper/tongo/liteclient"
 	"github.com/tonkeeper/tongo/ton"
 )
@@ -17,6 +19,32 @@ type mockConn struct {
 	seqno        uint32
 	isOK         bool
 	avgRoundTrip time.Duration
+	noBudget     bool
+	client       *liteclient.Client
+	serverKey    string
+	drained      chan struct{}
+	closed       bool
+}
+
+func (m *mockConn) ServerKey() string {
+	return m.serverKey
+}
+
+func (m *mockConn) Drain(timeout time.Duration) {
+	if m.drained != nil {
+		close(m.drained)
+	}
+}
+
+func (m *mockConn) Close() {
+	m.closed = true
+}
+
+func (m *mockConn) HasBudget() bool {
+	return !m.noBudget
+}
+
+func (m *mockConn) CheckHealth(maxSeqno uint32) {
 }
 
 func (m *mockConn) AverageRoundTrip() time.Duration {
@@ -31,6 +59,10 @@ func (m *mockConn) ID() int {
 	return m.id
 }
 
+func (m *mockConn) Host() string {
+	return fmt.Sprintf("host-%d", m.id)
+}
+
 func (m *mockConn) MasterHead() ton.BlockIDExt {
 	return ton.BlockIDExt{BlockID: ton.BlockID{Seqno: m.seqno}}
 }
@@ -48,7 +80,7 @@ func (m *mockConn) IsOK() bool {
 }
 
 func (m *mockConn) Client() *liteclient.Client {
-	panic("implement me")
+	return m.client
 }
 
 func (m *mockConn) Status() ConnStatus {
@@ -331,3 +363,158 @@ func TestChannelBufferCapacity(t *testing.T) {
 		t.Errorf("insufficient channel buffer: got %d, expected >= 10", capacity)
 	}
 }
+
+func TestConnPool_withBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		conns  []conn
+		wantID int
+	}{
+		{
+			name: "best connection has budget",
+			conns: []conn{
+				&mockConn{seqno: 100, isOK: true, id: 0},
+				&mockConn{seqno: 100, isOK: true, id: 1},
+			},
+			wantID: 0,
+		},
+		{
+			name: "switch to a connection with budget",
+			conns: []conn{
+				&mockConn{seqno: 100, isOK: true, id: 0, noBudget: true},
+				&mockConn{seqno: 99, isOK: true, id: 1},
+				&mockConn{seqno: 100, isOK: false, id: 2},
+				&mockConn{seqno: 100, isOK: true, id: 3},
+			},
+			wantID: 3,
+		},
+		{
+			name: "all connections are exhausted",
+			conns: []conn{
+				&mockConn{seqno: 100, isOK: true, id: 0, noBudget: true},
+				&mockConn{seqno: 100, isOK: true, id: 1, noBudget: true},
+			},
+			wantID: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ConnPool{conns: tt.conns}
+			c := p.withBudget(tt.conns[0]).(*mockConn)
+			if tt.wantID != c.id {
+				t.Fatalf("expected connection id %d, got %d", tt.wantID, c.id)
+			}
+		})
+	}
+}
+
+func TestConnPool_AlternativeClient(t *testing.T) {
+	primary := &mockConn{id: 0, seqno: 100, isOK: true, client: &liteclient.Client{}, avgRoundTrip: time.Millisecond}
+	slow := &mockConn{id: 1, seqno: 100, isOK: true, client: &liteclient.Client{}, avgRoundTrip: 30 * time.Millisecond}
+	fast := &mockConn{id: 2, seqno: 100, isOK: true, client: &liteclient.Client{}, avgRoundTrip: 10 * time.Millisecond}
+	behind := &mockConn{id: 3, seqno: 90, isOK: true, client: &liteclient.Client{}}
+	exhausted := &mockConn{id: 4, seqno: 100, isOK: true, client: &liteclient.Client{}, noBudget: true}
+	p := &ConnPool{conns: []conn{primary, slow, fast, behind, exhausted}}
+
+	client, _, err := p.AlternativeClient(primary.client)
+	if err != nil {
+		t.Fatalf("AlternativeClient() failed: %v", err)
+	}
+	if client != fast.client {
+		t.Fatalf("want the fastest alternative connection")
+	}
+	p = &ConnPool{conns: []conn{primary}}
+	if _, _, err := p.AlternativeClient(primary.client); err != ErrNoConnections {
+		t.Fatalf("want ErrNoConnections, got: %v", err)
+	}
+}
+
+func TestConnPool_Close(t *testing.T) {
+	first := &mockConn{id: 0, seqno: 100, isOK: true, client: &liteclient.Client{}}
+	second := &mockConn{id: 1, seqno: 100, isOK: true, client: &liteclient.Client{}}
+	p := &ConnPool{conns: []conn{first, second}, bestConn: first}
+	p.Close()
+	if !first.closed || !second.closed {
+		t.Fatalf("all connections must be closed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &connection{cancel: cancel, client: &liteclient.Client{}}
+	c.Close()
+	if ctx.Err() == nil {
+		t.Fatalf("closed connection must be stopped")
+	}
+}
+
+func TestConnPool_UpdateLiteServers(t *testing.T) {
+	keep := &mockConn{id: 0, seqno: 10, isOK: true, serverKey: "1.1.1.1:1/a"}
+	removed := &mockConn{id: 1, seqno: 11, isOK: true, serverKey: "2.2.2.2:2/b", drained: make(chan struct{})}
+	p := &ConnPool{
+		conns:    []conn{keep, removed},
+		bestConn: removed,
+		settings: connSettings{maxConnections: 2},
+	}
+
+	err := p.UpdateLiteServers(context.Background(), []config.LiteServer{
+		{Host: "3.3.3.3:3", Key: "not base64"},
+	})
+	if err == nil {
+		t.Fatalf("expected error when no lite server is available")
+	}
+	if p.ConnectionsNumber() != 2 {
+		t.Fatalf("pool must be left intact, got %v connections", p.ConnectionsNumber())
+	}
+
+	err = p.UpdateLiteServers(context.Background(), []config.LiteServer{
+		{Host: "1.1.1.1:1", Key: "a"},
+		{Host: "3.3.3.3:3", Key: "not base64"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateLiteServers() failed: %v", err)
+	}
+	if p.ConnectionsNumber() != 1 || p.conns[0] != keep {
+		t.Fatalf("want only the kept connection, got %v", p.conns)
+	}
+	if p.bestConnection() != keep {
+		t.Fatalf("best connection must be replaced")
+	}
+	select {
+	case <-removed.drained:
+	case <-time.After(time.Second):
+		t.Fatalf("removed connection must be drained")
+	}
+}
+
+func TestConnPool_UpdateLiteServersConcurrently(t *testing.T) {
+	keep := &mockConn{id: 0, seqno: 10, isOK: true, serverKey: "1.1.1.1:1/a", client: &liteclient.Client{}}
+	removed := &mockConn{id: 1, seqno: 10, isOK: true, serverKey: "2.2.2.2:2/b", client: &liteclient.Client{}}
+	p := &ConnPool{
+		conns:    []conn{keep, removed},
+		bestConn: keep,
+		settings: connSettings{maxConnections: 2},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg, started sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			for ctx.Err() == nil {
+				p.BestArchiveClient(ctx)
+			}
+		}()
+	}
+	started.Wait()
+	for i := 0; i < 1000; i++ {
+		p.mu.Lock()
+		p.conns = []conn{keep, removed}
+		p.mu.Unlock()
+		if err := p.UpdateLiteServers(context.Background(), []config.LiteServer{{Host: "1.1.1.1:1", Key: "a"}}); err != nil {
+			t.Fatalf("UpdateLiteServers() failed: %v", err)
+		}
+	}
+	cancel()
+	wg.Wait()
+}
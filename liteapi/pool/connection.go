@@ -12,7 +12,14 @@ import (
 type connection struct {
 	id         int
 	serverHost string
+	serverKey  string
 	client     *liteclient.Client
+	// cancel stops Run of this connection.
+	cancel context.CancelFunc
+	// limiter throttles requests according to a quota reported by a lite proxy.
+	limiter *rateLimiter
+	// health tracks errors of requests and evicts this connection if it misbehaves.
+	health *health
 
 	// masterHeadUpdatedCh is used to send a notification when a known master head is changed.
 	masterHeadUpdatedCh chan masterHeadUpdated
@@ -29,6 +36,7 @@ type masterHeadUpdated struct {
 }
 
 func (c *connection) Run(ctx context.Context, detectArchive bool) {
+	go c.updateRateLimit(ctx)
 	if detectArchive {
 		go func() {
 			ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
@@ -88,15 +96,84 @@ func (c *connection) Run(ctx context.Context, detectArchive bool) {
 	}
 }
 
-// IsOK returns true if there is no problems with the underlying liteclient and its connection to a lite server.
+// updateRateLimit requests a quota of a lite proxy.
+// Regular lite servers don't support liteProxy.getRequestRateLimit,
+// so requests to them are not throttled.
+func (c *connection) updateRateLimit(ctx context.Context) {
+	if c.limiter == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	res, err := c.client.LiteProxyGetRequestRateLimit(ctx)
+	if err != nil {
+		return
+	}
+	c.limiter.SetLimit(int(res.Limit), time.Duration(res.PerTime)*time.Second)
+}
+
+// IsOK returns true if there is no problems with the underlying liteclient and its connection to a lite server
+// and the circuit breaker of this connection is closed.
 func (c *connection) IsOK() bool {
+	if c.health != nil && !c.health.IsAvailable() {
+		return false
+	}
 	return c.client.IsOK()
 }
 
+// CheckHealth records how far this connection is behind the best known masterchain head
+// and probes the lite server if its circuit breaker waits for re-admission.
+func (c *connection) CheckHealth(maxSeqno uint32) {
+	if c.health == nil {
+		return
+	}
+	c.health.observeHead(maxSeqno - min(maxSeqno, c.MasterHead().Seqno))
+	if c.health.NeedsProbe() {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			// the result is recorded by the health observer of the liteclient.
+			res, err := c.client.LiteServerGetMasterchainInfo(ctx)
+			if err == nil {
+				c.SetMasterHead(res.Last.ToBlockIdExt())
+			}
+		}()
+	}
+}
+
 func (c *connection) ID() int {
 	return c.id
 }
 
+func (c *connection) Host() string {
+	return c.serverHost
+}
+
+// ServerKey identifies a lite server of this connection.
+func (c *connection) ServerKey() string {
+	return c.serverHost + "/" + c.serverKey
+}
+
+// Drain stops this connection and closes it after the given timeout
+// to let requests that are in flight finish.
+func (c *connection) Drain(timeout time.Duration) {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	time.Sleep(timeout)
+	c.Close()
+}
+
+// Close stops this connection and closes it immediately.
+func (c *connection) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.client != nil {
+		c.client.Close()
+	}
+}
+
 func (c *connection) Client() *liteclient.Client {
 	return c.client
 }
@@ -176,16 +253,38 @@ func (c *connection) AverageRoundTrip() time.Duration {
 	return c.client.AverageRoundTrip()
 }
 
+// HasBudget returns true if a request can be sent to this connection without waiting for its rate limit.
+func (c *connection) HasBudget() bool {
+	if c.limiter == nil {
+		return true
+	}
+	return c.limiter.HasBudget()
+}
+
 type ConnStatus struct {
 	ServerHost string
 	Connected  bool
 	Archive    bool
+	// MasterSeqno is a seqno of the latest masterchain block known to a lite server.
+	MasterSeqno      uint32
+	AverageRoundTrip time.Duration
+	RateLimit        RateLimitStatus
+	Health           HealthStatus
 }
 
 func (c *connection) Status() ConnStatus {
-	return ConnStatus{
-		ServerHost: c.serverHost,
-		Connected:  c.IsOK(),
-		Archive:    c.IsArchiveNode(),
+	status := ConnStatus{
+		ServerHost:       c.serverHost,
+		Connected:        c.IsOK(),
+		Archive:          c.IsArchiveNode(),
+		MasterSeqno:      c.MasterHead().Seqno,
+		AverageRoundTrip: c.AverageRoundTrip(),
+	}
+	if c.limiter != nil {
+		status.RateLimit = c.limiter.Status()
+	}
+	if c.health != nil {
+		status.Health = c.health.Status()
 	}
+	return status
 }
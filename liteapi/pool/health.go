@@ -0,0 +1,215 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+const (
+	// healthWindow is a number of the latest requests used to calculate an error rate.
+	healthWindow = 50
+	// minHealthSamples is a number of requests required before an error rate can open a circuit breaker.
+	minHealthSamples = 10
+	// maxErrorRate opens a circuit breaker when exceeded.
+	maxErrorRate = 0.5
+	// maxConsecutiveErrors opens a circuit breaker when reached.
+	maxConsecutiveErrors = 5
+	// maxHeadLag is a number of masterchain blocks a connection can be behind before its circuit breaker opens.
+	maxHeadLag = 3
+	// breakerCooldown is a time a circuit breaker stays open for the first time.
+	// It doubles every time a probe fails, up to maxBreakerCooldown.
+	breakerCooldown    = 10 * time.Second
+	maxBreakerCooldown = 5 * time.Minute
+)
+
+// BreakerState is a state of a circuit breaker of a connection.
+type BreakerState string
+
+const (
+	// BreakerClosed means that a connection works fine and is used.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen means that a connection is evicted till its cooldown ends.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen means that a cooldown ended and a connection is being probed.
+	// The next successful request closes the breaker, a failed one opens it again.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// HealthStatus describes health of a connection.
+type HealthStatus struct {
+	// Score is between 0 and 1, where 1 means that a connection is perfectly healthy.
+	// It is zero while a circuit breaker is open.
+	Score     float64
+	ErrorRate float64
+	Breaker   BreakerState
+	// Reason explains why a circuit breaker was opened last time.
+	Reason string
+	// OpenUntil is a time when a cooldown of an open circuit breaker ends.
+	OpenUntil time.Time
+	// HeadLag is a number of masterchain blocks a connection is behind the best known head.
+	HeadLag uint32
+}
+
+// health tracks an error rate of a connection and implements a circuit breaker.
+type health struct {
+	mu sync.Mutex
+	// outcomes is a ring buffer of the latest request outcomes, true means an error.
+	outcomes          [healthWindow]bool
+	samples           int
+	next              int
+	consecutiveErrors int
+	headLag           uint32
+
+	state     BreakerState
+	reason    string
+	cooldown  time.Duration
+	openUntil time.Time
+
+	now func() time.Time
+}
+
+func newHealth() *health {
+	return &health{
+		state:    BreakerClosed,
+		cooldown: breakerCooldown,
+		now:      time.Now,
+	}
+}
+
+// healthObserver forwards request outcomes to health of a connection and to a user observer.
+type healthObserver struct {
+	health *health
+	next   liteclient.RequestObserver
+}
+
+func (o *healthObserver) ObserveRequest(host string, method liteclient.RequestName, duration time.Duration, err error) {
+	o.health.observe(err)
+	if o.next != nil {
+		o.next.ObserveRequest(host, method, duration, err)
+	}
+}
+
+func (h *health) errorRate() float64 {
+	if h.samples == 0 {
+		return 0
+	}
+	errs := 0
+	for i := 0; i < h.samples; i++ {
+		if h.outcomes[i] {
+			errs++
+		}
+	}
+	return float64(errs) / float64(h.samples)
+}
+
+// observe records an outcome of a request.
+func (h *health) observe(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// a caller gave up on a request, for example, because a hedged request won.
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := err.(liteclient.LiteServerErrorC); ok {
+		// a lite server is alive and answers, errors like "account not found" are domain errors.
+		err = nil
+	}
+	h.updateState()
+	h.outcomes[h.next] = err != nil
+	h.next = (h.next + 1) % healthWindow
+	h.samples = min(h.samples+1, healthWindow)
+	if err == nil {
+		h.consecutiveErrors = 0
+		if h.state == BreakerHalfOpen && h.headLag <= maxHeadLag {
+			h.close()
+		}
+		return
+	}
+	h.consecutiveErrors++
+	switch {
+	case h.state == BreakerHalfOpen:
+		h.open(fmt.Sprintf("probe failed: %v", err))
+	case h.state != BreakerClosed:
+	case h.consecutiveErrors >= maxConsecutiveErrors:
+		h.open(fmt.Sprintf("%v consecutive errors, last one: %v", h.consecutiveErrors, err))
+	case h.samples >= minHealthSamples && h.errorRate() > maxErrorRate:
+		h.open(fmt.Sprintf("error rate %.2f, last error: %v", h.errorRate(), err))
+	}
+}
+
+// observeHead records how many masterchain blocks a connection is behind the best known head.
+func (h *health) observeHead(lag uint32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.headLag = lag
+	h.updateState()
+	if lag > maxHeadLag && h.state == BreakerClosed {
+		h.open(fmt.Sprintf("stale head: %v blocks behind", lag))
+	}
+}
+
+// open evicts a connection. must be called with the mutex held.
+func (h *health) open(reason string) {
+	if h.state == BreakerHalfOpen {
+		h.cooldown = min(2*h.cooldown, maxBreakerCooldown)
+	}
+	h.state = BreakerOpen
+	h.reason = reason
+	h.openUntil = h.now().Add(h.cooldown)
+}
+
+// close re-admits a connection. must be called with the mutex held.
+func (h *health) close() {
+	h.state = BreakerClosed
+	h.cooldown = breakerCooldown
+	h.consecutiveErrors = 0
+	h.samples = 0
+	h.next = 0
+}
+
+// updateState moves an open breaker to the half-open state once its cooldown ends.
+// must be called with the mutex held.
+func (h *health) updateState() {
+	if h.state == BreakerOpen && !h.now().Before(h.openUntil) {
+		h.state = BreakerHalfOpen
+	}
+}
+
+// IsAvailable returns true if a connection can be used for requests.
+func (h *health) IsAvailable() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.updateState()
+	return h.state == BreakerClosed
+}
+
+// NeedsProbe returns true if a connection has to be probed to be re-admitted.
+func (h *health) NeedsProbe() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.updateState()
+	return h.state == BreakerHalfOpen
+}
+
+func (h *health) Status() HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.updateState()
+	status := HealthStatus{
+		ErrorRate: h.errorRate(),
+		Breaker:   h.state,
+		Reason:    h.reason,
+		HeadLag:   h.headLag,
+	}
+	if h.state == BreakerOpen {
+		status.OpenUntil = h.openUntil
+		return status
+	}
+	status.Score = (1 - status.ErrorRate) / float64(1+h.headLag)
+	return status
+}
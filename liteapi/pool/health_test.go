@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+func TestHealth_circuitBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	h := newHealth()
+	h.now = func() time.Time { return now }
+	transportErr := errors.New("connection reset")
+
+	for i := 0; i < 20; i++ {
+		h.observe(liteclient.LiteServerErrorC{Code: 651, Message: "block is not applied"})
+	}
+	if !h.IsAvailable() {
+		t.Fatalf("lite server errors must not open the breaker")
+	}
+	for i := 0; i < maxConsecutiveErrors; i++ {
+		h.observe(transportErr)
+	}
+	status := h.Status()
+	if status.Breaker != BreakerOpen || status.Score != 0 || h.IsAvailable() {
+		t.Fatalf("breaker must be open, got: %+v", status)
+	}
+
+	now = now.Add(breakerCooldown)
+	if !h.NeedsProbe() {
+		t.Fatalf("breaker must be half-open after cooldown")
+	}
+	h.observe(transportErr)
+	if status := h.Status(); status.Breaker != BreakerOpen || status.OpenUntil != now.Add(2*breakerCooldown) {
+		t.Fatalf("failed probe must open the breaker with doubled cooldown, got: %+v", status)
+	}
+
+	now = now.Add(2 * breakerCooldown)
+	h.observe(nil)
+	if status := h.Status(); status.Breaker != BreakerClosed || status.Score != 1 {
+		t.Fatalf("successful probe must close the breaker, got: %+v", status)
+	}
+}
+
+func TestHealth_staleHead(t *testing.T) {
+	h := newHealth()
+	h.observeHead(1)
+	if status := h.Status(); status.Breaker != BreakerClosed || status.Score != 0.5 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	h.observeHead(maxHeadLag + 1)
+	if h.IsAvailable() {
+		t.Fatalf("breaker must be open for a stale head")
+	}
+}
+
+func TestHealth_errorRate(t *testing.T) {
+	h := newHealth()
+	for i := 0; i < minHealthSamples; i++ {
+		h.observe(nil)
+		h.observe(nil)
+		if i%3 == 0 {
+			h.observe(errors.New("timeout"))
+		}
+	}
+	if !h.IsAvailable() {
+		t.Fatalf("breaker must be closed with a low error rate")
+	}
+	for i := 0; i < 4*minHealthSamples; i++ {
+		h.observe(nil)
+		h.observe(errors.New("timeout"))
+		h.observe(errors.New("timeout"))
+	}
+	if h.IsAvailable() {
+		t.Fatalf("breaker must be open with a high error rate")
+	}
+}
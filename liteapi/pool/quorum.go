@@ -0,0 +1,124 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+var (
+	// ErrNoQuorum is returned when not enough lite servers agree on a response.
+	ErrNoQuorum = errors.New("no quorum")
+	// ErrQuorumMismatch is reported to liteclient.RequestObserver for a lite server
+	// whose response differs from the one agreed by the quorum.
+	ErrQuorumMismatch = errors.New("response differs from quorum")
+)
+
+// QuorumRequest sends a read request to a lite server at the given masterchain block.
+// Besides a decoded response, it returns a canonical representation of the response
+// which is compared byte-for-byte across lite servers.
+type QuorumRequest[T any] func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (T, []byte, error)
+
+type quorumResponse[T any] struct {
+	conn     conn
+	value    T
+	key      []byte
+	err      error
+	duration time.Duration
+}
+
+// quorumConnections returns QuorumSize working connections with the best ping
+// and the masterchain head known to all of them.
+func (p *ConnPool) quorumConnections() ([]conn, ton.BlockIDExt, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var maxSeqno uint32
+	for _, c := range p.conns {
+		maxSeqno = max(maxSeqno, c.MasterHead().Seqno)
+	}
+	var conns []conn
+	for _, c := range p.conns {
+		if c.IsOK() && c.MasterHead().Seqno > 0 && c.MasterHead().Seqno+1 >= maxSeqno {
+			conns = append(conns, c)
+		}
+	}
+	if len(conns) == 0 || len(conns) < p.quorumThreshold {
+		return nil, ton.BlockIDExt{}, fmt.Errorf("%w: %v working connections, %v required", ErrNoQuorum, len(conns), p.quorumThreshold)
+	}
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].AverageRoundTrip() < conns[j].AverageRoundTrip()
+	})
+	conns = conns[:min(len(conns), max(p.quorumSize, 1))]
+	head := conns[0].MasterHead()
+	for _, c := range conns[1:] {
+		if h := c.MasterHead(); h.Seqno < head.Seqno {
+			head = h
+		}
+	}
+	return conns, head, nil
+}
+
+// Quorum sends the request to QuorumSize lite servers at the same masterchain block
+// and returns a response as soon as QuorumThreshold of them agree byte-for-byte,
+// requests to the rest of lite servers are canceled then.
+// Lite servers that return a different response before the quorum is reached are reported
+// to liteclient.RequestObserver with ErrQuorumMismatch.
+// The request gets the latest masterchain block known to all chosen lite servers.
+func Quorum[T any](ctx context.Context, p *ConnPool, method liteclient.RequestName, request QuorumRequest[T]) (T, error) {
+	var zero T
+	conns, head, err := p.quorumConnections()
+	if err != nil {
+		return zero, err
+	}
+	threshold := max(p.quorumThreshold, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan quorumResponse[T], len(conns))
+	for _, c := range conns {
+		go func(c conn) {
+			start := time.Now()
+			value, key, err := request(ctx, c.Client(), head)
+			results <- quorumResponse[T]{conn: c, value: value, key: key, err: err, duration: time.Since(start)}
+		}(c)
+	}
+
+	var responses []quorumResponse[T]
+	votes := make(map[string]int, len(conns))
+	maxVotes := 0
+	var lastErr error
+	for pending := len(conns); pending > 0; {
+		r := <-results
+		pending--
+		if r.err != nil {
+			lastErr = r.err
+		} else {
+			responses = append(responses, r)
+			votes[string(r.key)]++
+			maxVotes = max(maxVotes, votes[string(r.key)])
+			if votes[string(r.key)] >= threshold {
+				if p.observer != nil {
+					for _, other := range responses {
+						if !bytes.Equal(other.key, r.key) {
+							p.observer.ObserveRequest(other.conn.Host(), method, other.duration, ErrQuorumMismatch)
+						}
+					}
+				}
+				return r.value, nil
+			}
+		}
+		if maxVotes+pending < threshold {
+			break
+		}
+	}
+	if lastErr != nil {
+		return zero, fmt.Errorf("%w: %v of %v lite servers agree, last error: %w", ErrNoQuorum, maxVotes, threshold, lastErr)
+	}
+	return zero, fmt.Errorf("%w: %v of %v lite servers agree", ErrNoQuorum, maxVotes, threshold)
+}
@@ -0,0 +1,160 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+type mockObserver struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (m *mockObserver) ObserveRequest(host string, method liteclient.RequestName, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if errors.Is(err, ErrQuorumMismatch) {
+		m.hosts = append(m.hosts, host)
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		name          string
+		responses     []string
+		wantResponse  string
+		wantErr       bool
+		wantMismatch  []string
+		wantHeadSeqno uint32
+	}{
+		{
+			name:          "all agree",
+			responses:     []string{"a", "a", "a"},
+			wantResponse:  "a",
+			wantHeadSeqno: 99,
+		},
+		{
+			name:          "one disagrees",
+			responses:     []string{"a", "b", "a"},
+			wantResponse:  "a",
+			wantMismatch:  []string{"host-1"},
+			wantHeadSeqno: 99,
+		},
+		{
+			name:      "no quorum",
+			responses: []string{"a", "b", "c"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[*liteclient.Client]string{}
+			var conns []conn
+			for i, r := range tt.responses {
+				c := &mockConn{id: i, seqno: 100 - uint32(i%2), isOK: true, client: &liteclient.Client{}}
+				responses[c.client] = r
+				conns = append(conns, c)
+			}
+			// this connection is too far behind to be used.
+			conns = append(conns, &mockConn{id: 10, seqno: 50, isOK: true, client: &liteclient.Client{}})
+			observer := &mockObserver{}
+			p := &ConnPool{conns: conns, observer: observer, quorumSize: 3, quorumThreshold: 2}
+			var headSeqno uint32
+			var mu sync.Mutex
+			// Quorum doesn't wait for the rest of lite servers once the quorum is reached,
+			// so agreeing lite servers answer after the disagreeing ones.
+			var disagreed sync.WaitGroup
+			for _, r := range responses {
+				if r != tt.wantResponse {
+					disagreed.Add(1)
+				}
+			}
+			res, err := Quorum(context.Background(), p, "test", func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (string, []byte, error) {
+				mu.Lock()
+				headSeqno = masterHead.Seqno
+				mu.Unlock()
+				r, ok := responses[client]
+				if !ok {
+					return "", nil, errors.New("unexpected connection")
+				}
+				if r != tt.wantResponse {
+					disagreed.Done()
+				} else {
+					disagreed.Wait()
+					time.Sleep(50 * time.Millisecond)
+				}
+				return r, []byte(r), nil
+			})
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoQuorum) {
+					t.Fatalf("want ErrNoQuorum, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Quorum() failed: %v", err)
+			}
+			if res != tt.wantResponse {
+				t.Fatalf("want response: %v, got: %v", tt.wantResponse, res)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if headSeqno != tt.wantHeadSeqno {
+				t.Fatalf("want head seqno: %v, got: %v", tt.wantHeadSeqno, headSeqno)
+			}
+			if len(observer.hosts) != len(tt.wantMismatch) || (len(tt.wantMismatch) > 0 && observer.hosts[0] != tt.wantMismatch[0]) {
+				t.Fatalf("want mismatched hosts: %v, got: %v", tt.wantMismatch, observer.hosts)
+			}
+		})
+	}
+}
+
+func TestQuorum_AllFailed(t *testing.T) {
+	var conns []conn
+	for i := 0; i < 3; i++ {
+		conns = append(conns, &mockConn{id: i, seqno: 100, isOK: true, client: &liteclient.Client{}})
+	}
+	// a zero threshold must not make Quorum index a missing response.
+	p := &ConnPool{conns: conns, quorumSize: 3, quorumThreshold: 0}
+	_, err := Quorum(context.Background(), p, "test", func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (string, []byte, error) {
+		return "", nil, errors.New("lite server failed")
+	})
+	if !errors.Is(err, ErrNoQuorum) {
+		t.Fatalf("want ErrNoQuorum, got: %v", err)
+	}
+	if p := New(QuorumStrategy); p.quorumThreshold != 1 || p.quorumSize != 1 {
+		t.Fatalf("want quorum 1 of 1, got %v of %v", p.quorumThreshold, p.quorumSize)
+	}
+}
+
+func TestQuorum_DoesNotWaitForSlowServers(t *testing.T) {
+	var conns []conn
+	for i := 0; i < 3; i++ {
+		conns = append(conns, &mockConn{id: i, seqno: 100, isOK: true, client: &liteclient.Client{}})
+	}
+	slow := conns[2].Client()
+	p := &ConnPool{conns: conns, quorumSize: 3, quorumThreshold: 2}
+	canceled := make(chan struct{})
+	res, err := Quorum(context.Background(), p, "test", func(ctx context.Context, client *liteclient.Client, masterHead ton.BlockIDExt) (string, []byte, error) {
+		if client == slow {
+			<-ctx.Done()
+			close(canceled)
+			return "", nil, ctx.Err()
+		}
+		return "a", []byte("a"), nil
+	})
+	if err != nil || res != "a" {
+		t.Fatalf("want response a, got: %v, %v", res, err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("request to the slow lite server must be canceled")
+	}
+}
@@ -0,0 +1,136 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket that throttles requests sent to a lite server.
+// A lite proxy reports its quota with liteProxy.getRequestRateLimit,
+// regular lite servers don't support this method and are not limited.
+type rateLimiter struct {
+	mu sync.Mutex
+	// limit is a number of requests allowed per period.
+	// zero means that requests are not limited.
+	limit  int
+	period time.Duration
+	// tokens can be negative when requests are waiting for their turn.
+	tokens    float64
+	updatedAt time.Time
+	requests  uint64
+	now       func() time.Time
+}
+
+// RateLimitStatus describes a quota of a lite server and its current usage.
+type RateLimitStatus struct {
+	// Limit is a number of requests allowed per Period.
+	// Zero means that a lite server doesn't limit requests.
+	Limit  int
+	Period time.Duration
+	// Remaining is a number of requests that can be sent right now without waiting.
+	// It is always zero if requests are not limited.
+	Remaining int
+	// Requests is a total number of requests sent to a lite server.
+	Requests uint64
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{now: time.Now}
+}
+
+// SetLimit configures the bucket to allow limit requests per period and fills it up.
+func (l *rateLimiter) SetLimit(limit int, period time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit <= 0 || period <= 0 {
+		l.limit, l.period = 0, 0
+		return
+	}
+	l.limit = limit
+	l.period = period
+	l.tokens = float64(limit)
+	l.updatedAt = l.now()
+}
+
+// refill adds tokens accumulated since the last update.
+// must be called with the mutex held.
+func (l *rateLimiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.updatedAt)
+	l.updatedAt = now
+	l.tokens += float64(l.limit) * elapsed.Seconds() / l.period.Seconds()
+	if l.tokens > float64(l.limit) {
+		l.tokens = float64(l.limit)
+	}
+}
+
+// reserve takes a token and returns how long a caller has to wait before sending a request.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests++
+	if l.limit == 0 {
+		return 0
+	}
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.period) / float64(l.limit))
+}
+
+// cancel returns a token taken by reserve.
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests--
+	if l.limit == 0 {
+		return
+	}
+	l.tokens++
+}
+
+// Wait implements liteclient.RateLimiter.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// HasBudget returns true if a request can be sent right now without waiting.
+func (l *rateLimiter) HasBudget() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == 0 {
+		return true
+	}
+	l.refill()
+	return l.tokens >= 1
+}
+
+func (l *rateLimiter) Status() RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == 0 {
+		return RateLimitStatus{Requests: l.requests}
+	}
+	l.refill()
+	return RateLimitStatus{
+		Limit:     l.limit,
+		Period:    l.period,
+		Remaining: max(int(l.tokens), 0),
+		Requests:  l.requests,
+	}
+}
@@ -0,0 +1,47 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	if delay := l.reserve(); delay != 0 {
+		t.Fatalf("unlimited limiter must not delay requests, got: %v", delay)
+	}
+	l.SetLimit(2, time.Second)
+	for i := 0; i < 2; i++ {
+		if delay := l.reserve(); delay != 0 {
+			t.Fatalf("request %d must not be delayed, got: %v", i, delay)
+		}
+	}
+	if l.HasBudget() {
+		t.Fatalf("budget must be exhausted")
+	}
+	if delay := l.reserve(); delay != 500*time.Millisecond {
+		t.Fatalf("want delay 500ms, got: %v", delay)
+	}
+	l.cancel()
+	now = now.Add(time.Second)
+	status := l.Status()
+	want := RateLimitStatus{Limit: 2, Period: time.Second, Remaining: 2, Requests: 3}
+	if status != want {
+		t.Fatalf("want status: %+v, got: %+v", want, status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.reserve()
+	l.reserve()
+	if err := l.Wait(ctx); err == nil {
+		t.Fatalf("Wait() must fail when context is done")
+	}
+	if status := l.Status(); status.Requests != 5 {
+		t.Fatalf("cancelled request must not be counted, got: %v", status.Requests)
+	}
+}
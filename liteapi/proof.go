@@ -0,0 +1,1101 @@
+package liteapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// maxProvenBlocks limits a number of proven masterchain blocks kept in memory.
+	maxProvenBlocks = 10_000
+	// maxBlockProofSteps limits a number of partial proofs requested to prove a single block.
+	maxBlockProofSteps = 100
+
+	// pub.ed25519#4813b4c6 key:int256 = PublicKey;
+	pubKeyEd25519Tag uint32 = 0x4813b4c6
+	// ton.blockId root_cell_hash:int256 file_hash:int256 = ton.BlockId;
+	tonBlockIdTag uint32 = 0xc50b6e70
+)
+
+// ProofError is returned when a response of a lite server doesn't pass proof checks.
+type ProofError struct {
+	Method string
+	Err    error
+}
+
+func (e ProofError) Error() string {
+	return fmt.Sprintf("%v: proof check failed: %v", e.Method, e.Err)
+}
+
+func (e ProofError) Unwrap() error {
+	return e.Err
+}
+
+// IsProofError returns true if the given error is caused by an invalid proof.
+func IsProofError(err error) bool {
+	var e ProofError
+	return errors.As(err, &e)
+}
+
+func proofError(method string, err error) error {
+	return ProofError{Method: method, Err: err}
+}
+
+// blockProver keeps track of masterchain blocks proven starting from a trusted key block.
+type blockProver struct {
+	mu sync.Mutex
+	// keyBlock is the latest proven key block.
+	keyBlock ton.BlockIDExt
+	proven   map[ton.BlockIDExt]struct{}
+}
+
+func newBlockProver(trusted ton.BlockIDExt) *blockProver {
+	return &blockProver{
+		keyBlock: trusted,
+		proven:   map[ton.BlockIDExt]struct{}{trusted: {}},
+	}
+}
+
+func (p *blockProver) isProven(blockID ton.BlockIDExt) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.proven[blockID]
+	return ok
+}
+
+func (p *blockProver) trustedKeyBlock() ton.BlockIDExt {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keyBlock
+}
+
+func (p *blockProver) addProven(blockID ton.BlockIDExt, isKeyBlock bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if isKeyBlock && blockID.Seqno > p.keyBlock.Seqno {
+		p.keyBlock = blockID
+	}
+	if len(p.proven) >= maxProvenBlocks {
+		for id := range p.proven {
+			if id != p.keyBlock {
+				delete(p.proven, id)
+			}
+		}
+	}
+	p.proven[blockID] = struct{}{}
+}
+
+// provenBlock contains parts of a block that are present in block proofs.
+type provenBlock struct {
+	Magic    tlb.Magic `tlb:"block#11ef55aa"`
+	GlobalId int32
+	Info     struct {
+		Magic tlb.Magic `tlb:"block_info#9bc7a987"`
+		tlb.BlockInfoPart
+	} `tlb:"^"`
+	ValueFlow   boc.Cell `tlb:"^"`
+	StateUpdate struct {
+		Magic    tlb.Magic `tlb:"!merkle_update#04"`
+		FromHash tlb.Bits256
+		ToHash   tlb.Bits256
+	} `tlb:"^"`
+	Extra tlb.BlockExtra `tlb:"^"`
+}
+
+func shardFromIdent(si tlb.ShardIdent) (int32, uint64) {
+	return si.WorkchainID, si.ShardPrefix | uint64(1)<<(63-si.ShardPfxBits)
+}
+
+// verifyBlockProof checks that the given merkle proof is built for the given block and
+// returns the decoded part of the block.
+func verifyBlockProof(proof *boc.Cell, blockID ton.BlockIDExt) (*provenBlock, error) {
+	root, err := boc.VerifyMerkleProof(proof, blockID.RootHash)
+	if err != nil {
+		return nil, err
+	}
+	var block provenBlock
+	if err := tlb.Unmarshal(root, &block); err != nil {
+		return nil, fmt.Errorf("failed to decode block proof: %w", err)
+	}
+	workchain, shard := shardFromIdent(block.Info.Shard)
+	if block.Info.SeqNo != blockID.Seqno || workchain != blockID.Workchain || shard != blockID.Shard {
+		return nil, fmt.Errorf("block proof is built for another block")
+	}
+	return &block, nil
+}
+
+// provenRef keeps a hash of a referenced cell that can be pruned in a merkle proof.
+type provenRef struct {
+	Hash tlb.Bits256
+}
+
+func (r *provenRef) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error {
+	ref, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	r.Hash, err = ref.VirtualHash()
+	return err
+}
+
+// provenShardAccount is a ShardAccount with the account itself replaced by its hash
+// because the account is usually pruned in state proofs.
+type provenShardAccount struct {
+	Account       provenRef
+	LastTransHash tlb.Bits256
+	LastTransLt   uint64
+}
+
+// provenShardState contains parts of a shard state required to check lite server responses.
+// Referenced cells are kept as is, so it is possible to tell a pruned part of the state from a missing one.
+type provenShardState struct {
+	Magic           tlb.Magic `tlb:"shard_state#9023afe2"`
+	GlobalID        int32
+	ShardID         tlb.ShardIdent
+	SeqNo           uint32
+	VertSeqNo       uint32
+	GenUtime        uint32
+	GenLt           uint64
+	MinRefMcSeqno   uint32
+	OutMsgQueueInfo provenCell
+	BeforeSplit     bool
+	Accounts        provenCell
+	Other           provenCell
+	Custom          tlb.Maybe[provenCell]
+}
+
+// provenMcStateExtra contains the beginning of McStateExtra of a masterchain state.
+type provenMcStateExtra struct {
+	Magic       tlb.Magic `tlb:"masterchain_state_extra#cc26"`
+	ShardHashes tlb.Maybe[provenCell]
+}
+
+// provenCell keeps a referenced cell of a merkle proof even if it is pruned.
+type provenCell struct {
+	Cell *boc.Cell
+}
+
+func (r *provenCell) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error {
+	ref, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	r.Cell = ref
+	return nil
+}
+
+// unmarshalProvenCell decodes a referenced cell of a merkle proof which must not be pruned.
+func unmarshalProvenCell(ref provenCell, v any) error {
+	if ref.Cell == nil {
+		return fmt.Errorf("referenced cell is missing")
+	}
+	if ref.Cell.CellType() == boc.PrunedBranchCell {
+		return tlb.ErrPrunedBranch
+	}
+	ref.Cell.ResetCounters()
+	return tlb.Unmarshal(ref.Cell, v)
+}
+
+// keyRange is a range of hashmap keys, both ends are included.
+type keyRange struct {
+	Min, Max []byte
+}
+
+// prunedKeyRange returns a range of keys which can be hidden in a pruned branch with the given key prefix.
+func prunedKeyRange(prefix boc.BitString, keySize int) (keyRange, error) {
+	lo, hi := prefix.Copy(), prefix.Copy()
+	for i := prefix.GetWriteCursor(); i < keySize; i++ {
+		if err := lo.WriteBit(false); err != nil {
+			return keyRange{}, err
+		}
+		if err := hi.WriteBit(true); err != nil {
+			return keyRange{}, err
+		}
+	}
+	return keyRange{Min: lo.Buffer(), Max: hi.Buffer()}, nil
+}
+
+// checkPrunedRanges checks that no pruned branch can hide a key from (after, upTo].
+// A nil bound means the range is not limited from that side.
+func checkPrunedRanges(pruned []keyRange, after, upTo []byte) error {
+	for _, r := range pruned {
+		if after != nil && bytes.Compare(r.Max, after) <= 0 {
+			continue
+		}
+		if upTo != nil && bytes.Compare(r.Min, upTo) > 0 {
+			continue
+		}
+		return fmt.Errorf("keys from %x to %x are hidden in a pruned branch: %w", r.Min, r.Max, tlb.ErrPrunedBranch)
+	}
+	return nil
+}
+
+// walkProvenHashmapE walks a HashmapE or HashmapAugE of a merkle proof
+// collecting ranges of keys hidden in pruned branches.
+func walkProvenHashmapE(root tlb.Maybe[provenCell], keySize int, leaf func(key boc.BitString, c *boc.Cell) error) ([]keyRange, error) {
+	if !root.Exists {
+		return nil, nil
+	}
+	var pruned []keyRange
+	root.Value.Cell.ResetCounters()
+	err := tlb.WalkProvenHashmap(root.Value.Cell, keySize, leaf, func(prefix boc.BitString) error {
+		r, err := prunedKeyRange(prefix, keySize)
+		pruned = append(pruned, r)
+		return err
+	})
+	return pruned, err
+}
+
+// findProvenAccount looks up the account in proven ShardAccounts.
+// It returns nil if the proof shows that the account is absent
+// and an error if the path to the account is pruned.
+func findProvenAccount(accounts *boc.Cell, address tlb.Bits256) (*provenShardAccount, error) {
+	if accounts.CellType() == boc.PrunedBranchCell {
+		return nil, tlb.ErrPrunedBranch
+	}
+	accounts.ResetCounters()
+	// ahme_empty$0 or ahme_root$1 root:^(HashmapAug 256 ShardAccount DepthBalanceInfo)
+	notEmpty, err := accounts.ReadBit()
+	if err != nil {
+		return nil, err
+	}
+	if !notEmpty {
+		return nil, nil
+	}
+	root, err := accounts.NextRef()
+	if err != nil {
+		return nil, err
+	}
+	root.ResetCounters()
+	key := boc.NewBitString(256)
+	if err := key.WriteBytes(address[:]); err != nil {
+		return nil, err
+	}
+	leaf, found, err := tlb.FindKeyInProvenHashmap(root, key)
+	if err != nil || !found {
+		return nil, err
+	}
+	var item struct {
+		Extra   tlb.DepthBalanceInfo
+		Account provenShardAccount
+	}
+	if err := tlb.Unmarshal(leaf, &item); err != nil {
+		return nil, err
+	}
+	return &item.Account, nil
+}
+
+// verifyStateProof checks that the given merkle proof is built for a shard state with the given hash
+// and decodes the state into the value pointed to by state.
+func verifyStateProof(proof *boc.Cell, stateHash tlb.Bits256, state any) error {
+	root, err := boc.VerifyMerkleProof(proof, stateHash)
+	if err != nil {
+		return err
+	}
+	if err := tlb.Unmarshal(root, state); err != nil {
+		return fmt.Errorf("failed to decode state proof: %w", err)
+	}
+	return nil
+}
+
+// verifyBlockStateProof checks a typical lite server proof consisting of
+// a block proof followed by a proof of a state of this block.
+func verifyBlockStateProof(proofBoc []byte, blockID ton.BlockIDExt, state any) error {
+	cells, err := boc.DeserializeBoc(proofBoc)
+	if err != nil {
+		return err
+	}
+	if len(cells) != 2 {
+		return fmt.Errorf("must be two root cells")
+	}
+	block, err := verifyBlockProof(cells[0], blockID)
+	if err != nil {
+		return err
+	}
+	return verifyStateProof(cells[1], block.StateUpdate.ToHash, state)
+}
+
+func deserializeSingleRoot(data []byte) (*boc.Cell, error) {
+	cells, err := boc.DeserializeBoc(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(cells) != 1 {
+		return nil, boc.ErrNotSingleRoot
+	}
+	return cells[0], nil
+}
+
+// verifyBlockLink checks a single step of a block proof chain and returns the block it leads to.
+func verifyBlockLink(link liteclient.LiteServerBlockLink) (ton.BlockIDExt, bool, error) {
+	switch link.SumType {
+	case "LiteServerBlockLinkForward":
+		l := link.LiteServerBlockLinkForward
+		from, to := l.From.ToBlockIdExt(), l.To.ToBlockIdExt()
+		if err := verifyForwardLink(from, to, l.ToKeyBlock, l.DestProof, l.ConfigProof, l.Signatures); err != nil {
+			return ton.BlockIDExt{}, false, err
+		}
+		return to, l.ToKeyBlock, nil
+	case "LiteServerBlockLinkBack":
+		l := link.LiteServerBlockLinkBack
+		from, to := l.From.ToBlockIdExt(), l.To.ToBlockIdExt()
+		if err := verifyBackwardLink(from, to, l.ToKeyBlock, l.DestProof, l.Proof, l.StateProof); err != nil {
+			return ton.BlockIDExt{}, false, err
+		}
+		return to, l.ToKeyBlock, nil
+	}
+	return ton.BlockIDExt{}, false, fmt.Errorf("unknown block link type %v", link.SumType)
+}
+
+func verifyDestProof(destProof []byte, to ton.BlockIDExt, toKey bool) error {
+	cell, err := deserializeSingleRoot(destProof)
+	if err != nil {
+		return err
+	}
+	block, err := verifyBlockProof(cell, to)
+	if err != nil {
+		return err
+	}
+	if block.Info.KeyBlock != toKey {
+		return fmt.Errorf("key block flag mismatch")
+	}
+	return nil
+}
+
+// verifyForwardLink checks that the "to" block is signed by the validators of the "from" key block.
+func verifyForwardLink(from, to ton.BlockIDExt, toKey bool, destProof, configProof []byte, signatures liteclient.LiteServerSignatureSet) error {
+	if from.Workchain != -1 || to.Workchain != -1 {
+		return fmt.Errorf("forward link must connect masterchain blocks")
+	}
+	if to.Seqno <= from.Seqno {
+		return fmt.Errorf("forward link must lead to a newer block")
+	}
+	if err := verifyDestProof(destProof, to, toKey); err != nil {
+		return err
+	}
+	cell, err := deserializeSingleRoot(configProof)
+	if err != nil {
+		return err
+	}
+	block, err := verifyBlockProof(cell, from)
+	if err != nil {
+		return err
+	}
+	if !block.Info.KeyBlock {
+		return fmt.Errorf("forward link must start from a key block")
+	}
+	if !block.Extra.Custom.Exists {
+		return fmt.Errorf("config proof doesn't contain masterchain block extra")
+	}
+	validators, err := mainValidators(block.Extra.Custom.Value.Value.Config)
+	if err != nil {
+		return err
+	}
+	return checkBlockSignatures(to, signatures, validators)
+}
+
+// verifyBackwardLink checks that the "to" block is present in the list of previous blocks of the "from" block's state.
+func verifyBackwardLink(from, to ton.BlockIDExt, toKey bool, destProof, proof, stateProof []byte) error {
+	if from.Workchain != -1 || to.Workchain != -1 {
+		return fmt.Errorf("backward link must connect masterchain blocks")
+	}
+	if to.Seqno >= from.Seqno {
+		return fmt.Errorf("backward link must lead to an older block")
+	}
+	if err := verifyDestProof(destProof, to, toKey); err != nil {
+		return err
+	}
+	cell, err := deserializeSingleRoot(proof)
+	if err != nil {
+		return err
+	}
+	block, err := verifyBlockProof(cell, from)
+	if err != nil {
+		return err
+	}
+	cell, err = deserializeSingleRoot(stateProof)
+	if err != nil {
+		return err
+	}
+	var state tlb.ShardStateUnsplit
+	if err := verifyStateProof(cell, block.StateUpdate.ToHash, &state); err != nil {
+		return err
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return fmt.Errorf("state proof doesn't contain masterchain state extra")
+	}
+	prevBlocks := state.ShardStateUnsplit.Custom.Value.Value.Other.PrevBlocks
+	for seqno, ref := range prevBlocks.Items() {
+		if uint32(seqno) != to.Seqno {
+			continue
+		}
+		if ref.BlkRef.RootHash != tlb.Bits256(to.RootHash) || ref.BlkRef.FileHash != tlb.Bits256(to.FileHash) {
+			return fmt.Errorf("previous block hash mismatch")
+		}
+		if toKey && !ref.Key {
+			return fmt.Errorf("key block flag mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("block %v not found in previous blocks", to.Seqno)
+}
+
+// mainValidators returns validators of the current set that sign masterchain blocks.
+func mainValidators(params tlb.ConfigParams) ([]tlb.ValidatorDescr, error) {
+	for _, item := range params.Config.Items() {
+		if item.Key != 34 {
+			continue
+		}
+		cell := item.Value.Value
+		cell.ResetCounters()
+		var param tlb.ConfigParam34
+		if err := tlb.Unmarshal(&cell, &param); err != nil {
+			return nil, fmt.Errorf("failed to decode config param 34: %w", err)
+		}
+		var (
+			main  uint16
+			items []tlb.HashmapItem[tlb.Uint16, tlb.ValidatorDescr]
+		)
+		switch set := param.CurValidators; set.SumType {
+		case "Validators":
+			main, items = set.Validators.Main, set.Validators.List.Items()
+		case "ValidatorsExt":
+			main, items = set.ValidatorsExt.Main, set.ValidatorsExt.List.Items()
+		default:
+			return nil, fmt.Errorf("unknown validator set type %v", set.SumType)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+		var validators []tlb.ValidatorDescr
+		for _, it := range items {
+			if uint16(it.Key) < main {
+				validators = append(validators, it.Value)
+			}
+		}
+		return validators, nil
+	}
+	return nil, fmt.Errorf("config param 34 not found in config proof")
+}
+
+func validatorNodeIdShort(pubKey tlb.Bits256) [32]byte {
+	buf := make([]byte, 36)
+	binary.LittleEndian.PutUint32(buf, pubKeyEd25519Tag)
+	copy(buf[4:], pubKey[:])
+	return sha256.Sum256(buf)
+}
+
+func blockSignatureMessage(blockID ton.BlockIDExt) []byte {
+	msg := make([]byte, 68)
+	binary.LittleEndian.PutUint32(msg, tonBlockIdTag)
+	copy(msg[4:36], blockID.RootHash[:])
+	copy(msg[36:], blockID.FileHash[:])
+	return msg
+}
+
+// checkBlockSignatures checks that the block is signed by validators with more than 2/3 of the total weight.
+func checkBlockSignatures(blockID ton.BlockIDExt, set liteclient.LiteServerSignatureSet, validators []tlb.ValidatorDescr) error {
+	type validator struct {
+		pubKey ed25519.PublicKey
+		weight uint64
+	}
+	byNodeID := make(map[[32]byte]validator, len(validators))
+	var totalWeight uint64
+	for _, v := range validators {
+		pubKey := v.PubKey()
+		byNodeID[validatorNodeIdShort(pubKey)] = validator{pubKey: pubKey[:], weight: v.Weight()}
+		totalWeight += v.Weight()
+	}
+	msg := blockSignatureMessage(blockID)
+	signed := make(map[[32]byte]struct{}, len(set.Signatures))
+	var signedWeight uint64
+	for _, sig := range set.Signatures {
+		nodeID := [32]byte(sig.NodeIdShort)
+		if _, ok := signed[nodeID]; ok {
+			continue
+		}
+		v, ok := byNodeID[nodeID]
+		if !ok {
+			return fmt.Errorf("signature of unknown validator %x", nodeID)
+		}
+		if !ed25519.Verify(v.pubKey, msg, sig.Signature) {
+			return fmt.Errorf("invalid signature of validator %x", nodeID)
+		}
+		signed[nodeID] = struct{}{}
+		signedWeight += v.weight
+	}
+	if signedWeight*3 <= totalWeight*2 {
+		return fmt.Errorf("not enough signatures: signed weight %v of %v", signedWeight, totalWeight)
+	}
+	return nil
+}
+
+// VerifyMasterchainBlock builds a chain of proofs from the trusted key block to the given masterchain block
+// and checks it. The client must be configured with ProofPolicySecure.
+func (c *Client) VerifyMasterchainBlock(ctx context.Context, blockID ton.BlockIDExt) error {
+	if c.prover == nil {
+		return fmt.Errorf("block verification requires ProofPolicySecure")
+	}
+	if blockID.Workchain != -1 {
+		return fmt.Errorf("only masterchain blocks can be verified")
+	}
+	if c.prover.isProven(blockID) {
+		return nil
+	}
+	known := c.prover.trustedKeyBlock()
+	for i := 0; i < maxBlockProofSteps; i++ {
+		res, err := c.GetBlockProofRaw(ctx, known, &blockID)
+		if err != nil {
+			return err
+		}
+		if res.From.ToBlockIdExt() != known {
+			return proofError("VerifyMasterchainBlock", fmt.Errorf("proof starts from unexpected block"))
+		}
+		current := known
+		for _, step := range res.Steps {
+			from := step.LiteServerBlockLinkForward.From
+			if step.SumType == "LiteServerBlockLinkBack" {
+				from = step.LiteServerBlockLinkBack.From
+			}
+			if from.ToBlockIdExt() != current {
+				return proofError("VerifyMasterchainBlock", fmt.Errorf("broken proof chain"))
+			}
+			to, toKey, err := verifyBlockLink(step)
+			if err != nil {
+				return proofError("VerifyMasterchainBlock", err)
+			}
+			c.prover.addProven(to, toKey)
+			current = to
+			if toKey {
+				known = to
+			}
+		}
+		if current != res.To.ToBlockIdExt() {
+			return proofError("VerifyMasterchainBlock", fmt.Errorf("proof ends at unexpected block"))
+		}
+		if current == blockID {
+			return nil
+		}
+		if res.Complete {
+			return proofError("VerifyMasterchainBlock", fmt.Errorf("complete proof doesn't lead to the requested block"))
+		}
+		if len(res.Steps) == 0 {
+			return proofError("VerifyMasterchainBlock", fmt.Errorf("partial proof without steps"))
+		}
+		known = current
+	}
+	return proofError("VerifyMasterchainBlock", fmt.Errorf("too many proof steps"))
+}
+
+// verifyShardInfo checks that the shard block returned by a lite server is present
+// in the proven state of the requested masterchain block.
+func verifyShardInfo(res liteclient.LiteServerShardInfoC, blockID ton.BlockIDExt, workchain int32) error {
+	if res.Id.ToBlockIdExt() != blockID {
+		return fmt.Errorf("shard info is returned for another block")
+	}
+	return verifyShardBlock(res.ShardProof, blockID, res.Shardblk.ToBlockIdExt(), workchain)
+}
+
+// verifyShardBlock checks that the shard block is present in the proven state of the masterchain block.
+func verifyShardBlock(shardProof []byte, blockID, shardBlock ton.BlockIDExt, workchain int32) error {
+	var state tlb.ShardStateUnsplit
+	if err := verifyBlockStateProof(shardProof, blockID, &state); err != nil {
+		return err
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return fmt.Errorf("state proof doesn't contain masterchain state extra")
+	}
+	shardHashes := state.ShardStateUnsplit.Custom.Value.Value.ShardHashes
+	for _, item := range shardHashes.Items() {
+		if int32(item.Key) != workchain {
+			continue
+		}
+		for _, desc := range item.Value.Value.BinTree.Values {
+			if ton.ToBlockId(desc, workchain) == shardBlock {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("shard block %v not found in masterchain state", shardBlock)
+}
+
+// verifyAllShardsInfo checks that the shard hashes returned by a lite server
+// are the same as in the proven state of the requested masterchain block.
+func verifyAllShardsInfo(res liteclient.LiteServerAllShardsInfoC, data *boc.Cell, blockID ton.BlockIDExt) error {
+	if res.Id.ToBlockIdExt() != blockID {
+		return fmt.Errorf("shards info is returned for another block")
+	}
+	var state provenShardState
+	if err := verifyBlockStateProof(res.Proof, blockID, &state); err != nil {
+		return err
+	}
+	if !state.Custom.Exists {
+		return fmt.Errorf("state proof doesn't contain masterchain state extra")
+	}
+	var extra provenMcStateExtra
+	if err := unmarshalProvenCell(state.Custom.Value, &extra); err != nil {
+		return fmt.Errorf("failed to decode masterchain state extra: %w", err)
+	}
+	var shards struct {
+		ShardHashes tlb.Maybe[provenCell]
+	}
+	data.ResetCounters()
+	if err := tlb.Unmarshal(data, &shards); err != nil {
+		return err
+	}
+	if shards.ShardHashes.Exists != extra.ShardHashes.Exists {
+		return fmt.Errorf("shard hashes mismatch")
+	}
+	if !extra.ShardHashes.Exists {
+		return nil
+	}
+	expected, err := extra.ShardHashes.Value.Cell.VirtualHash()
+	if err != nil {
+		return err
+	}
+	hash, err := shards.ShardHashes.Value.Cell.Hash256()
+	if err != nil {
+		return err
+	}
+	if hash != expected {
+		return fmt.Errorf("shard hashes mismatch")
+	}
+	return nil
+}
+
+// verifyAccountState checks that the account state returned by a lite server belongs to
+// the requested masterchain block and returns the last transaction of the account.
+func verifyAccountState(res liteclient.LiteServerAccountStateC, blockID ton.BlockIDExt, accountID ton.AccountID) (uint64, tlb.Bits256, error) {
+	if res.Id.ToBlockIdExt() != blockID {
+		return 0, tlb.Bits256{}, fmt.Errorf("state is returned for another block")
+	}
+	shardBlock := res.Shardblk.ToBlockIdExt()
+	if shardBlock.Workchain != accountID.Workchain {
+		return 0, tlb.Bits256{}, fmt.Errorf("shard block belongs to another workchain")
+	}
+	shard, err := ton.ParseShardID(int64(shardBlock.Shard))
+	if err != nil {
+		return 0, tlb.Bits256{}, err
+	}
+	if !shard.MatchAccountID(accountID) {
+		return 0, tlb.Bits256{}, fmt.Errorf("account doesn't belong to shard %v", shardBlock.BlockID)
+	}
+	if accountID.Workchain == -1 {
+		if shardBlock != blockID {
+			return 0, tlb.Bits256{}, fmt.Errorf("masterchain account state is returned for another block")
+		}
+	} else if err := verifyShardBlock(res.ShardProof, blockID, shardBlock, accountID.Workchain); err != nil {
+		return 0, tlb.Bits256{}, err
+	}
+	var state provenShardState
+	if err := verifyBlockStateProof(res.Proof, shardBlock, &state); err != nil {
+		return 0, tlb.Bits256{}, err
+	}
+	acc, err := findProvenAccount(state.Accounts.Cell, tlb.Bits256(accountID.Address))
+	if err != nil {
+		return 0, tlb.Bits256{}, fmt.Errorf("failed to find account in ShardAccounts: %w", err)
+	}
+	if acc == nil {
+		if len(res.State) != 0 {
+			return 0, tlb.Bits256{}, fmt.Errorf("account not found in ShardAccounts")
+		}
+		return 0, tlb.Bits256{}, nil
+	}
+	if len(res.State) == 0 {
+		return 0, tlb.Bits256{}, fmt.Errorf("account state is missing")
+	}
+	root, err := deserializeSingleRoot(res.State)
+	if err != nil {
+		return 0, tlb.Bits256{}, err
+	}
+	hash, err := root.Hash256()
+	if err != nil {
+		return 0, tlb.Bits256{}, err
+	}
+	if hash != acc.Account.Hash {
+		return 0, tlb.Bits256{}, fmt.Errorf("account state hash mismatch")
+	}
+	return acc.LastTransLt, acc.LastTransHash, nil
+}
+
+// provenBlockTransactions contains parts of a block required to check its transactions.
+type provenBlockTransactions struct {
+	Magic       tlb.Magic `tlb:"block#11ef55aa"`
+	GlobalId    int32
+	Info        boc.Cell `tlb:"^"`
+	ValueFlow   boc.Cell `tlb:"^"`
+	StateUpdate boc.Cell `tlb:"^"`
+	Extra       struct {
+		Magic         tlb.Magic `tlb:"block_extra#4a33f6fd"`
+		InMsgDescr    boc.Cell  `tlb:"^"`
+		OutMsgDescr   boc.Cell  `tlb:"^"`
+		AccountBlocks provenCell
+	} `tlb:"^"`
+}
+
+// blockTxKey returns a key which orders transactions of a block by account and lt.
+func blockTxKey(account tlb.Bits256, lt uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, account[:]...), lt)
+}
+
+// verifyBlockTransactions checks that the transactions are present in the proven block
+// and that no transaction between after and the last returned one is skipped.
+// If the list is not incomplete, no transaction must follow the last returned one.
+func verifyBlockTransactions(proofBoc []byte, blockID ton.BlockIDExt, cells []*boc.Cell, txs []ton.Transaction, after *liteclient.LiteServerTransactionId3C, incomplete bool) error {
+	proof, err := deserializeSingleRoot(proofBoc)
+	if err != nil {
+		return err
+	}
+	root, err := boc.VerifyMerkleProof(proof, blockID.RootHash)
+	if err != nil {
+		return err
+	}
+	var block provenBlockTransactions
+	if err := tlb.Unmarshal(root, &block); err != nil {
+		return fmt.Errorf("failed to decode block proof: %w", err)
+	}
+	var accountBlocks struct {
+		Root tlb.Maybe[provenCell]
+	}
+	if err := unmarshalProvenCell(block.Extra.AccountBlocks, &accountBlocks); err != nil {
+		return fmt.Errorf("failed to decode account blocks: %w", err)
+	}
+	hashes := make(map[string]tlb.Bits256)
+	var prunedTxs []keyRange
+	prunedAccounts, err := walkProvenHashmapE(accountBlocks.Root, 256, func(key boc.BitString, c *boc.Cell) error {
+		var accountBlock struct {
+			Extra       tlb.CurrencyCollection
+			Magic       tlb.Magic `tlb:"acc_trans#5"`
+			AccountAddr tlb.Bits256
+		}
+		if err := tlb.Unmarshal(c, &accountBlock); err != nil {
+			return err
+		}
+		addr := accountBlock.AccountAddr
+		return tlb.WalkProvenHashmap(c, 64, func(key boc.BitString, c *boc.Cell) error {
+			var tx struct {
+				Extra tlb.CurrencyCollection
+				Tx    provenRef
+			}
+			if err := tlb.Unmarshal(c, &tx); err != nil {
+				return err
+			}
+			hashes[string(blockTxKey(addr, binary.BigEndian.Uint64(key.Buffer())))] = tx.Tx.Hash
+			return nil
+		}, func(prefix boc.BitString) error {
+			r, err := prunedKeyRange(prefix, 64)
+			prunedTxs = append(prunedTxs, keyRange{Min: append(addr[:], r.Min...), Max: append(addr[:], r.Max...)})
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to decode account blocks: %w", err)
+	}
+	pruned := prunedTxs
+	for _, r := range prunedAccounts {
+		pruned = append(pruned, keyRange{
+			Min: binary.BigEndian.AppendUint64(r.Min, 0),
+			Max: binary.BigEndian.AppendUint64(r.Max, math.MaxUint64),
+		})
+	}
+	var lower, upTo []byte
+	if after != nil {
+		lower = blockTxKey(tlb.Bits256(after.Account), after.Lt)
+	}
+	prev := lower
+	returned := make(map[string]struct{}, len(txs))
+	for i, tx := range txs {
+		key := blockTxKey(tx.AccountAddr, tx.Lt)
+		if prev != nil && bytes.Compare(key, prev) <= 0 {
+			return fmt.Errorf("transactions are not sorted")
+		}
+		prev = key
+		returned[string(key)] = struct{}{}
+		expected, ok := hashes[string(key)]
+		if !ok {
+			return fmt.Errorf("transaction %v:%v not found in block", tx.AccountAddr.Hex(), tx.Lt)
+		}
+		hash, err := cells[i].Hash256()
+		if err != nil {
+			return err
+		}
+		if hash != expected {
+			return fmt.Errorf("transaction %v:%v hash mismatch", tx.AccountAddr.Hex(), tx.Lt)
+		}
+	}
+	if incomplete {
+		if len(txs) == 0 {
+			return nil
+		}
+		upTo = prev
+	}
+	for key := range hashes {
+		if _, ok := returned[key]; ok {
+			continue
+		}
+		if lower != nil && bytes.Compare([]byte(key), lower) <= 0 {
+			continue
+		}
+		if upTo != nil && bytes.Compare([]byte(key), upTo) > 0 {
+			continue
+		}
+		return fmt.Errorf("transaction %x is missing", key)
+	}
+	return checkPrunedRanges(pruned, lower, upTo)
+}
+
+// verifyLookupBlockResult checks that the header matches the found block id and the lookup request.
+// It doesn't check res.ShardLinks and res.PrevHeader, so the found block is not proven
+// to be referenced by mcBlockID unless it is mcBlockID itself.
+func verifyLookupBlockResult(res liteclient.LiteServerLookupBlockResultC, blockID ton.BlockID, mcBlockID ton.BlockIDExt, mode uint32, info tlb.BlockInfo, lt *uint64) error {
+	if res.McBlockId.ToBlockIdExt() != mcBlockID {
+		return fmt.Errorf("lookup result is returned for another masterchain block")
+	}
+	id := res.Id.ToBlockIdExt()
+	header, err := deserializeSingleRoot(res.Header)
+	if err != nil {
+		return err
+	}
+	if _, err := verifyBlockProof(header, id); err != nil {
+		return err
+	}
+	if id.Workchain != blockID.Workchain {
+		return fmt.Errorf("found block belongs to another workchain")
+	}
+	shard, err := ton.ParseShardID(int64(blockID.Shard))
+	if err != nil {
+		return err
+	}
+	if !shard.MatchBlockID(id.BlockID) {
+		return fmt.Errorf("found block belongs to another shard")
+	}
+	if id.Workchain == -1 && id.Seqno > mcBlockID.Seqno {
+		return fmt.Errorf("found block is newer than the masterchain block")
+	}
+	if id.Workchain == -1 && id.Seqno == mcBlockID.Seqno && id != mcBlockID {
+		return fmt.Errorf("found block doesn't match the masterchain block")
+	}
+	if mode&1 != 0 && id.Seqno != blockID.Seqno {
+		return fmt.Errorf("found block has another seqno")
+	}
+	if mode&2 != 0 && lt != nil && (*lt < info.StartLt || *lt > info.EndLt) {
+		return fmt.Errorf("found block doesn't contain the requested lt")
+	}
+	return nil
+}
+
+// provenShardStateOther contains parts of the shard state's ^[...] cell required to check libraries.
+type provenShardStateOther struct {
+	OverloadHistory    uint64
+	UnderloadHistory   uint64
+	TotalBalance       tlb.CurrencyCollection
+	TotalValidatorFees tlb.CurrencyCollection
+	Libraries          tlb.Maybe[provenCell]
+}
+
+// verifyLibraries checks that the returned libraries are present in the proven state of the block
+// and the requested libraries that are not returned are proven to be absent.
+func verifyLibraries(res liteclient.LiteServerLibraryResultWithProofC, blockID ton.BlockIDExt, libraryList []ton.Bits256) error {
+	cell, err := deserializeSingleRoot(res.StateProof)
+	if err != nil {
+		return err
+	}
+	block, err := verifyBlockProof(cell, blockID)
+	if err != nil {
+		return err
+	}
+	cell, err = deserializeSingleRoot(res.DataProof)
+	if err != nil {
+		return err
+	}
+	var state provenShardState
+	if err := verifyStateProof(cell, block.StateUpdate.ToHash, &state); err != nil {
+		return err
+	}
+	var other provenShardStateOther
+	if err := unmarshalProvenCell(state.Other, &other); err != nil {
+		return fmt.Errorf("failed to decode libraries: %w", err)
+	}
+	returned := make(map[tlb.Bits256]struct{}, len(res.Result))
+	for _, lib := range res.Result {
+		returned[tlb.Bits256(lib.Hash)] = struct{}{}
+	}
+	for _, hash := range libraryList {
+		found := false
+		if other.Libraries.Exists {
+			key := boc.NewBitString(256)
+			if err := key.WriteBytes(hash[:]); err != nil {
+				return err
+			}
+			root := other.Libraries.Value.Cell
+			root.ResetCounters()
+			if _, found, err = tlb.FindKeyInProvenHashmap(root, key); err != nil {
+				return fmt.Errorf("failed to find library %x: %w", hash, err)
+			}
+		}
+		if _, ok := returned[tlb.Bits256(hash)]; ok != found {
+			return fmt.Errorf("library %x presence mismatch", hash)
+		}
+		delete(returned, tlb.Bits256(hash))
+	}
+	if len(returned) > 0 {
+		return fmt.Errorf("not requested libraries are returned")
+	}
+	return nil
+}
+
+// provenOutMsgQueueInfo contains parts of OutMsgQueueInfo required to check dispatch queues.
+type provenOutMsgQueueInfo struct {
+	OutQueue      tlb.Maybe[provenCell]
+	OutQueueExtra uint64
+	ProcInfo      tlb.Maybe[provenCell]
+	Extra         tlb.Maybe[struct {
+		Magic              tlb.Magic `tlb:"out_msg_queue_extra#0"`
+		DispatchQueue      tlb.Maybe[provenCell]
+		DispatchQueueExtra uint64
+	}]
+}
+
+// provenDispatchQueue is a dispatch queue of an account visible in a merkle proof.
+type provenDispatchQueue struct {
+	Count uint64
+	MinLt uint64
+	MaxLt uint64
+	// LtRangeProven reports whether no pruned branch can hide messages outside of [MinLt, MaxLt].
+	LtRangeProven bool
+}
+
+// verifyDispatchQueueInfo checks that the returned dispatch queues match the proven state of the block.
+func verifyDispatchQueueInfo(res liteclient.LiteServerDispatchQueueInfoC, blockID ton.BlockIDExt, afterAddr *ton.Bits256) error {
+	var state provenShardState
+	if err := verifyBlockStateProof(res.Proof, blockID, &state); err != nil {
+		return err
+	}
+	var info provenOutMsgQueueInfo
+	if err := unmarshalProvenCell(state.OutMsgQueueInfo, &info); err != nil {
+		return fmt.Errorf("failed to decode out message queue: %w", err)
+	}
+	if !info.Extra.Exists {
+		return checkDispatchQueues(nil, nil, res.AccountDispatchQueues, res.Complete, afterAddr)
+	}
+	proven := make(map[tlb.Bits256]provenDispatchQueue)
+	pruned, err := walkProvenHashmapE(info.Extra.Value.DispatchQueue, 256, func(key boc.BitString, c *boc.Cell) error {
+		var leaf struct {
+			Extra    uint64
+			Messages tlb.Maybe[provenCell]
+			Count    tlb.Uint48
+		}
+		if err := tlb.Unmarshal(c, &leaf); err != nil {
+			return err
+		}
+		queue, err := provenDispatchQueueOf(leaf.Messages, uint64(leaf.Count))
+		if err != nil {
+			return err
+		}
+		var addr tlb.Bits256
+		copy(addr[:], key.Buffer())
+		proven[addr] = queue
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to decode dispatch queue: %w", err)
+	}
+	return checkDispatchQueues(proven, pruned, res.AccountDispatchQueues, res.Complete, afterAddr)
+}
+
+// provenDispatchQueueOf returns the lt range of messages of a dispatch queue visible in a merkle proof.
+func provenDispatchQueueOf(messages tlb.Maybe[provenCell], count uint64) (provenDispatchQueue, error) {
+	queue := provenDispatchQueue{Count: count}
+	visible := false
+	pruned, err := walkProvenHashmapE(messages, 64, func(key boc.BitString, c *boc.Cell) error {
+		lt := binary.BigEndian.Uint64(key.Buffer())
+		if !visible {
+			queue.MinLt, queue.MaxLt, visible = lt, lt, true
+		}
+		queue.MinLt, queue.MaxLt = min(queue.MinLt, lt), max(queue.MaxLt, lt)
+		return nil
+	})
+	if err != nil || !visible {
+		return queue, err
+	}
+	minLt, maxLt := binary.BigEndian.AppendUint64(nil, queue.MinLt), binary.BigEndian.AppendUint64(nil, queue.MaxLt)
+	queue.LtRangeProven = checkPrunedRanges(pruned, nil, minLt) == nil && checkPrunedRanges(pruned, maxLt, nil) == nil
+	return queue, nil
+}
+
+// checkDispatchQueues checks that every returned dispatch queue is present among the proven ones
+// with the same size and lt range, and that no account is skipped or hidden in a pruned branch.
+func checkDispatchQueues(proven map[tlb.Bits256]provenDispatchQueue, pruned []keyRange, queues []liteclient.LiteServerAccountDispatchQueueInfoC, complete bool, afterAddr *ton.Bits256) error {
+	returned := make(map[tlb.Bits256]struct{}, len(queues))
+	last := (*tlb.Bits256)(afterAddr)
+	for _, q := range queues {
+		addr := tlb.Bits256(q.Addr)
+		if last != nil && bytes.Compare(addr[:], last[:]) <= 0 {
+			return fmt.Errorf("dispatch queues are not sorted by address")
+		}
+		last = &addr
+		returned[addr] = struct{}{}
+		queue, ok := proven[addr]
+		if !ok {
+			return fmt.Errorf("dispatch queue of %x not found in state", addr)
+		}
+		if queue.Count != q.Size {
+			return fmt.Errorf("dispatch queue of %x size mismatch", addr)
+		}
+		if !queue.LtRangeProven {
+			return fmt.Errorf("dispatch queue of %x lt range is not proven", addr)
+		}
+		if queue.MinLt != q.MinLt || queue.MaxLt != q.MaxLt {
+			return fmt.Errorf("dispatch queue of %x lt range mismatch", addr)
+		}
+	}
+	var after, upTo []byte
+	if afterAddr != nil {
+		after = afterAddr[:]
+	}
+	if !complete {
+		if len(queues) == 0 {
+			return nil
+		}
+		upTo = last[:]
+	}
+	for addr := range proven {
+		if _, ok := returned[addr]; ok {
+			continue
+		}
+		if after != nil && bytes.Compare(addr[:], after) <= 0 {
+			continue
+		}
+		if upTo != nil && bytes.Compare(addr[:], upTo) > 0 {
+			continue
+		}
+		return fmt.Errorf("dispatch queue of %x is missing", addr)
+	}
+	return checkPrunedRanges(pruned, after, upTo)
+}
+
+// verifyTransactionChain checks that transactions form a chain starting from the given lt and hash.
+func verifyTransactionChain(cells []*boc.Cell, txs []ton.Transaction, lt uint64, hash ton.Bits256) error {
+	for i, cell := range cells {
+		cellHash, err := cell.Hash256()
+		if err != nil {
+			return err
+		}
+		if cellHash != hash || txs[i].Lt != lt {
+			return fmt.Errorf("transaction %v doesn't match the expected hash or lt", i)
+		}
+		hash, lt = ton.Bits256(txs[i].PrevTransHash), txs[i].PrevTransLt
+	}
+	return nil
+}
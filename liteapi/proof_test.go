@@ -0,0 +1,707 @@
+package liteapi
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestCheckBlockSignatures(t *testing.T) {
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: -1, Shard: 0x8000000000000000, Seqno: 100},
+		RootHash: ton.Bits256{1, 2, 3},
+		FileHash: ton.Bits256{4, 5, 6},
+	}
+	var (
+		validators []tlb.ValidatorDescr
+		keys       []ed25519.PrivateKey
+	)
+	for _, weight := range []uint64{10, 10, 10, 10} {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("GenerateKey() failed: %v", err)
+		}
+		descr := tlb.ValidatorDescr{SumType: "Validator", Validator: &struct {
+			PublicKey tlb.SigPubKey
+			Weight    uint64
+		}{Weight: weight}}
+		copy(descr.Validator.PublicKey.PubKey[:], pub)
+		validators = append(validators, descr)
+		keys = append(keys, priv)
+	}
+	sign := func(key ed25519.PrivateKey, pubKey tlb.Bits256, msg []byte) liteclient.LiteServerSignatureC {
+		return liteclient.LiteServerSignatureC{
+			NodeIdShort: tl.Int256(validatorNodeIdShort(pubKey)),
+			Signature:   ed25519.Sign(key, msg),
+		}
+	}
+	msg := blockSignatureMessage(blockID)
+	tests := []struct {
+		name    string
+		signers []int
+		msg     []byte
+		wantErr bool
+	}{
+		{name: "all signed", signers: []int{0, 1, 2, 3}, msg: msg},
+		{name: "three of four", signers: []int{0, 1, 3}, msg: msg},
+		{name: "two of four", signers: []int{0, 2}, msg: msg, wantErr: true},
+		{name: "duplicates are ignored", signers: []int{0, 0, 1, 1}, msg: msg, wantErr: true},
+		{name: "wrong message", signers: []int{0, 1, 2}, msg: blockSignatureMessage(ton.BlockIDExt{}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var set liteclient.LiteServerSignatureSet
+			for _, i := range tt.signers {
+				set.Signatures = append(set.Signatures, sign(keys[i], validators[i].PubKey(), tt.msg))
+			}
+			err := checkBlockSignatures(blockID, set, validators)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkBlockSignatures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBlockProver(t *testing.T) {
+	trusted := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Seqno: 10}}
+	p := newBlockProver(trusted)
+	if !p.isProven(trusted) {
+		t.Fatalf("trusted block must be proven")
+	}
+	keyBlock := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Seqno: 20}}
+	p.addProven(keyBlock, true)
+	p.addProven(ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Seqno: 5}}, true)
+	if got := p.trustedKeyBlock(); got != keyBlock {
+		t.Fatalf("trustedKeyBlock() = %v, want %v", got, keyBlock)
+	}
+}
+
+func TestProvenShardAccount(t *testing.T) {
+	shardAccount := tlb.ShardAccount{
+		Account:       tlb.Account{SumType: "AccountNone"},
+		LastTransHash: tlb.Bits256{7, 7, 7},
+		LastTransLt:   12345,
+	}
+	cell := boc.NewCell()
+	if err := tlb.Marshal(cell, shardAccount); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	accountCell, err := cell.Refs()[0].Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	var acc provenShardAccount
+	if err := tlb.Unmarshal(cell, &acc); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	want := provenShardAccount{
+		Account:       provenRef{Hash: accountCell},
+		LastTransHash: shardAccount.LastTransHash,
+		LastTransLt:   shardAccount.LastTransLt,
+	}
+	if acc != want {
+		t.Fatalf("want: %v, got: %v", want, acc)
+	}
+}
+
+func TestVerifyAccountState_Mismatch(t *testing.T) {
+	mcBlock := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: 0x8000000000000000, Seqno: 10}}
+	shardBlock := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: 0, Shard: 0x4000000000000000, Seqno: 20}}
+	account := ton.MustParseAccountID("0:2d41ed396a9f1ba03839d63c5650fafc6fd9b2bd9e6ff9c6ba8b0e5f5e3c5ade")
+	tests := []struct {
+		name     string
+		id       ton.BlockIDExt
+		shardblk ton.BlockIDExt
+		wantErr  string
+	}{
+		{
+			name:     "another masterchain block",
+			id:       ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: 0x8000000000000000, Seqno: 11}},
+			shardblk: shardBlock,
+			wantErr:  "state is returned for another block",
+		},
+		{
+			name:     "account from another shard",
+			id:       mcBlock,
+			shardblk: ton.BlockIDExt{BlockID: ton.BlockID{Workchain: 0, Shard: 0xc000000000000000, Seqno: 20}},
+			wantErr:  "account doesn't belong to shard",
+		},
+		{
+			name:     "another workchain",
+			id:       mcBlock,
+			shardblk: mcBlock,
+			wantErr:  "shard block belongs to another workchain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := liteclient.LiteServerAccountStateC{
+				Id:       liteclient.BlockIDExt(tt.id),
+				Shardblk: liteclient.BlockIDExt(tt.shardblk),
+			}
+			_, _, err := verifyAccountState(res, mcBlock, account)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckDispatchQueues(t *testing.T) {
+	proven := map[tlb.Bits256]provenDispatchQueue{
+		{1}:   {Count: 2, MinLt: 10, MaxLt: 20, LtRangeProven: true},
+		{2}:   {Count: 1, MinLt: 30, MaxLt: 30, LtRangeProven: true},
+		{3}:   {Count: 3, MinLt: 40, MaxLt: 50, LtRangeProven: true},
+		{5}:   {Count: 1, MinLt: 60, MaxLt: 60, LtRangeProven: true},
+		{9}:   {Count: 1, MinLt: 70, MaxLt: 70},
+		{0xc}: {Count: 1, MinLt: 80, MaxLt: 80, LtRangeProven: true},
+	}
+	// accounts starting with 0x0a are hidden by a pruned branch.
+	pruned := []keyRange{{
+		Min: append([]byte{0xa}, bytes.Repeat([]byte{0}, 31)...),
+		Max: append([]byte{0xa}, bytes.Repeat([]byte{0xff}, 31)...),
+	}}
+	info := func(addr byte, size, minLt, maxLt uint64) liteclient.LiteServerAccountDispatchQueueInfoC {
+		return liteclient.LiteServerAccountDispatchQueueInfoC{Addr: tl.Int256{addr}, Size: size, MinLt: minLt, MaxLt: maxLt}
+	}
+	after := ton.Bits256{1}
+	afterFifth := ton.Bits256{5}
+	afterNinth := ton.Bits256{9}
+	tests := []struct {
+		name     string
+		queues   []liteclient.LiteServerAccountDispatchQueueInfoC
+		complete bool
+		after    *ton.Bits256
+		wantErr  string
+	}{
+		{
+			name:   "first page",
+			queues: []liteclient.LiteServerAccountDispatchQueueInfoC{info(1, 2, 10, 20), info(2, 1, 30, 30), info(3, 3, 40, 50)},
+		},
+		{
+			name:     "complete list hides a pruned branch",
+			queues:   []liteclient.LiteServerAccountDispatchQueueInfoC{info(0xc, 1, 80, 80)},
+			complete: true,
+			after:    &afterNinth,
+			wantErr:  "pruned",
+		},
+		{
+			name:    "lt range not proven",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(9, 1, 70, 70)},
+			after:   &afterFifth,
+			wantErr: "lt range is not proven",
+		},
+		{
+			name:    "page crosses a pruned branch",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(0xc, 1, 80, 80)},
+			after:   &afterNinth,
+			wantErr: "pruned",
+		},
+		{
+			name:   "first account",
+			queues: []liteclient.LiteServerAccountDispatchQueueInfoC{info(1, 2, 10, 20)},
+		},
+		{
+			name:   "after address",
+			queues: []liteclient.LiteServerAccountDispatchQueueInfoC{info(2, 1, 30, 30), info(3, 3, 40, 50)},
+			after:  &after,
+		},
+		{
+			name:    "unknown account",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(4, 1, 1, 1)},
+			wantErr: "not found in state",
+		},
+		{
+			name:    "size mismatch",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(1, 3, 10, 20)},
+			wantErr: "size mismatch",
+		},
+		{
+			name:    "lt range mismatch",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(1, 2, 10, 15)},
+			wantErr: "lt range mismatch",
+		},
+		{
+			name:    "skipped account",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(1, 2, 10, 20), info(3, 3, 40, 50)},
+			wantErr: "is missing",
+		},
+		{
+			name:     "truncated complete list",
+			queues:   []liteclient.LiteServerAccountDispatchQueueInfoC{info(1, 2, 10, 20), info(2, 1, 30, 30)},
+			complete: true,
+			wantErr:  "is missing",
+		},
+		{
+			name:    "not sorted",
+			queues:  []liteclient.LiteServerAccountDispatchQueueInfoC{info(2, 1, 30, 30), info(1, 2, 10, 20)},
+			wantErr: "not sorted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDispatchQueues(proven, pruned, tt.queues, tt.complete, tt.after)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkDispatchQueues() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFindProvenAccount(t *testing.T) {
+	type leaf struct {
+		Extra   tlb.DepthBalanceInfo
+		Account tlb.ShardAccount
+	}
+	present := tlb.Bits256{1}
+	other := tlb.Bits256{2}
+	accountsRoot := boc.NewCell()
+	hashmap := tlb.NewHashmap([]tlb.Bits256{present, other}, []leaf{
+		{Account: tlb.ShardAccount{Account: tlb.Account{SumType: "AccountNone"}, LastTransLt: 10}},
+		{Account: tlb.ShardAccount{Account: tlb.Account{SumType: "AccountNone"}, LastTransLt: 20}},
+	})
+	if err := tlb.Marshal(accountsRoot, hashmap); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	accounts := boc.NewCell()
+	if err := accounts.WriteBit(true); err != nil {
+		t.Fatalf("WriteBit() failed: %v", err)
+	}
+	if err := accounts.AddRef(accountsRoot); err != nil {
+		t.Fatalf("AddRef() failed: %v", err)
+	}
+
+	acc, err := findProvenAccount(accounts, present)
+	if err != nil || acc == nil || acc.LastTransLt != 10 {
+		t.Fatalf("want account with lt 10, got %v, %v", acc, err)
+	}
+	acc, err = findProvenAccount(accounts, tlb.Bits256{3})
+	if err != nil || acc != nil {
+		t.Fatalf("want absent account, got %v, %v", acc, err)
+	}
+
+	// prune the path to the other account as a lite server could do to hide it
+	prover, err := boc.NewMerkleProver(accounts)
+	if err != nil {
+		t.Fatalf("NewMerkleProver() failed: %v", err)
+	}
+	cursor := prover.Cursor()
+	cursor.Ref(0).Ref(1).Prune()
+	proofBoc, err := prover.CreateProof(cursor)
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	proof, err := boc.DeserializeBoc(proofBoc)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	hash, err := accounts.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	root, err := boc.VerifyMerkleProof(proof[0], hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	if acc, err := findProvenAccount(root, present); err != nil || acc == nil {
+		t.Fatalf("want present account, got %v, %v", acc, err)
+	}
+	if _, err := findProvenAccount(root, other); !errors.Is(err, tlb.ErrPrunedBranch) {
+		t.Fatalf("want ErrPrunedBranch, got %v", err)
+	}
+}
+
+// masterchainBlockShard is a shard block which is present in the state of tlb/testdata/block-5.
+var masterchainBlockShard = ton.BlockIDExt{
+	BlockID:  ton.BlockID{Workchain: 0, Shard: 0x8000000000000000, Seqno: 22738548},
+	RootHash: ton.MustParseHash("f7a03e15551893cb81ceed2fd962629bb40ee8ded998cfb8e3182c574ba7de93"),
+	FileHash: ton.MustParseHash("0cc3d3561a6efde9803e80e40cfc6ab445680b8532467e0960a3277edb756500"),
+}
+
+// loadTestBlock reads a block from tlb/testdata and returns it with its ID.
+func loadTestBlock(t *testing.T, name string) (*boc.Cell, ton.BlockIDExt) {
+	data, err := os.ReadFile(fmt.Sprintf("../tlb/testdata/%v/block.bin", name))
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	root, err := deserializeSingleRoot(data)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	var block provenBlock
+	if err := tlb.Unmarshal(root, &block); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	hash, err := root.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	workchain, shard := shardFromIdent(block.Info.Shard)
+	blockID := ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: workchain, Shard: shard, Seqno: block.Info.SeqNo},
+		RootHash: hash,
+	}
+	root.ResetCounters()
+	return root, blockID
+}
+
+// testBlockState returns a state of the block taken from its state update.
+func testBlockState(block *boc.Cell) *boc.Cell {
+	return block.Refs()[2].Refs()[1]
+}
+
+// createTestProof creates a merkle proof of the given tree pruning cells chosen by prune.
+func createTestProof(t *testing.T, root *boc.Cell, prune func(cursor *boc.Cursor)) *boc.Cell {
+	prover, err := boc.NewMerkleProver(root)
+	if err != nil {
+		t.Fatalf("NewMerkleProver() failed: %v", err)
+	}
+	cursor := prover.Cursor()
+	if prune != nil {
+		prune(cursor)
+	}
+	proofBoc, err := prover.CreateProof(cursor)
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	proof, err := deserializeSingleRoot(proofBoc)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	return proof
+}
+
+func serializeTestProof(t *testing.T, roots ...*boc.Cell) []byte {
+	data, err := boc.SerializeBocRoots(roots, false, false, false, 0)
+	if err != nil {
+		t.Fatalf("SerializeBocRoots() failed: %v", err)
+	}
+	return data
+}
+
+func TestVerifyBlockProof(t *testing.T) {
+	block, blockID := loadTestBlock(t, "block-5")
+	anotherSeqno := blockID
+	anotherSeqno.Seqno++
+	anotherHash := blockID
+	anotherHash.RootHash = ton.Bits256{1}
+	tests := []struct {
+		name    string
+		prune   func(cursor *boc.Cursor)
+		blockID ton.BlockIDExt
+		wantErr string
+	}{
+		{
+			name:    "block proof",
+			blockID: blockID,
+		},
+		{
+			name:    "proof without value flow",
+			prune:   func(cursor *boc.Cursor) { cursor.Ref(1).Prune() },
+			blockID: blockID,
+		},
+		{
+			name:    "another block",
+			blockID: anotherSeqno,
+			wantErr: "block proof is built for another block",
+		},
+		{
+			name:    "another root hash",
+			blockID: anotherHash,
+			wantErr: "root hash mismatch",
+		},
+		{
+			name:    "pruned block info",
+			prune:   func(cursor *boc.Cursor) { cursor.Ref(0).Prune() },
+			blockID: blockID,
+			wantErr: "block proof is built for another block",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof := createTestProof(t, block, tt.prune)
+			provenBlock, err := verifyBlockProof(proof, tt.blockID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyBlockProof() failed: %v", err)
+				}
+				if provenBlock.Info.SeqNo != blockID.Seqno {
+					t.Fatalf("want seqno %v, got %v", blockID.Seqno, provenBlock.Info.SeqNo)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyShardBlock(t *testing.T) {
+	block, blockID := loadTestBlock(t, "block-5")
+	blockProof := createTestProof(t, block, nil)
+	unknownShard := masterchainBlockShard
+	unknownShard.Seqno++
+	tests := []struct {
+		name       string
+		prune      func(cursor *boc.Cursor)
+		shardBlock ton.BlockIDExt
+		wantErr    string
+	}{
+		{
+			name:       "shard block in state",
+			shardBlock: masterchainBlockShard,
+		},
+		{
+			name:       "unknown shard block",
+			shardBlock: unknownShard,
+			wantErr:    "not found in masterchain state",
+		},
+		{
+			name: "pruned shard hashes",
+			// custom:(Maybe ^McStateExtra) is the fourth reference of the state,
+			// shard hashes are the first one of McStateExtra.
+			prune:      func(cursor *boc.Cursor) { cursor.Ref(3).Ref(0).Prune() },
+			shardBlock: masterchainBlockShard,
+			wantErr:    "not found in masterchain state",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateProof := createTestProof(t, testBlockState(block), tt.prune)
+			proof := serializeTestProof(t, blockProof, stateProof)
+			err := verifyShardBlock(proof, blockID, tt.shardBlock, tt.shardBlock.Workchain)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyShardBlock() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAllShardsInfo(t *testing.T) {
+	block, blockID := loadTestBlock(t, "block-5")
+	blockProof := createTestProof(t, block, nil)
+	shardHashes := testBlockState(block).Refs()[3].Refs()[0]
+	data := boc.NewCell()
+	if err := data.WriteBit(true); err != nil {
+		t.Fatalf("WriteBit() failed: %v", err)
+	}
+	if err := data.AddRef(shardHashes); err != nil {
+		t.Fatalf("AddRef() failed: %v", err)
+	}
+	empty := boc.NewCell()
+	if err := empty.WriteBit(false); err != nil {
+		t.Fatalf("WriteBit() failed: %v", err)
+	}
+	tests := []struct {
+		name      string
+		prune     func(cursor *boc.Cursor)
+		data      *boc.Cell
+		wantErr   string
+		wantErrIs error
+	}{
+		{
+			name: "same shard hashes",
+			data: data,
+		},
+		{
+			name: "pruned shard hashes",
+			// the hash of pruned shard hashes is still known.
+			prune: func(cursor *boc.Cursor) { cursor.Ref(3).Ref(0).Prune() },
+			data:  data,
+		},
+		{
+			name:    "empty shard hashes",
+			data:    empty,
+			wantErr: "shard hashes mismatch",
+		},
+		{
+			name:      "pruned masterchain state extra",
+			prune:     func(cursor *boc.Cursor) { cursor.Ref(3).Prune() },
+			data:      data,
+			wantErr:   "failed to decode masterchain state extra",
+			wantErrIs: tlb.ErrPrunedBranch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateProof := createTestProof(t, testBlockState(block), tt.prune)
+			res := liteclient.LiteServerAllShardsInfoC{
+				Id:    liteclient.BlockIDExt(blockID),
+				Proof: serializeTestProof(t, blockProof, stateProof),
+			}
+			err := verifyAllShardsInfo(res, tt.data, blockID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyAllShardsInfo() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error %q, got: %v", tt.wantErr, err)
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Fatalf("want %v, got: %v", tt.wantErrIs, err)
+			}
+		})
+	}
+}
+
+func TestVerifyBlockTransactions(t *testing.T) {
+	block, blockID := loadTestBlock(t, "block-5")
+	var decoded tlb.Block
+	if err := tlb.Unmarshal(block, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	var txs []ton.Transaction
+	var cells []*boc.Cell
+	for _, tx := range decoded.AllTransactions() {
+		txBoc, err := tx.SourceBoc()
+		if err != nil {
+			t.Fatalf("SourceBoc() failed: %v", err)
+		}
+		cell, err := deserializeSingleRoot(txBoc)
+		if err != nil {
+			t.Fatalf("DeserializeBoc() failed: %v", err)
+		}
+		txs = append(txs, ton.Transaction{Transaction: *tx, BlockID: blockID})
+		cells = append(cells, cell)
+	}
+	// a lite server returns transactions ordered by account and lt.
+	order := make([]int, len(txs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := txs[order[i]], txs[order[j]]
+		return bytes.Compare(blockTxKey(a.AccountAddr, a.Lt), blockTxKey(b.AccountAddr, b.Lt)) < 0
+	})
+	sortedTxs := make([]ton.Transaction, len(txs))
+	sortedCells := make([]*boc.Cell, len(txs))
+	for i, j := range order {
+		sortedTxs[i], sortedCells[i] = txs[j], cells[j]
+	}
+	txs, cells = sortedTxs, sortedCells
+	if len(txs) != 6 {
+		t.Fatalf("want 6 transactions, got %v", len(txs))
+	}
+	pick := func(indexes ...int) ([]ton.Transaction, []*boc.Cell) {
+		var pickedTxs []ton.Transaction
+		var pickedCells []*boc.Cell
+		for _, i := range indexes {
+			pickedTxs = append(pickedTxs, txs[i])
+			pickedCells = append(pickedCells, cells[i])
+		}
+		return pickedTxs, pickedCells
+	}
+	after := &liteclient.LiteServerTransactionId3C{Account: tl.Int256(txs[2].AccountAddr), Lt: txs[2].Lt}
+	// the right branch of account blocks contains the only account starting with bit 1.
+	pruneAccount := func(cursor *boc.Cursor) { cursor.Ref(3).Ref(2).Ref(0).Ref(1).Prune() }
+	tests := []struct {
+		name       string
+		prune      func(cursor *boc.Cursor)
+		indexes    []int
+		swapCells  bool
+		after      *liteclient.LiteServerTransactionId3C
+		incomplete bool
+		wantErr    string
+	}{
+		{
+			name:    "all transactions",
+			indexes: []int{0, 1, 2, 3, 4, 5},
+		},
+		{
+			name:       "first page",
+			indexes:    []int{0, 1, 2},
+			incomplete: true,
+		},
+		{
+			name:    "last page",
+			indexes: []int{3, 4, 5},
+			after:   after,
+		},
+		{
+			name:       "empty incomplete page",
+			incomplete: true,
+		},
+		{
+			name:    "truncated complete list",
+			indexes: []int{0, 1, 2},
+			wantErr: "is missing",
+		},
+		{
+			name:       "skipped transaction",
+			indexes:    []int{0, 2},
+			incomplete: true,
+			wantErr:    "is missing",
+		},
+		{
+			name:       "not sorted",
+			indexes:    []int{1, 0},
+			incomplete: true,
+			wantErr:    "not sorted",
+		},
+		{
+			name:       "hash mismatch",
+			indexes:    []int{0, 1},
+			swapCells:  true,
+			incomplete: true,
+			wantErr:    "hash mismatch",
+		},
+		{
+			name:       "page before a pruned account",
+			prune:      pruneAccount,
+			indexes:    []int{0, 1, 2, 3, 4},
+			incomplete: true,
+		},
+		{
+			name:    "complete list hides a pruned account",
+			prune:   pruneAccount,
+			indexes: []int{0, 1, 2, 3, 4},
+			wantErr: "pruned",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof := createTestProof(t, block, tt.prune)
+			proofBoc := serializeTestProof(t, proof)
+			pickedTxs, pickedCells := pick(tt.indexes...)
+			if tt.swapCells {
+				pickedCells[0], pickedCells[1] = pickedCells[1], pickedCells[0]
+			}
+			err := verifyBlockTransactions(proofBoc, blockID, pickedCells, pickedTxs, tt.after, tt.incomplete)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyBlockTransactions() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("want error %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
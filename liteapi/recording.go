@@ -0,0 +1,238 @@
+package liteapi
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+// magicWaitMasterchainSeqno is a tag of liteServer.waitMasterchainSeqno prefix of a request.
+const magicWaitMasterchainSeqno = 0xbaeab892
+
+// RecordedRequest is a raw liteServer.query request and responses a lite server returned for it.
+type RecordedRequest struct {
+	// Method and Decoded are informational and make a recording human-readable,
+	// only Request and Responses are used to replay it.
+	Method string `json:"method"`
+	// WaitMasterchainSeqno is set if a request is prefixed with liteServer.waitMasterchainSeqno.
+	WaitMasterchainSeqno uint32          `json:"wait_masterchain_seqno,omitempty"`
+	Decoded              json.RawMessage `json:"decoded,omitempty"`
+	// Request is hex-encoded TL data of a request without liteServer.waitMasterchainSeqno prefix,
+	// so the same request waiting for different seqnos is recorded and replayed once.
+	Request string `json:"request"`
+	// Responses are in the order they were received.
+	Responses []RecordedResponse `json:"responses"`
+}
+
+// RecordedResponse is either a hex-encoded TL response of a lite server
+// or an error if a lite server didn't answer.
+// A recorded error is replayed as a lite server error with the same message.
+type RecordedResponse struct {
+	Data  string `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Recording is a set of requests captured by Recorder.
+type Recording struct {
+	Requests []RecordedRequest `json:"requests"`
+}
+
+// Recorder captures raw requests sent by a client and responses to them.
+// Take a look at WithRecorder() option.
+type Recorder struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+	// index maps a request to its position in requests.
+	index map[string]int
+}
+
+var _ liteclient.Recorder = &Recorder{}
+
+func NewRecorder() *Recorder {
+	return &Recorder{index: map[string]int{}}
+}
+
+// RecordRequest implements liteclient.Recorder.
+func (r *Recorder) RecordRequest(request []byte, response []byte, err error) {
+	key := hex.EncodeToString(requestKey(request))
+	var recordedResponse RecordedResponse
+	if err != nil {
+		recordedResponse.Error = err.Error()
+	} else {
+		recordedResponse.Data = hex.EncodeToString(response)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i, ok := r.index[key]; ok {
+		r.requests[i].Responses = append(r.requests[i].Responses, recordedResponse)
+		return
+	}
+	recorded := describeRequest(request)
+	recorded.Request = key
+	recorded.Responses = []RecordedResponse{recordedResponse}
+	r.index[key] = len(r.requests)
+	r.requests = append(r.requests, recorded)
+}
+
+// requestKey strips liteServer.waitMasterchainSeqno prefix from a request,
+// so a request matches its recording no matter which seqno it waits for.
+func requestKey(request []byte) []byte {
+	if len(request) >= 12 && binary.LittleEndian.Uint32(request[:4]) == magicWaitMasterchainSeqno {
+		return request[12:]
+	}
+	return request
+}
+
+// describeRequest decodes a request with liteclient.LiteapiRequestDecoder.
+func describeRequest(request []byte) RecordedRequest {
+	var recorded RecordedRequest
+	if len(request) >= 12 && binary.LittleEndian.Uint32(request[:4]) == magicWaitMasterchainSeqno {
+		recorded.WaitMasterchainSeqno = binary.LittleEndian.Uint32(request[4:8])
+		request = request[12:]
+		if len(request) == 0 {
+			recorded.Method = "liteServer.waitMasterchainSeqno"
+			return recorded
+		}
+	}
+	_, name, decoded, err := liteclient.LiteapiRequestDecoder(request)
+	if err != nil {
+		recorded.Method = liteclient.UnknownRequest
+		return recorded
+	}
+	recorded.Method = *name
+	if decoded != nil {
+		if b, err := json.Marshal(decoded); err == nil {
+			recorded.Decoded = b
+		}
+	}
+	return recorded
+}
+
+// Recording returns requests captured so far.
+func (r *Recorder) Recording() Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	requests := make([]RecordedRequest, 0, len(r.requests))
+	for _, req := range r.requests {
+		req.Responses = append([]RecordedResponse{}, req.Responses...)
+		requests = append(requests, req)
+	}
+	return Recording{Requests: requests}
+}
+
+// Save writes requests captured so far to a JSON file.
+func (r *Recorder) Save(path string) error {
+	b, err := json.MarshalIndent(r.Recording(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// LoadRecording reads a recording saved by Recorder.Save.
+func LoadRecording(path string) (Recording, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Recording{}, err
+	}
+	var recording Recording
+	if err := json.Unmarshal(b, &recording); err != nil {
+		return Recording{}, err
+	}
+	return recording, nil
+}
+
+// replayHandler serves recorded responses.
+// If a request was recorded several times, its responses are returned in the recorded order,
+// and the last one is repeated after that.
+type replayHandler struct {
+	mu        sync.Mutex
+	responses map[string][]replayResponse
+	next      map[string]int
+}
+
+type replayResponse struct {
+	data []byte
+	err  error
+}
+
+func newReplayHandler(recording Recording) (*replayHandler, error) {
+	h := &replayHandler{
+		responses: make(map[string][]replayResponse, len(recording.Requests)),
+		next:      map[string]int{},
+	}
+	for _, req := range recording.Requests {
+		request, err := hex.DecodeString(req.Request)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recorded request: %w", err)
+		}
+		key := string(requestKey(request))
+		for _, resp := range req.Responses {
+			if resp.Error != "" {
+				h.responses[key] = append(h.responses[key], replayResponse{err: errors.New(resp.Error)})
+				continue
+			}
+			data, err := hex.DecodeString(resp.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recorded response: %w", err)
+			}
+			h.responses[key] = append(h.responses[key], replayResponse{data: data})
+		}
+	}
+	return h, nil
+}
+
+func (h *replayHandler) HandleRawRequest(ctx context.Context, request []byte) ([]byte, bool, error) {
+	key := string(requestKey(request))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	responses := h.responses[key]
+	if len(responses) == 0 {
+		return nil, true, liteclient.LiteServerErrorC{Code: 651, Message: "no recorded response for " + describeRequest(request).Method}
+	}
+	i := h.next[key]
+	if i < len(responses)-1 {
+		h.next[key] = i + 1
+	}
+	return responses[i].data, true, responses[i].err
+}
+
+func (h *replayHandler) HandleRequest(ctx context.Context, name liteclient.RequestName, request any) (any, error) {
+	// never called because HandleRawRequest handles all requests.
+	return nil, fmt.Errorf("no recorded response")
+}
+
+// startReplayServer starts an in-process lite server that replays the recording.
+// The server has to be closed by the caller.
+func startReplayServer(recording Recording) (*liteclient.Server, config.LiteServer, error) {
+	handler, err := newReplayHandler(recording)
+	if err != nil {
+		return nil, config.LiteServer{}, err
+	}
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, config.LiteServer{}, err
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, config.LiteServer{}, err
+	}
+	server := liteclient.NewServer(key, handler)
+	go server.Serve(l)
+	return server, config.LiteServer{
+		Host: l.Addr().String(),
+		Key:  base64.StdEncoding.EncodeToString(pub),
+	}, nil
+}
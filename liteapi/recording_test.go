@@ -0,0 +1,118 @@
+package liteapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	info := liteclient.LiteServerMasterchainInfoC{
+		Last: liteclient.TonNodeBlockIdExtC{Workchain: 0xffffffff, Shard: 0x8000000000000000, Seqno: 100},
+	}
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	server := liteclient.NewServer(key, liteclient.HandlerFunc(func(ctx context.Context, name liteclient.RequestName, request any) (any, error) {
+		if name == liteclient.LiteServerGetMasterchainInfoRequestName {
+			return info, nil
+		}
+		return nil, liteclient.LiteServerErrorC{Code: 651, Message: "not found"}
+	}))
+	go server.Serve(l)
+	defer server.Close()
+
+	recorder := NewRecorder()
+	recording, err := NewClient(
+		WithLiteServers([]config.LiteServer{{Host: l.Addr().String(), Key: base64.StdEncoding.EncodeToString(pub)}}),
+		WithRecorder(recorder),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	if _, err := recording.GetMasterchainInfo(context.Background()); err != nil {
+		t.Fatalf("GetMasterchainInfo() failed: %v", err)
+	}
+	requests := recorder.Recording().Requests
+	if len(requests) == 0 || requests[0].Method != liteclient.LiteServerGetMasterchainInfoRequestName {
+		t.Fatalf("unexpected recording: %+v", requests)
+	}
+	path := filepath.Join(t.TempDir(), "recording.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	server.Close()
+
+	replay, err := NewClient(WithReplay(path))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	res, err := replay.GetMasterchainInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetMasterchainInfo() failed: %v", err)
+	}
+	if res != info {
+		t.Fatalf("want %v, got %v", info, res)
+	}
+	cli, _, err := replay.pool.BestMasterchainClient(context.Background())
+	if err != nil {
+		t.Fatalf("BestMasterchainClient() failed: %v", err)
+	}
+	_, err = cli.LiteServerGetTime(context.Background())
+	var liteServerErr liteclient.LiteServerErrorC
+	if !errors.As(err, &liteServerErr) {
+		t.Fatalf("want lite server error for a request that was not recorded, got %v", err)
+	}
+	host := replay.pool.Status().Connections[0].ServerHost
+	replay.Close()
+	if conn, err := net.Dial("tcp", host); err == nil {
+		conn.Close()
+		t.Fatalf("replay server must be stopped when the client is closed")
+	}
+}
+
+func TestRecorder_waitMasterchainSeqnoAndErrors(t *testing.T) {
+	withSeqno := func(seqno uint32, request []byte) []byte {
+		prefix := make([]byte, 12)
+		binary.LittleEndian.PutUint32(prefix, magicWaitMasterchainSeqno)
+		binary.LittleEndian.PutUint32(prefix[4:], seqno)
+		return append(prefix, request...)
+	}
+	request := []byte{0x2e, 0xe6, 0xb5, 0x89} // liteServer.getMasterchainInfo
+	recorder := NewRecorder()
+	recorder.RecordRequest(withSeqno(5, request), []byte{1, 2, 3}, nil)
+	recorder.RecordRequest(withSeqno(7, request), nil, errors.New("request timeout"))
+	requests := recorder.Recording().Requests
+	if len(requests) != 1 || len(requests[0].Responses) != 2 {
+		t.Fatalf("requests waiting for different seqnos must be recorded once, got %+v", requests)
+	}
+	if requests[0].WaitMasterchainSeqno != 5 || requests[0].Method != liteclient.LiteServerGetMasterchainInfoRequestName {
+		t.Fatalf("unexpected recorded request: %+v", requests[0])
+	}
+	handler, err := newReplayHandler(recorder.Recording())
+	if err != nil {
+		t.Fatalf("newReplayHandler() failed: %v", err)
+	}
+	response, _, err := handler.HandleRawRequest(context.Background(), withSeqno(9, request))
+	if err != nil || !bytes.Equal(response, []byte{1, 2, 3}) {
+		t.Fatalf("want the recorded response, got %x, %v", response, err)
+	}
+	if _, _, err := handler.HandleRawRequest(context.Background(), request); err == nil || err.Error() != "request timeout" {
+		t.Fatalf("want the recorded error, got %v", err)
+	}
+}
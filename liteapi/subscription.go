@@ -0,0 +1,286 @@
+package liteapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// masterchainWaitTimeout is used to wait for a new masterchain block before checking a context again.
+const masterchainWaitTimeout = time.Minute
+
+// BlockBatch is a masterchain block with all shard blocks it is the first to commit.
+type BlockBatch struct {
+	Masterchain ton.BlockIDExt
+	Block       tlb.Block
+	// ShardBlocks are sorted in topological order: a block always goes after its parents.
+	// This includes intermediate blocks that were produced between two masterchain blocks and
+	// blocks produced around shard splits and merges.
+	ShardBlocks []ton.BlockIDExt
+}
+
+// blockSource contains all methods needed by a block subscription.
+// used to implement tests.
+type blockSource interface {
+	waitMasterchainSeqno(ctx context.Context, seqno uint32) error
+	lookupMasterchainBlock(ctx context.Context, seqno uint32) (ton.BlockIDExt, error)
+	GetBlock(ctx context.Context, blockID ton.BlockIDExt) (tlb.Block, error)
+	GetBlockHeader(ctx context.Context, blockID ton.BlockIDExt, mode uint32) (tlb.BlockInfo, error)
+}
+
+func (c *Client) waitMasterchainSeqno(ctx context.Context, seqno uint32) error {
+	for {
+		err := c.pool.WaitMasterchainSeqno(ctx, seqno, masterchainWaitTimeout)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) lookupMasterchainBlock(ctx context.Context, seqno uint32) (ton.BlockIDExt, error) {
+	blockID, _, err := c.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: 0x8000000000000000, Seqno: seqno}, 1, nil, nil)
+	return blockID, err
+}
+
+// BlockSubscription streams masterchain blocks one by one along with shard blocks they commit.
+// It is pull-based: nothing is requested from lite servers until Next is called,
+// so a slow consumer never makes the subscription buffer blocks.
+type BlockSubscription struct {
+	source blockSource
+	next   uint32
+	// shardTops contains the latest shard blocks committed by the previous masterchain block.
+	shardTops map[ton.BlockIDExt]struct{}
+}
+
+// SubscribeBlocks returns a subscription that starts from the masterchain block with the given seqno.
+// To resume a subscription later, pass the value returned by BlockSubscription.Cursor.
+func (c *Client) SubscribeBlocks(ctx context.Context, fromSeqno uint32) (*BlockSubscription, error) {
+	return newBlockSubscription(ctx, c, fromSeqno)
+}
+
+func newBlockSubscription(ctx context.Context, source blockSource, fromSeqno uint32) (*BlockSubscription, error) {
+	s := &BlockSubscription{
+		source:    source,
+		next:      fromSeqno,
+		shardTops: map[ton.BlockIDExt]struct{}{},
+	}
+	if fromSeqno == 0 {
+		return s, nil
+	}
+	blockID, err := source.lookupMasterchainBlock(ctx, fromSeqno-1)
+	if err != nil {
+		return nil, err
+	}
+	block, err := source.GetBlock(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ton.ShardIDs(&block) {
+		s.shardTops[id] = struct{}{}
+	}
+	return s, nil
+}
+
+// Cursor returns a seqno of the masterchain block that will be returned by the next call of Next.
+func (s *BlockSubscription) Cursor() uint32 {
+	return s.next
+}
+
+// Next waits for the next masterchain block and returns it with all new shard blocks.
+// If Next returns an error, it can be called again to retry the same block.
+func (s *BlockSubscription) Next(ctx context.Context) (BlockBatch, error) {
+	if err := s.source.waitMasterchainSeqno(ctx, s.next); err != nil {
+		return BlockBatch{}, err
+	}
+	blockID, err := s.source.lookupMasterchainBlock(ctx, s.next)
+	if err != nil {
+		return BlockBatch{}, err
+	}
+	block, err := s.source.GetBlock(ctx, blockID)
+	if err != nil {
+		return BlockBatch{}, err
+	}
+	tops := ton.ShardIDs(&block)
+	visited := make(map[ton.BlockIDExt]struct{}, len(s.shardTops))
+	for id := range s.shardTops {
+		visited[id] = struct{}{}
+	}
+	var shardBlocks []ton.BlockIDExt
+	for _, top := range tops {
+		shardBlocks, err = s.collectShardBlocks(ctx, top, visited, shardBlocks)
+		if err != nil {
+			return BlockBatch{}, err
+		}
+	}
+	s.shardTops = make(map[ton.BlockIDExt]struct{}, len(tops))
+	for _, id := range tops {
+		s.shardTops[id] = struct{}{}
+	}
+	s.next++
+	return BlockBatch{
+		Masterchain: blockID,
+		Block:       block,
+		ShardBlocks: shardBlocks,
+	}, nil
+}
+
+// collectShardBlocks walks back from the given shard block till already known blocks
+// and appends all new blocks to the result with parents going first.
+func (s *BlockSubscription) collectShardBlocks(ctx context.Context, blockID ton.BlockIDExt, visited map[ton.BlockIDExt]struct{}, res []ton.BlockIDExt) ([]ton.BlockIDExt, error) {
+	if _, ok := visited[blockID]; ok || blockID.Seqno == 0 {
+		return res, nil
+	}
+	visited[blockID] = struct{}{}
+	info, err := s.source.GetBlockHeader(ctx, blockID, 0)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := ton.GetParents(info)
+	if err != nil {
+		return nil, err
+	}
+	for _, parent := range parents {
+		res, err = s.collectShardBlocks(ctx, parent, visited, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(res, blockID), nil
+}
+
+// accountTransactionsSource contains all methods needed by an account transactions subscription.
+// used to implement tests.
+type accountTransactionsSource interface {
+	waitNextMasterchainBlock(ctx context.Context) error
+	GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error)
+	GetTransactions(ctx context.Context, count uint32, accountID ton.AccountID, lt uint64, hash ton.Bits256) ([]ton.Transaction, error)
+}
+
+func (c *Client) waitNextMasterchainBlock(ctx context.Context) error {
+	_, head, err := c.pool.BestMasterchainClient(ctx)
+	if err != nil {
+		return err
+	}
+	return c.waitMasterchainSeqno(ctx, head.Seqno+1)
+}
+
+// defaultTransactionsBatchSize limits a number of transactions returned by AccountTransactionsSubscription.Next.
+const defaultTransactionsBatchSize = 1000
+
+// AccountTransactionsSubscription streams transactions of an account in LT order.
+// Every transaction is delivered exactly once:
+// a new batch is built by following prev_trans_lt/prev_trans_hash links
+// from the last transaction of the account back to the last delivered one.
+// A batch contains at most 1000 transactions, so a long history is delivered in several batches.
+// Transactions that are gone from a regular lite server are requested from an archive node
+// if the client is configured with WithDetectArchiveNodes().
+type AccountTransactionsSubscription struct {
+	source    accountTransactionsSource
+	accountID ton.AccountID
+	lastLt    uint64
+	lastHash  ton.Bits256
+	batchSize int
+	// pending contains the latest transactions of batches that are found but not delivered yet,
+	// the next batch to deliver goes last.
+	pending []txCursor
+}
+
+type txCursor struct {
+	lt   uint64
+	hash ton.Bits256
+}
+
+// SubscribeAccountTransactions returns a subscription that delivers transactions of the account
+// following the transaction with the given lt and hash.
+// To get the whole history of the account, pass zero lt and hash.
+// The subscription must not be used with a client returned by WithBlock.
+func (c *Client) SubscribeAccountTransactions(ctx context.Context, accountID ton.AccountID, fromLt uint64, fromHash ton.Bits256) (*AccountTransactionsSubscription, error) {
+	return &AccountTransactionsSubscription{
+		source:    c,
+		accountID: accountID,
+		lastLt:    fromLt,
+		lastHash:  fromHash,
+		batchSize: defaultTransactionsBatchSize,
+	}, nil
+}
+
+// Cursor returns lt and hash of the last delivered transaction.
+// They can be passed to SubscribeAccountTransactions to resume the subscription.
+func (s *AccountTransactionsSubscription) Cursor() (uint64, ton.Bits256) {
+	return s.lastLt, s.lastHash
+}
+
+// Next waits for new transactions of the account and returns them sorted by LT in ascending order.
+// If Next returns an error, it can be called again and no transactions are lost.
+func (s *AccountTransactionsSubscription) Next(ctx context.Context) ([]ton.Transaction, error) {
+	for {
+		from := len(s.pending) - 1
+		if from < 0 {
+			state, err := s.source.GetAccountState(ctx, s.accountID)
+			if err != nil {
+				return nil, err
+			}
+			if state.LastTransLt <= s.lastLt {
+				if err := s.source.waitNextMasterchainBlock(ctx); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			s.pending = []txCursor{{lt: state.LastTransLt, hash: ton.Bits256(state.LastTransHash)}}
+			from = 0
+		}
+		txs, pending, err := s.collectTransactions(ctx, s.pending[from].lt, s.pending[from].hash)
+		if err != nil {
+			return nil, err
+		}
+		s.pending = append(s.pending[:from], pending...)
+		last := txs[len(txs)-1]
+		s.lastLt, s.lastHash = last.Lt, ton.Bits256(last.Hash())
+		return txs, nil
+	}
+}
+
+// collectTransactions walks back from the given transaction to the last delivered one.
+// It returns the oldest batch of transactions and the latest transactions of the rest of batches.
+func (s *AccountTransactionsSubscription) collectTransactions(ctx context.Context, lt uint64, hash ton.Bits256) ([]ton.Transaction, []txCursor, error) {
+	batchSize := s.batchSize
+	if batchSize <= 0 {
+		batchSize = defaultTransactionsBatchSize
+	}
+	var res []ton.Transaction
+	var pending []txCursor
+	for lt > s.lastLt {
+		txs, err := s.source.GetTransactions(ctx, maxTransactionCount, s.accountID, lt, hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(txs) == 0 {
+			return nil, nil, fmt.Errorf("transaction %v:%v not found", lt, hash.Hex())
+		}
+		for _, tx := range txs {
+			if tx.Lt != lt || ton.Bits256(tx.Hash()) != hash {
+				return nil, nil, fmt.Errorf("broken transaction chain at lt %v", lt)
+			}
+			if tx.Lt <= s.lastLt {
+				break
+			}
+			if len(res) == batchSize {
+				// only the latest transaction of a batch is kept to collect the batch again later.
+				pending = append(pending, txCursor{lt: res[0].Lt, hash: ton.Bits256(res[0].Hash())})
+				res = res[:0]
+			}
+			res = append(res, tx)
+			lt, hash = tx.PrevTransLt, ton.Bits256(tx.PrevTransHash)
+		}
+	}
+	if lt != s.lastLt || (lt != 0 && hash != s.lastHash) {
+		return nil, nil, fmt.Errorf("transaction chain doesn't lead to the last delivered transaction %v", s.lastLt)
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res, pending, nil
+}
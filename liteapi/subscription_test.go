@@ -0,0 +1,223 @@
+package liteapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+type mockBlockSource struct {
+	masterchain map[uint32]ton.BlockIDExt
+	blocks      map[ton.BlockIDExt]tlb.Block
+	headers     map[ton.BlockIDExt]tlb.BlockInfo
+}
+
+func (m *mockBlockSource) waitMasterchainSeqno(ctx context.Context, seqno uint32) error {
+	return nil
+}
+
+func (m *mockBlockSource) lookupMasterchainBlock(ctx context.Context, seqno uint32) (ton.BlockIDExt, error) {
+	id, ok := m.masterchain[seqno]
+	if !ok {
+		return ton.BlockIDExt{}, fmt.Errorf("block not found")
+	}
+	return id, nil
+}
+
+func (m *mockBlockSource) GetBlock(ctx context.Context, blockID ton.BlockIDExt) (tlb.Block, error) {
+	block, ok := m.blocks[blockID]
+	if !ok {
+		return tlb.Block{}, fmt.Errorf("block not found")
+	}
+	return block, nil
+}
+
+func (m *mockBlockSource) GetBlockHeader(ctx context.Context, blockID ton.BlockIDExt, mode uint32) (tlb.BlockInfo, error) {
+	info, ok := m.headers[blockID]
+	if !ok {
+		return tlb.BlockInfo{}, fmt.Errorf("header not found")
+	}
+	return info, nil
+}
+
+func shardBlockID(shard uint64, seqno uint32) ton.BlockIDExt {
+	return ton.BlockIDExt{
+		BlockID:  ton.BlockID{Workchain: 0, Shard: shard, Seqno: seqno},
+		RootHash: ton.Bits256{byte(shard >> 56), byte(seqno)},
+	}
+}
+
+func masterchainBlockWithShards(shards ...ton.BlockIDExt) tlb.Block {
+	var descrs []tlb.ShardDesc
+	for _, id := range shards {
+		var d tlb.ShardDesc
+		d.SumType = "New"
+		d.New.SeqNo = id.Seqno
+		d.New.RootHash = tlb.Bits256(id.RootHash)
+		d.New.FileHash = tlb.Bits256(id.FileHash)
+		d.New.NextValidatorShard = int64(id.Shard)
+		descrs = append(descrs, d)
+	}
+	var block tlb.Block
+	block.Extra.Custom.Exists = true
+	block.Extra.Custom.Value.Value.ShardHashes = tlb.NewHashmapE(
+		[]tlb.Uint32{0},
+		[]tlb.Ref[tlb.ShardInfoBinTree]{{Value: tlb.ShardInfoBinTree{BinTree: tlb.BinTree[tlb.ShardDesc]{Values: descrs}}}},
+	)
+	return block
+}
+
+func shardHeader(id ton.BlockIDExt, pfxBits int, prefix uint64, afterSplit bool, prev ton.BlockIDExt) tlb.BlockInfo {
+	var info tlb.BlockInfo
+	info.SeqNo = id.Seqno
+	info.AfterSplit = afterSplit
+	info.Shard = tlb.ShardIdent{ShardPfxBits: tlb.Uint6(pfxBits), WorkchainID: id.Workchain, ShardPrefix: prefix}
+	info.PrevRef.SumType = "PrevBlkInfo"
+	info.PrevRef.PrevBlkInfo = &struct{ Prev tlb.ExtBlkRef }{
+		Prev: tlb.ExtBlkRef{SeqNo: prev.Seqno, RootHash: tlb.Bits256(prev.RootHash), FileHash: tlb.Bits256(prev.FileHash)},
+	}
+	return info
+}
+
+func TestBlockSubscription_Next(t *testing.T) {
+	mc9 := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: 0x8000000000000000, Seqno: 9}}
+	mc10 := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: 0x8000000000000000, Seqno: 10}}
+	root10 := shardBlockID(0x8000000000000000, 10)
+	root11 := shardBlockID(0x8000000000000000, 11)
+	left12 := shardBlockID(0x4000000000000000, 12)
+	right12 := shardBlockID(0xc000000000000000, 12)
+
+	source := &mockBlockSource{
+		masterchain: map[uint32]ton.BlockIDExt{9: mc9, 10: mc10},
+		blocks: map[ton.BlockIDExt]tlb.Block{
+			mc9:  masterchainBlockWithShards(root10),
+			mc10: masterchainBlockWithShards(left12, right12),
+		},
+		headers: map[ton.BlockIDExt]tlb.BlockInfo{
+			root11:  shardHeader(root11, 0, 0, false, root10),
+			left12:  shardHeader(left12, 1, 0, true, root11),
+			right12: shardHeader(right12, 1, 0x8000000000000000, true, root11),
+		},
+	}
+	sub, err := newBlockSubscription(context.Background(), source, 10)
+	if err != nil {
+		t.Fatalf("newBlockSubscription() failed: %v", err)
+	}
+	batch, err := sub.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if batch.Masterchain != mc10 {
+		t.Fatalf("want masterchain block: %v, got: %v", mc10, batch.Masterchain)
+	}
+	want := []ton.BlockIDExt{root11, left12, right12}
+	if !reflect.DeepEqual(batch.ShardBlocks, want) {
+		t.Fatalf("want shard blocks: %v, got: %v", want, batch.ShardBlocks)
+	}
+	if sub.Cursor() != 11 {
+		t.Fatalf("want cursor: 11, got: %v", sub.Cursor())
+	}
+}
+
+type mockAccountTransactionsSource struct {
+	// txs are sorted by lt in descending order.
+	txs []ton.Transaction
+	// visible is a number of the oldest transactions that are already in the blockchain.
+	visible int
+	waits   int
+}
+
+func (m *mockAccountTransactionsSource) waitNextMasterchainBlock(ctx context.Context) error {
+	m.waits++
+	m.visible++
+	return nil
+}
+
+func (m *mockAccountTransactionsSource) GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error) {
+	if m.visible == 0 {
+		return tlb.ShardAccount{}, nil
+	}
+	last := m.txs[len(m.txs)-m.visible]
+	return tlb.ShardAccount{LastTransLt: last.Lt, LastTransHash: last.Hash()}, nil
+}
+
+func (m *mockAccountTransactionsSource) GetTransactions(ctx context.Context, count uint32, accountID ton.AccountID, lt uint64, hash ton.Bits256) ([]ton.Transaction, error) {
+	for i := range m.txs {
+		if m.txs[i].Lt == lt {
+			end := min(i+int(count), len(m.txs))
+			return m.txs[i:end], nil
+		}
+	}
+	return nil, nil
+}
+
+// transactionChain returns a chain of n transactions with lts 10, 20, ... sorted by lt in descending order.
+func transactionChain(n int) []ton.Transaction {
+	var txs []ton.Transaction
+	var prevLt uint64
+	var prevHash tlb.Bits256
+	for i := 1; i <= n; i++ {
+		var tx ton.Transaction
+		tx.Lt = uint64(i * 10)
+		tx.PrevTransLt = prevLt
+		tx.PrevTransHash = prevHash
+		prevLt, prevHash = tx.Lt, tx.Hash()
+		txs = append([]ton.Transaction{tx}, txs...)
+	}
+	return txs
+}
+
+func TestAccountTransactionsSubscription_Next(t *testing.T) {
+	source := &mockAccountTransactionsSource{txs: transactionChain(40), visible: 35}
+	sub := &AccountTransactionsSubscription{source: source}
+
+	got, err := sub.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if len(got) != 35 || got[0].Lt != 10 || got[34].Lt != 350 {
+		t.Fatalf("unexpected first batch: %v transactions", len(got))
+	}
+	got, err = sub.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Lt != 360 || source.waits != 1 {
+		t.Fatalf("unexpected second batch: %v transactions, %v waits", len(got), source.waits)
+	}
+	lt, hash := sub.Cursor()
+	if lt != 360 || hash != ton.Bits256(got[0].Hash()) {
+		t.Fatalf("unexpected cursor: %v %v", lt, hash)
+	}
+}
+
+func TestAccountTransactionsSubscription_Batches(t *testing.T) {
+	source := &mockAccountTransactionsSource{txs: transactionChain(40), visible: 35}
+	sub := &AccountTransactionsSubscription{source: source, batchSize: 10}
+
+	// the history is delivered from the oldest transaction in batches of at most 10 transactions.
+	for _, want := range [][2]uint64{{10, 50}, {60, 150}, {160, 250}, {260, 350}, {360, 360}} {
+		got, err := sub.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		if len(got) == 0 || len(got) > 10 || got[0].Lt != want[0] || got[len(got)-1].Lt != want[1] {
+			t.Fatalf("want batch from %v to %v, got %v transactions", want[0], want[1], len(got))
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].Lt != got[i-1].Lt+10 {
+				t.Fatalf("batch must contain consecutive transactions")
+			}
+		}
+		if lt, _ := sub.Cursor(); lt != want[1] {
+			t.Fatalf("want cursor %v, got %v", want[1], lt)
+		}
+	}
+	if source.waits != 1 {
+		t.Fatalf("want a single wait for a new transaction, got %v", source.waits)
+	}
+}
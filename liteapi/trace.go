@@ -0,0 +1,226 @@
+package liteapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// defaultTraceLimit limits a number of transactions in a trace.
+	defaultTraceLimit = 1000
+	// defaultTraceSearchDepth limits a number of transactions of an account
+	// looked through to find a transaction caused by a message.
+	defaultTraceSearchDepth = 1000
+)
+
+var (
+	// ErrTransactionNotFound is returned when there is no transaction caused by the given message.
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrTraceLimitExceeded is returned when a trace contains more transactions than allowed.
+	ErrTraceLimitExceeded = errors.New("trace limit exceeded")
+)
+
+// TxTree is a trace of transactions started by a single message.
+// It has the same TX and Children fields as txemulator.TxTree, so an emulated trace can be compared with the actual one.
+type TxTree struct {
+	TX       tlb.Transaction
+	Children []*TxTree
+	// Incomplete is set when a transaction caused by one of the outgoing messages of TX wasn't found,
+	// because the message hasn't been processed yet or the search depth was exceeded.
+	// The latter happens for old traces of busy accounts, take a look at WithTraceSearchDepth.
+	Incomplete bool
+}
+
+type TraceOptions struct {
+	// Limit is a maximum number of transactions in a trace.
+	Limit int
+	// SearchDepth is a maximum number of transactions of an account
+	// to look through when searching for a transaction caused by a message.
+	// Transactions are looked through starting from the latest one,
+	// so it limits how old a trace can be.
+	SearchDepth int
+}
+
+type TraceOption func(o *TraceOptions) error
+
+// WithTraceLimit sets a maximum number of transactions in a trace, 1000 by default.
+func WithTraceLimit(limit int) TraceOption {
+	return func(o *TraceOptions) error {
+		if limit <= 0 {
+			return fmt.Errorf("trace limit must be positive")
+		}
+		o.Limit = limit
+		return nil
+	}
+}
+
+// WithTraceSearchDepth sets a maximum number of transactions of a destination account
+// looked through to find a transaction caused by a message, 1000 by default.
+// An account's history is looked through backwards starting from its latest transaction,
+// so if a destination account has more than depth transactions after the one caused by a message,
+// the message is considered unprocessed and its source transaction is marked as Incomplete.
+// Increase the depth to reconstruct older traces at the cost of more requests.
+func WithTraceSearchDepth(depth int) TraceOption {
+	return func(o *TraceOptions) error {
+		if depth <= 0 {
+			return fmt.Errorf("trace search depth must be positive")
+		}
+		o.SearchDepth = depth
+		return nil
+	}
+}
+
+// traceSource contains all methods needed to reconstruct a trace.
+// used to implement tests.
+type traceSource interface {
+	GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error)
+	GetTransactions(ctx context.Context, count uint32, accountID ton.AccountID, lt uint64, hash ton.Bits256) ([]ton.Transaction, error)
+}
+
+// accountHistory is a cached part of account's transactions starting from the latest one.
+type accountHistory struct {
+	// txs are sorted by lt in descending order.
+	txs []ton.Transaction
+	// nextLt and nextHash point to the next older transaction to be fetched.
+	nextLt   uint64
+	nextHash ton.Bits256
+}
+
+type tracer struct {
+	source    traceSource
+	options   TraceOptions
+	histories map[ton.AccountID]*accountHistory
+	counter   int
+}
+
+// GetTrace reconstructs a trace started by the given transaction.
+// It follows internal outgoing messages to transactions they caused on destination accounts across all shards.
+// Messages that haven't been processed yet are skipped and their source transactions are marked as Incomplete,
+// so a trace of an ongoing chain is returned partially.
+// A transaction caused by a message is searched for in the latest transactions of the destination account
+// limited by WithTraceSearchDepth, so a part of an old trace can be reported as Incomplete too.
+func (c *Client) GetTrace(ctx context.Context, root tlb.Transaction, opts ...TraceOption) (*TxTree, error) {
+	t, err := newTracer(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	return t.trace(ctx, root)
+}
+
+// GetTraceByExternalMessage reconstructs a trace started by the external message with the given normalized hash.
+// The message is searched for in the latest transactions of the account limited by WithTraceSearchDepth,
+// ErrTransactionNotFound is returned if it isn't found there.
+func (c *Client) GetTraceByExternalMessage(ctx context.Context, accountID ton.AccountID, msgHash ton.Bits256, opts ...TraceOption) (*TxTree, error) {
+	t, err := newTracer(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	root, err := t.findTransaction(ctx, accountID, tlb.Bits256(msgHash), 0)
+	if err != nil {
+		return nil, err
+	}
+	return t.trace(ctx, root)
+}
+
+func newTracer(source traceSource, opts []TraceOption) (*tracer, error) {
+	options := TraceOptions{
+		Limit:       defaultTraceLimit,
+		SearchDepth: defaultTraceSearchDepth,
+	}
+	for _, o := range opts {
+		if err := o(&options); err != nil {
+			return nil, err
+		}
+	}
+	return &tracer{
+		source:    source,
+		options:   options,
+		histories: map[ton.AccountID]*accountHistory{},
+	}, nil
+}
+
+func (t *tracer) trace(ctx context.Context, tx tlb.Transaction) (*TxTree, error) {
+	t.counter++
+	if t.counter > t.options.Limit {
+		return nil, ErrTraceLimitExceeded
+	}
+	tree := &TxTree{TX: tx}
+	for _, ref := range tx.Msgs.OutMsgs.Values() {
+		msg := ref.Value
+		if msg.Info.SumType != "IntMsgInfo" {
+			continue
+		}
+		dest, err := ton.AccountIDFromTlb(msg.Info.IntMsgInfo.Dest)
+		if err != nil {
+			return nil, err
+		}
+		if dest == nil {
+			continue
+		}
+		child, err := t.findTransaction(ctx, *dest, msg.Hash(true), msg.Info.IntMsgInfo.CreatedLt)
+		if errors.Is(err, ErrTransactionNotFound) {
+			tree.Incomplete = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		subtree, err := t.trace(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		tree.Children = append(tree.Children, subtree)
+	}
+	return tree, nil
+}
+
+func inMsgHash(tx *tlb.Transaction) (tlb.Bits256, bool) {
+	if !tx.Msgs.InMsg.Exists {
+		return tlb.Bits256{}, false
+	}
+	return tx.Msgs.InMsg.Value.Value.Hash(true), true
+}
+
+// findTransaction looks for a transaction of the account caused by the message with the given hash.
+// The transaction must be newer than minLt.
+func (t *tracer) findTransaction(ctx context.Context, accountID ton.AccountID, msgHash tlb.Bits256, minLt uint64) (tlb.Transaction, error) {
+	history, ok := t.histories[accountID]
+	if !ok {
+		state, err := t.source.GetAccountState(ctx, accountID)
+		if err != nil {
+			return tlb.Transaction{}, err
+		}
+		history = &accountHistory{nextLt: state.LastTransLt, nextHash: ton.Bits256(state.LastTransHash)}
+		t.histories[accountID] = history
+	}
+	for i := 0; i < t.options.SearchDepth; i++ {
+		if i >= len(history.txs) {
+			if history.nextLt == 0 || history.nextLt <= minLt {
+				return tlb.Transaction{}, ErrTransactionNotFound
+			}
+			count := min(maxTransactionCount, t.options.SearchDepth-i)
+			txs, err := t.source.GetTransactions(ctx, uint32(count), accountID, history.nextLt, history.nextHash)
+			if err != nil {
+				return tlb.Transaction{}, err
+			}
+			if len(txs) == 0 {
+				return tlb.Transaction{}, fmt.Errorf("transaction %v of account %v not found", history.nextLt, accountID)
+			}
+			last := txs[len(txs)-1]
+			history.txs = append(history.txs, txs...)
+			history.nextLt, history.nextHash = last.PrevTransLt, ton.Bits256(last.PrevTransHash)
+		}
+		tx := history.txs[i]
+		if tx.Lt <= minLt {
+			return tlb.Transaction{}, ErrTransactionNotFound
+		}
+		if hash, ok := inMsgHash(&tx.Transaction); ok && hash == msgHash {
+			return tx.Transaction, nil
+		}
+	}
+	return tlb.Transaction{}, ErrTransactionNotFound
+}
@@ -0,0 +1,153 @@
+package liteapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+type mockTraceSource struct {
+	// histories contain transactions sorted by lt in descending order.
+	histories map[ton.AccountID][]ton.Transaction
+}
+
+func (m *mockTraceSource) GetAccountState(ctx context.Context, accountID ton.AccountID) (tlb.ShardAccount, error) {
+	txs := m.histories[accountID]
+	if len(txs) == 0 {
+		return tlb.ShardAccount{}, nil
+	}
+	return tlb.ShardAccount{LastTransLt: txs[0].Lt, LastTransHash: txs[0].Hash()}, nil
+}
+
+func (m *mockTraceSource) GetTransactions(ctx context.Context, count uint32, accountID ton.AccountID, lt uint64, hash ton.Bits256) ([]ton.Transaction, error) {
+	txs := m.histories[accountID]
+	for i := range txs {
+		if txs[i].Lt == lt {
+			return txs[i:min(i+int(count), len(txs))], nil
+		}
+	}
+	return nil, nil
+}
+
+func internalMessage(t *testing.T, dest ton.AccountID, createdLt uint64) tlb.Message {
+	var msg tlb.Message
+	msg.Info.SumType = "IntMsgInfo"
+	msg.Info.IntMsgInfo = &struct {
+		IhrDisabled bool
+		Bounce      bool
+		Bounced     bool
+		Src         tlb.MsgAddress
+		Dest        tlb.MsgAddress
+		Value       tlb.CurrencyCollection
+		IhrFee      tlb.VarUInteger16
+		FwdFee      tlb.Grams
+		CreatedLt   uint64
+		CreatedAt   uint32
+	}{
+		Src:       tlb.MsgAddress{SumType: "AddrNone"},
+		Dest:      dest.ToMsgAddress(),
+		CreatedLt: createdLt,
+	}
+	msg.Body.Value = tlb.Any(*boc.NewCell())
+	cell := boc.NewCell()
+	if err := tlb.Marshal(cell, msg); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	// unmarshal to get a message with its hash calculated.
+	var res tlb.Message
+	if err := tlb.Unmarshal(cell, &res); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	return res
+}
+
+func transaction(lt uint64, in *tlb.Message, out ...tlb.Message) ton.Transaction {
+	var tx ton.Transaction
+	tx.Lt = lt
+	if in != nil {
+		tx.Msgs.InMsg.Exists = true
+		tx.Msgs.InMsg.Value.Value = *in
+	}
+	var keys []tlb.Uint15
+	var values []tlb.Ref[tlb.Message]
+	for i, msg := range out {
+		keys = append(keys, tlb.Uint15(i))
+		values = append(values, tlb.Ref[tlb.Message]{Value: msg})
+	}
+	tx.Msgs.OutMsgs = tlb.NewHashmapE(keys, values)
+	return tx
+}
+
+func TestGetTrace(t *testing.T) {
+	a := ton.MustParseAccountID("0:1000000000000000000000000000000000000000000000000000000000000000")
+	b := ton.MustParseAccountID("0:2000000000000000000000000000000000000000000000000000000000000000")
+	c := ton.MustParseAccountID("0:3000000000000000000000000000000000000000000000000000000000000000")
+	d := ton.MustParseAccountID("0:4000000000000000000000000000000000000000000000000000000000000000")
+
+	toB := internalMessage(t, b, 101)
+	toC := internalMessage(t, c, 102)
+	toD := internalMessage(t, d, 103)
+	fromBToC := internalMessage(t, c, 201)
+
+	root := transaction(100, nil, toB, toC, toD)
+	txB := transaction(200, &toB, fromBToC)
+	txC1 := transaction(300, &toC)
+	txC2 := transaction(400, &fromBToC)
+	source := &mockTraceSource{
+		histories: map[ton.AccountID][]ton.Transaction{
+			a: {root},
+			b: {transaction(500, nil), txB, transaction(50, nil)},
+			c: {txC2, txC1},
+		},
+	}
+	tr, err := newTracer(source, nil)
+	if err != nil {
+		t.Fatalf("newTracer() failed: %v", err)
+	}
+	tree, err := tr.trace(context.Background(), root.Transaction)
+	if err != nil {
+		t.Fatalf("trace() failed: %v", err)
+	}
+	if len(tree.Children) != 2 {
+		t.Fatalf("want 2 children, got: %v", len(tree.Children))
+	}
+	if tree.Children[0].TX.Lt != 200 || tree.Children[1].TX.Lt != 300 {
+		t.Fatalf("unexpected children: %v, %v", tree.Children[0].TX.Lt, tree.Children[1].TX.Lt)
+	}
+	if len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].TX.Lt != 400 {
+		t.Fatalf("unexpected trace of account b")
+	}
+	if !tree.Incomplete {
+		t.Fatalf("trace must be incomplete because the message to account d isn't processed")
+	}
+	if tree.Children[0].Incomplete || tree.Children[1].Incomplete {
+		t.Fatalf("children must be complete")
+	}
+
+	// transactions caused by the messages to accounts b and c are the second ones of their histories.
+	tr, err = newTracer(source, []TraceOption{WithTraceSearchDepth(1)})
+	if err != nil {
+		t.Fatalf("newTracer() failed: %v", err)
+	}
+	tree, err = tr.trace(context.Background(), root.Transaction)
+	if err != nil {
+		t.Fatalf("trace() failed: %v", err)
+	}
+	if len(tree.Children) != 0 || !tree.Incomplete {
+		t.Fatalf("transactions deeper than the search depth must not be found")
+	}
+	if _, err := newTracer(source, []TraceOption{WithTraceSearchDepth(0)}); err == nil {
+		t.Fatalf("zero search depth must be rejected")
+	}
+
+	tr, err = newTracer(source, []TraceOption{WithTraceLimit(2)})
+	if err != nil {
+		t.Fatalf("newTracer() failed: %v", err)
+	}
+	if _, err := tr.trace(context.Background(), root.Transaction); err != ErrTraceLimitExceeded {
+		t.Fatalf("want ErrTraceLimitExceeded, got: %v", err)
+	}
+}
@@ -143,6 +143,14 @@ func (a Address) hash() []byte {
 	return h.Sum(nil)
 }
 
+// ShortID returns an ADNL address of the key.
+// It is equal to sha256 of the TL-serialized pub.ed25519 key.
+func (a Address) ShortID() ton.Bits256 {
+	var id ton.Bits256
+	copy(id[:], a.hash())
+	return id
+}
+
 // ADNL address is equal Address.hash()
 
 func ADNLAddressToBase32(addr ton.Bits256) string {
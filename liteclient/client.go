@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
@@ -37,6 +38,8 @@ type Client struct {
 	queries      map[queryID]chan []byte
 	queriesMutex sync.Mutex
 	metrics      RequestObserver
+	limiter      RateLimiter
+	recorder     Recorder
 }
 
 // RequestObserver is notified once for every lite server method call.
@@ -46,6 +49,12 @@ type RequestObserver interface {
 	ObserveRequest(host string, method RequestName, duration time.Duration, err error)
 }
 
+// RateLimiter throttles requests sent to a lite server.
+type RateLimiter interface {
+	// Wait blocks until a request is allowed to be sent or ctx is done.
+	Wait(ctx context.Context) error
+}
+
 type Options func(connection *Client)
 
 func OptionObserver(o RequestObserver) Options {
@@ -54,6 +63,26 @@ func OptionObserver(o RequestObserver) Options {
 	}
 }
 
+func OptionRateLimiter(l RateLimiter) Options {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// Recorder is notified of every liteServer.query sent to a lite server.
+// request and response are raw TL data of the query and of its answer.
+// If a lite server didn't answer, response is nil and err describes the failure.
+// Requests cancelled by a caller are not recorded.
+type Recorder interface {
+	RecordRequest(request []byte, response []byte, err error)
+}
+
+func OptionRecorder(r Recorder) Options {
+	return func(c *Client) {
+		c.recorder = r
+	}
+}
+
 func OptionTimeout(t time.Duration) Options {
 	return func(c *Client) {
 		c.timeout = t
@@ -115,6 +144,7 @@ func (c *Client) Request(ctx context.Context, q []byte) (resp []byte, err error)
 }
 
 func (c *Client) request(ctx context.Context, q []byte) (_ []byte, host string, _ error) {
+	parent := ctx
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 	var id queryID
@@ -129,6 +159,14 @@ func (c *Client) request(ctx context.Context, q []byte) (_ []byte, host string,
 	if err != nil {
 		return nil, host, newClientError("NewPacket() failed: %v", err)
 	}
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			if parent.Err() != nil {
+				return nil, host, newRequestCanceledError("rate limit", parent.Err())
+			}
+			return nil, host, newClientError("rate limit: %v", err)
+		}
+	}
 	resp := c.registerCallback(id)
 	defer c.unregisterCallback(id)
 
@@ -144,6 +182,9 @@ func (c *Client) request(ctx context.Context, q []byte) (_ []byte, host string,
 	}
 	select {
 	case <-ctx.Done():
+		if parent.Err() != nil {
+			return nil, host, newRequestCanceledError("request timeout", parent.Err())
+		}
 		return nil, host, newClientError("request timeout: %v", ctx.Err())
 	case b := <-resp:
 		return b, host, nil
@@ -205,7 +246,13 @@ func decodeLength(b []byte) (int, []byte, error) {
 }
 
 func (c *Client) reader(conn *Connection) {
-	for p := range conn.Responses() {
+	for {
+		var p Packet
+		select {
+		case <-conn.closed:
+			return
+		case p = <-conn.Responses():
+		}
 		if p.MagicType() != magicADNLAnswer {
 			continue
 		}
@@ -216,6 +263,14 @@ func (c *Client) reader(conn *Connection) {
 	}
 }
 
+// Close closes all connections of this client.
+// Requests that are in flight fail.
+func (c *Client) Close() {
+	for _, conn := range c.connections {
+		conn.Close()
+	}
+}
+
 func (c *Client) processQueryAnswer(p Packet) error {
 	if len(p.Payload) < 37 {
 		return fmt.Errorf("too short payload")
@@ -247,7 +302,11 @@ func (c *Client) liteServerRequest(ctx context.Context, q []byte) (_ []byte, hos
 	data = append(data, tl.EncodeLength(len(q))...)
 	data = append(data, q...)
 	data = alignBytes(data)
-	return c.request(ctx, data)
+	resp, host, err := c.request(ctx, data)
+	if c.recorder != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		c.recorder.RecordRequest(q, resp, err)
+	}
+	return resp, host, err
 }
 
 func alignBytes(data []byte) []byte {
@@ -41,6 +41,9 @@ type Connection struct {
 	resp             chan Packet
 	authKey          ed25519.PrivateKey
 	authCompleteChan chan error // Closes when auth is complete or error is sent
+	// closed is closed by Close to stop all goroutines of this connection.
+	closed    chan struct{}
+	closeOnce sync.Once
 
 	// mu protects all fields below.
 	mu           sync.Mutex
@@ -61,6 +64,7 @@ func NewConnection(ctx context.Context, peerPublicKey []byte, host string, authK
 		resp:             make(chan Packet),
 		status:           Connecting,
 		authCompleteChan: make(chan error),
+		closed:           make(chan struct{}),
 	}
 	if len(authKeys) == 1 {
 		c.authKey = authKeys[0]
@@ -127,6 +131,9 @@ func (c *Connection) reconnect() {
 	c.mu.Unlock()
 
 	for {
+		if c.isClosed() {
+			return
+		}
 		if err := c.setupEncryptedConnection(context.Background()); err != nil {
 			fmt.Printf("error reconnecting to %s: %s\n", c.host, err)
 			time.Sleep(1 * time.Second)
@@ -176,9 +183,16 @@ func (c *Connection) reader(packetCh chan Packet) {
 				continue
 			}
 
-			c.resp <- p
+			select {
+			case c.resp <- p:
+			case <-c.closed:
+				// keep reading till the underlying connection is closed.
+			}
 
 		case <-time.After(reconnectTimeout):
+			if c.isClosed() {
+				return
+			}
 			c.reconnect()
 			// setupEncryptedConnection will run another reader.
 			return
@@ -201,6 +215,28 @@ func (c *Connection) Send(p Packet) error {
 	return nil
 }
 
+// Close closes the connection to a lite server and stops reconnecting.
+func (c *Connection) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.status = Connecting
+		if c.econn != nil {
+			c.econn.close()
+		}
+	})
+}
+
+func (c *Connection) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *Connection) Responses() chan Packet {
 	return c.resp
 }
@@ -209,7 +245,11 @@ func (c *Connection) ping() {
 	ping := make([]byte, 12)
 	binary.LittleEndian.PutUint32(ping[:4], magicTCPPing)
 	for {
-		time.Sleep(time.Second * 3)
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(time.Second * 3):
+		}
 		if _, err := rand.Read(ping[4:]); err != nil {
 			panic(err) // impossible if source of randomness is correct
 		}
@@ -14,9 +14,28 @@ func newClientError(msg string, args ...interface{}) clientError {
 	return clientError(fmt.Sprintf(msg, args...))
 }
 
+// requestCanceledError is returned when a caller's context is done before a lite server answers.
+// Unlike a timeout of the client itself, it says nothing about a lite server,
+// and errors.Is reports it as context.Canceled or context.DeadlineExceeded.
+type requestCanceledError struct {
+	clientError
+	cause error
+}
+
+func (e requestCanceledError) Unwrap() error {
+	return e.cause
+}
+
+func newRequestCanceledError(msg string, cause error) requestCanceledError {
+	return requestCanceledError{clientError: newClientError("%v: %v", msg, cause), cause: cause}
+}
+
 func IsClientError(err error) bool {
-	_, ok := err.(clientError)
-	return ok
+	switch err.(type) {
+	case clientError, requestCanceledError:
+		return true
+	}
+	return false
 }
 
 func IsNotConnectedYet(e error) bool {
@@ -1,6 +1,7 @@
 package liteclient
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -30,6 +31,11 @@ func TestIsClientError(t *testing.T) {
 			err:  newClientError("method() failed"),
 			want: true,
 		},
+		{
+			name: "canceled request",
+			err:  newRequestCanceledError("request timeout", context.Canceled),
+			want: true,
+		},
 		{
 			name: "not client error",
 			err:  errors.New("some err"),
@@ -44,3 +50,13 @@ func TestIsClientError(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestCanceledError(t *testing.T) {
+	err := error(newRequestCanceledError("request timeout", context.DeadlineExceeded))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got: %v", err)
+	}
+	if errors.Is(newClientError("request timeout: %v", context.DeadlineExceeded), context.DeadlineExceeded) {
+		t.Fatalf("a timeout of the client itself must not be reported as a caller's deadline")
+	}
+}
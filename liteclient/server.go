@@ -0,0 +1,399 @@
+package liteclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	magicLiteServerError = 0xbba9e148 // crc32("liteServer.error code:int message:string = liteServer.Error")
+
+	// liteServerErrorCode is a code of a lite server error returned when a handler fails with a regular error.
+	liteServerErrorCode = 601
+	// handshakeTimeout bounds the time a client has to complete the ADNL handshake.
+	handshakeTimeout = 10 * time.Second
+)
+
+// ErrServerClosed is returned by Server.Serve after Server.Close.
+var ErrServerClosed = errors.New("liteclient: server closed")
+
+// Handler processes requests received by Server.
+type Handler interface {
+	// HandleRequest gets a request decoded by LiteapiRequestDecoder, like LiteServerGetMasterchainInfoRequest,
+	// and returns a response of the corresponding type, like LiteServerMasterchainInfoC.
+	// If the returned error is LiteServerErrorC, it is sent to the client as is,
+	// other errors are sent as a lite server error with their text.
+	HandleRequest(ctx context.Context, name RequestName, request any) (any, error)
+}
+
+// HandlerFunc is an adapter to use a function as Handler.
+type HandlerFunc func(ctx context.Context, name RequestName, request any) (any, error)
+
+func (f HandlerFunc) HandleRequest(ctx context.Context, name RequestName, request any) (any, error) {
+	return f(ctx, name, request)
+}
+
+// MasterchainSeqnoWaiter can be implemented by Handler to support liteServer.waitMasterchainSeqno prefix.
+// Without it, the prefix is ignored and a request is processed immediately.
+type MasterchainSeqnoWaiter interface {
+	WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout time.Duration) error
+}
+
+// RawHandler can be implemented by Handler to process raw TL data of liteServer.query.
+// If it returns false, a request is decoded and passed to HandleRequest.
+type RawHandler interface {
+	HandleRawRequest(ctx context.Context, request []byte) ([]byte, bool, error)
+}
+
+// responseTags maps response types to their TL tags.
+var responseTags = map[reflect.Type]uint32{
+	reflect.TypeOf(LiteProxyRequestRateLimitC{}):        0x14cb3f0c,
+	reflect.TypeOf(LiteServerAccountStateC{}):           0x7079c751,
+	reflect.TypeOf(LiteServerAllShardsInfoC{}):          0x098fe72d,
+	reflect.TypeOf(LiteServerBlockDataC{}):              0xa574ed6c,
+	reflect.TypeOf(LiteServerBlockHeaderC{}):            0x752d8219,
+	reflect.TypeOf(LiteServerBlockStateC{}):             0xabaddc0c,
+	reflect.TypeOf(LiteServerBlockTransactionsC{}):      0xbd8cad2b,
+	reflect.TypeOf(LiteServerBlockTransactionsExtC{}):   0xfb8ffce4,
+	reflect.TypeOf(LiteServerConfigInfoC{}):             0xae7b272f,
+	reflect.TypeOf(LiteServerCurrentTimeC{}):            0xe953000d,
+	reflect.TypeOf(LiteServerDispatchQueueInfoC{}):      0x5d1132d0,
+	reflect.TypeOf(LiteServerLibraryResultC{}):          0x117ab96b,
+	reflect.TypeOf(LiteServerLibraryResultWithProofC{}): 0x99370a1f,
+	reflect.TypeOf(LiteServerLookupBlockResultC{}):      0x57c7ccc5,
+	reflect.TypeOf(LiteServerMasterchainInfoC{}):        0x85832881,
+	reflect.TypeOf(LiteServerMasterchainInfoExtC{}):     0xa8cce0f5,
+	reflect.TypeOf(LiteServerOutMsgQueueSizesC{}):       0xf8504a03,
+	reflect.TypeOf(LiteServerPartialBlockProofC{}):      0x8ed0d2c1,
+	reflect.TypeOf(LiteServerRunMethodResultC{}):        0xa39a616b,
+	reflect.TypeOf(LiteServerSendMsgStatusC{}):          0x3950e597,
+	reflect.TypeOf(LiteServerShardBlockProofC{}):        0x1d62a07a,
+	reflect.TypeOf(LiteServerShardInfoC{}):              0x9fe6cd84,
+	reflect.TypeOf(LiteServerTransactionInfoC{}):        0x0edeed47,
+	reflect.TypeOf(LiteServerTransactionListC{}):        0x6f26c60b,
+	reflect.TypeOf(LiteServerValidatorStatsC{}):         0xb9f796d8,
+	reflect.TypeOf(LiteServerVersionC{}):                0x5a0491e5,
+	reflect.TypeOf(LiteServerErrorC{}):                  magicLiteServerError,
+}
+
+// Server accepts ADNL TCP connections from lite clients
+// and dispatches their requests to Handler.
+// It is meant for tests and local development:
+// it doesn't support authentication of clients and doesn't limit them.
+type Server struct {
+	key     ed25519.PrivateKey
+	handler Handler
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewServer returns a lite server identified by the given key.
+// Clients connect to it using the public part of the key.
+func NewServer(key ed25519.PrivateKey, handler Handler) *Server {
+	return &Server{
+		key:       key,
+		handler:   handler,
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// ListenAndServe listens on the TCP network address and serves incoming connections.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts incoming connections on the listener and serves them till Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.trackConn(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners and closes all connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	return nil
+}
+
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// serverConn is a server side of an encrypted connection.
+type serverConn struct {
+	conn     net.Conn
+	decipher cipher.Stream
+	// mu serializes writes because cipher is a stream.
+	mu     sync.Mutex
+	cipher cipher.Stream
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.untrackConn(conn)
+	defer conn.Close()
+
+	sc, err := s.handshake(conn)
+	if err != nil {
+		slog.Debug("liteclient.Server handshake failed", "remote", conn.RemoteAddr().String(), "error", err.Error())
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := bufio.NewReader(conn)
+	for {
+		p, err := ParsePacket(reader, sc.decipher)
+		if err != nil {
+			return
+		}
+		switch p.MagicType() {
+		case magicTCPPing:
+			if len(p.Payload) != 12 {
+				continue
+			}
+			pong := make([]byte, 12)
+			binary.LittleEndian.PutUint32(pong[:4], magicTCPPong)
+			copy(pong[4:], p.Payload[4:])
+			if err := sc.send(pong); err != nil {
+				return
+			}
+		case magicADNLQuery:
+			go s.processQuery(ctx, sc, p)
+		}
+	}
+}
+
+// handshake performs a server side of the ADNL TCP handshake
+// which is initiated by encryptedConn.handshake on the client side.
+func (s *Server) handshake(conn net.Conn) (*serverConn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return nil, err
+	}
+	req := make([]byte, 256)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	address, err := NewAddress(s.key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(req[:32], address.hash()) {
+		return nil, fmt.Errorf("unknown server address")
+	}
+	shared, err := sharedKey(s.key, req[32:64])
+	if err != nil {
+		return nil, err
+	}
+	hash := req[64:96]
+	key := append([]byte{}, shared[:16]...)
+	key = append(key, hash[16:32]...)
+	nonce := append([]byte{}, hash[0:4]...)
+	nonce = append(nonce, shared[20:32]...)
+	cipherKey, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	var param params
+	cipher.NewCTR(cipherKey, nonce).XORKeyStream(param[:], req[96:256])
+	if !bytes.Equal(param.hash(), hash) {
+		return nil, fmt.Errorf("invalid handshake params")
+	}
+	// the client transmits with its tx key and receives with its rx key.
+	ci, err := aes.NewCipher(param.rxKey())
+	if err != nil {
+		return nil, err
+	}
+	dci, err := aes.NewCipher(param.txKey())
+	if err != nil {
+		return nil, err
+	}
+	sc := &serverConn{
+		conn:     conn,
+		cipher:   cipher.NewCTR(ci, param.rxNonce()),
+		decipher: cipher.NewCTR(dci, param.txNonce()),
+	}
+	// an empty packet confirms the handshake.
+	if err := sc.send(nil); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (sc *serverConn) send(payload []byte) error {
+	p, err := NewPacket(payload)
+	if err != nil {
+		return err
+	}
+	b := p.marshal()
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.cipher.XORKeyStream(b, b)
+	_, err = sc.conn.Write(b)
+	return err
+}
+
+// processQuery handles adnl.message.query and sends adnl.message.answer back.
+func (s *Server) processQuery(ctx context.Context, sc *serverConn, p Packet) {
+	if len(p.Payload) < 37 {
+		return
+	}
+	var id queryID
+	copy(id[:], p.Payload[4:36])
+	length, data, err := decodeLength(p.Payload[36:])
+	if err != nil || len(data) < length {
+		return
+	}
+	answer, err := s.processLiteServerQuery(ctx, data[:length])
+	if err != nil {
+		answer, err = encodeError(err)
+		if err != nil {
+			return
+		}
+	}
+	payload := make([]byte, 4, 44+len(answer))
+	binary.LittleEndian.PutUint32(payload, magicADNLAnswer)
+	payload = append(payload, id[:]...)
+	payload = append(payload, tl.EncodeLength(len(answer))...)
+	payload = append(payload, answer...)
+	payload = alignBytes(payload)
+	if err := sc.send(payload); err != nil {
+		slog.Debug("liteclient.Server send failed", "error", err.Error())
+	}
+}
+
+// processLiteServerQuery handles liteServer.query and returns a serialized response.
+func (s *Server) processLiteServerQuery(ctx context.Context, q []byte) ([]byte, error) {
+	if len(q) < 4 || binary.LittleEndian.Uint32(q[:4]) != magicLiteServerQuery {
+		return nil, fmt.Errorf("unsupported query")
+	}
+	length, data, err := decodeLength(q[4:])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < length {
+		return nil, fmt.Errorf("payload is smaller than should be according to length")
+	}
+	data = data[:length]
+	if raw, ok := s.handler.(RawHandler); ok {
+		response, handled, err := raw.HandleRawRequest(ctx, data)
+		if handled || err != nil {
+			return response, err
+		}
+	}
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data[:4]) == magicLiteServerWaitMasterchainSeqno {
+		seqno := binary.LittleEndian.Uint32(data[4:8])
+		timeout := time.Duration(binary.LittleEndian.Uint32(data[8:12])) * time.Millisecond
+		if waiter, ok := s.handler.(MasterchainSeqnoWaiter); ok {
+			if err := waiter.WaitMasterchainSeqno(ctx, seqno, timeout); err != nil {
+				return nil, err
+			}
+		}
+		data = data[12:]
+		if len(data) == 0 {
+			// a standalone liteServer.waitMasterchainSeqno is answered with a zero error code.
+			return encodeResponse(LiteServerErrorC{})
+		}
+	}
+	_, name, request, err := LiteapiRequestDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, fmt.Errorf("unknown request")
+	}
+	response, err := s.handler.HandleRequest(ctx, *name, request)
+	if err != nil {
+		return nil, err
+	}
+	return encodeResponse(response)
+}
+
+func encodeResponse(response any) ([]byte, error) {
+	tag, ok := responseTags[reflect.TypeOf(response)]
+	if !ok {
+		return nil, fmt.Errorf("unknown response type %T", response)
+	}
+	b, err := tl.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, tag), b...), nil
+}
+
+func encodeError(err error) ([]byte, error) {
+	var liteServerErr LiteServerErrorC
+	if !errors.As(err, &liteServerErr) {
+		liteServerErr = LiteServerErrorC{Code: liteServerErrorCode, Message: err.Error()}
+	}
+	return encodeResponse(liteServerErr)
+}
@@ -0,0 +1,79 @@
+package liteclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startTestServer(t *testing.T, handler Handler) *Client {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := NewServer(key, handler)
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+
+	c, err := NewConnection(context.Background(), pub, l.Addr().String())
+	require.Nil(t, err)
+	client := NewClient(c, OptionTimeout(5*time.Second))
+	t.Cleanup(client.Close)
+	return client
+}
+
+type waiterHandler struct {
+	HandlerFunc
+	seqno uint32
+}
+
+func (h *waiterHandler) WaitMasterchainSeqno(ctx context.Context, seqno uint32, timeout time.Duration) error {
+	h.seqno = seqno
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	info := LiteServerMasterchainInfoC{
+		Last: TonNodeBlockIdExtC{Workchain: 0xffffffff, Shard: 0x8000000000000000, Seqno: 100},
+	}
+	handler := &waiterHandler{
+		HandlerFunc: func(ctx context.Context, name RequestName, request any) (any, error) {
+			switch name {
+			case LiteServerGetMasterchainInfoRequestName:
+				return info, nil
+			case LiteServerGetTimeRequestName:
+				return nil, errors.New("time is unknown")
+			case LiteServerLookupBlockRequestName:
+				req := request.(LiteServerLookupBlockRequest)
+				return LiteServerBlockHeaderC{Id: TonNodeBlockIdExtC{Seqno: req.Id.Seqno}}, nil
+			}
+			return nil, LiteServerErrorC{Code: 651, Message: "not found"}
+		},
+	}
+	client := startTestServer(t, handler)
+	ctx := context.Background()
+
+	res, err := client.LiteServerGetMasterchainInfo(ctx)
+	require.Nil(t, err)
+	require.Equal(t, info, res)
+
+	_, err = client.LiteServerGetTime(ctx)
+	require.Equal(t, LiteServerErrorC{Code: liteServerErrorCode, Message: "time is unknown"}, err)
+
+	_, err = client.LiteServerGetVersion(ctx)
+	require.Equal(t, LiteServerErrorC{Code: 651, Message: "not found"}, err)
+
+	header, err := client.WaitMasterchainBlock(ctx, 101, 1000)
+	require.Nil(t, err)
+	require.Equal(t, uint32(101), header.Id.Seqno)
+	require.Equal(t, uint32(101), handler.seqno)
+
+	require.Nil(t, client.WaitMasterchainSeqno(ctx, 102, 1000))
+	require.Equal(t, uint32(102), handler.seqno)
+}
@@ -62,3 +62,23 @@ func (i *Int256) UnmarshalJSON(data []byte) error {
 	copy(i[:], b)
 	return nil
 }
+
+type Int128 [16]byte
+
+func (i Int128) MarshalTL() ([]byte, error) {
+	return i[:], nil
+}
+
+func (i *Int128) UnmarshalTL(r io.Reader) error {
+	var b [16]byte
+	_, err := io.ReadFull(r, b[:])
+	if err != nil {
+		return err
+	}
+	*i = b
+	return nil
+}
+
+func (i Int128) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(i[:]))
+}
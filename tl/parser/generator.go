@@ -222,7 +222,7 @@ func (g *Generator) generateGolangStruct(declaration CombinatorDeclaration) (str
 		}
 
 		optional := false
-		if field.Modificator.Name == "mode" { // mode.0?field
+		if field.Modificator.Name != "" { // mode.0?field or flags.0?field
 			optional = true
 		}
 
@@ -15,6 +15,8 @@ type Decoder struct {
 	withDebug  bool
 	debugPath  []string
 	resolveLib resolveLib
+
+	lazyShardAccounts bool
 }
 
 func (d *Decoder) WithDebug() *Decoder {
@@ -28,6 +30,14 @@ func (d *Decoder) WithLibraryResolver(resolveLib resolveLib) *Decoder {
 	return d
 }
 
+// WithLazyShardAccounts makes the decoder skip decoding ShardStateUnsplitData.Accounts,
+// so a shard state with millions of accounts is decoded quickly.
+// Accounts of such a state are available via ShardStateUnsplitData.ShardAccounts.
+func (d *Decoder) WithLazyShardAccounts() *Decoder {
+	d.lazyShardAccounts = true
+	return d
+}
+
 // NewDecoder returns a new Decoder.
 func NewDecoder() *Decoder {
 	return &Decoder{
@@ -240,6 +240,99 @@ func ProveKeyInHashmap[T any](prover *boc.MerkleProver, cell *boc.Cell, key boc.
 
 }
 
+// ErrPrunedBranch is returned when a lookup in a merkle proof reaches a pruned branch
+// before the key is proven to be present or absent.
+var ErrPrunedBranch = errors.New("hashmap branch is pruned")
+
+// FindKeyInProvenHashmap looks up the key in a Hashmap or HashmapAug
+// whose cells can be a part of a merkle proof.
+// If the key is present, it returns the leaf cell positioned right after the leaf's label.
+// If the proof shows that the key is absent, it returns false.
+func FindKeyInProvenHashmap(cell *boc.Cell, key boc.BitString) (*boc.Cell, bool, error) {
+	remaining := key.BitsAvailableForRead()
+	for {
+		if cell.CellType() == boc.PrunedBranchCell {
+			return nil, false, ErrPrunedBranch
+		}
+		label := boc.NewBitString(remaining)
+		size, _, err := loadLabel(remaining, cell, &label)
+		if err != nil {
+			return nil, false, err
+		}
+		for i := 0; i < size; i++ {
+			labelBit, err := label.ReadBit()
+			if err != nil {
+				return nil, false, err
+			}
+			keyBit, err := key.ReadBit()
+			if err != nil {
+				return nil, false, err
+			}
+			if labelBit != keyBit {
+				return nil, false, nil
+			}
+		}
+		remaining -= size
+		if remaining == 0 {
+			return cell, true, nil
+		}
+		isRight, err := key.ReadBit()
+		if err != nil {
+			return nil, false, err
+		}
+		remaining--
+		left, err := cell.NextRef()
+		if err != nil {
+			return nil, false, err
+		}
+		right, err := cell.NextRef()
+		if err != nil {
+			return nil, false, err
+		}
+		cell = left
+		if isRight {
+			cell = right
+		}
+		cell.ResetCounters()
+	}
+}
+
+// WalkProvenHashmap walks a Hashmap or HashmapAug whose cells can be a part of a merkle proof.
+// leaf is called for every leaf with its key and the leaf cell positioned right after the leaf's label.
+// pruned is called for every pruned branch with a prefix shared by all keys of the branch.
+func WalkProvenHashmap(cell *boc.Cell, keySize int, leaf func(key boc.BitString, c *boc.Cell) error, pruned func(prefix boc.BitString) error) error {
+	return walkProvenHashmap(cell, keySize, boc.NewBitString(keySize), leaf, pruned)
+}
+
+func walkProvenHashmap(cell *boc.Cell, remaining int, prefix boc.BitString, leaf func(key boc.BitString, c *boc.Cell) error, pruned func(prefix boc.BitString) error) error {
+	if cell.CellType() == boc.PrunedBranchCell {
+		return pruned(prefix)
+	}
+	size, _, err := loadLabel(remaining, cell, &prefix)
+	if err != nil {
+		return err
+	}
+	remaining -= size
+	if remaining == 0 {
+		return leaf(prefix, cell)
+	}
+	for _, isRight := range []bool{false, true} {
+		next, err := cell.NextRef()
+		if err != nil {
+			return err
+		}
+		next.ResetCounters()
+		nextPrefix := prefix.Copy()
+		if err := nextPrefix.WriteBit(isRight); err != nil {
+			return err
+		}
+		if err := walkProvenHashmap(next, remaining-1, nextPrefix, leaf, pruned); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *Hashmap[keyT, T]) mapInner(keySize, leftKeySize int, c *boc.Cell, keyPrefix *boc.BitString, decoder *Decoder) error {
 	var err error
 	var size int
@@ -426,7 +519,14 @@ func encodeLabel(c *boc.Cell, keyFirst, keyLast *boc.BitString, keySize int) (bo
 	}
 	keyFirst.ResetCounter()
 	keyLast.ResetCounter()
+	if err := writeLabel(c, label, keySize); err != nil {
+		return boc.BitString{}, err
+	}
+	return label, nil
+}
 
+// writeLabel writes the label in the most compact HmLabel form.
+func writeLabel(c *boc.Cell, label boc.BitString, keySize int) error {
 	labelLen := label.BitsAvailableForRead()
 
 	// We must find the most compact way to serialize key
@@ -447,7 +547,7 @@ func encodeLabel(c *boc.Cell, keyFirst, keyLast *boc.BitString, keySize int) (bo
 	for label.BitsAvailableForRead() > 0 {
 		bit, err := label.ReadBit()
 		if err != nil {
-			return boc.BitString{}, err
+			return err
 		}
 		if bit {
 			isAllZero = false
@@ -465,11 +565,7 @@ func encodeLabel(c *boc.Cell, keyFirst, keyLast *boc.BitString, keySize int) (bo
 		}
 	}
 
-	err := encodeFunc(c, keySize, label)
-	if err != nil {
-		return boc.BitString{}, err
-	}
-	return label, nil
+	return encodeFunc(c, keySize, label)
 }
 
 func encodeShortLabel(c *boc.Cell, keySize int, label boc.BitString) error {
@@ -1,6 +1,7 @@
 package tlb
 
 import (
+	"errors"
 	"os"
 	"reflect"
 	"testing"
@@ -103,3 +104,181 @@ func Benchmark_HashmapAug_Unmarshal(b *testing.B) {
 		}
 	}
 }
+
+func bitString(t *testing.T, s string) boc.BitString {
+	b := boc.NewBitString(len(s))
+	for _, c := range s {
+		if err := b.WriteBit(c == '1'); err != nil {
+			t.Fatalf("WriteBit() failed: %v", err)
+		}
+	}
+	return b
+}
+
+func TestPfxHashmapE(t *testing.T) {
+	single := NewPfxHashmapE[InternalAddress, struct{}]([]boc.BitString{bitString(t, "1")}, []struct{}{{}})
+	cell := boc.NewCell()
+	if err := Marshal(cell, single); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	root, err := cell.NextRef()
+	if err != nil {
+		t.Fatalf("NextRef() failed: %v", err)
+	}
+	// hml_short$0 len:10 s:1 phmn_leaf$0
+	if got := root.RawBitString(); got.BinaryString() != "01010" {
+		t.Fatalf("unexpected serialization: %v", got.BinaryString())
+	}
+
+	keys := []string{"10011111111", "100000000000101", "1000000000011"}
+	m := NewPfxHashmapE[InternalAddress, Uint8](
+		[]boc.BitString{bitString(t, keys[0]), bitString(t, keys[1]), bitString(t, keys[2])},
+		[]Uint8{1, 2, 3})
+	cell = boc.NewCell()
+	if err := Marshal(cell, m); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	cell.ResetCounters()
+	var decoded PfxHashmapE[InternalAddress, Uint8]
+	if err := Unmarshal(cell, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	got := map[string]Uint8{}
+	for i, key := range decoded.Keys() {
+		got[key.BinaryString()] = decoded.Values()[i]
+	}
+	want := map[string]Uint8{keys[0]: 1, keys[1]: 2, keys[2]: 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	if v, ok := decoded.LookupPrefix(bitString(t, keys[0]+"0101")); !ok || v != 1 {
+		t.Fatalf("LookupPrefix() failed")
+	}
+	if _, ok := decoded.LookupPrefix(bitString(t, "1000000000")); ok {
+		t.Fatalf("LookupPrefix() must not match a shorter key")
+	}
+
+	conflict := NewPfxHashmapE[InternalAddress, Uint8]([]boc.BitString{bitString(t, "10"), bitString(t, "101")}, []Uint8{1, 2})
+	if err := Marshal(boc.NewCell(), conflict); err == nil {
+		t.Fatalf("a key which is a prefix of another one must fail")
+	}
+}
+
+func TestFindKeyInProvenHashmap(t *testing.T) {
+	keys := []Uint32{1, 2, 3, 100, 1000}
+	values := []Uint32{10, 20, 30, 1000, 10000}
+	hashmap := NewHashmap(keys, values)
+	cell := boc.NewCell()
+	if err := Marshal(cell, hashmap); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	key := func(k uint32) boc.BitString {
+		bs := boc.NewBitString(32)
+		if err := bs.WriteUint(uint64(k), 32); err != nil {
+			t.Fatalf("WriteUint() failed: %v", err)
+		}
+		return bs
+	}
+	find := func(root *boc.Cell, k uint32) (uint32, bool, error) {
+		root.ResetCounters()
+		leaf, found, err := FindKeyInProvenHashmap(root, key(k))
+		if err != nil || !found {
+			return 0, found, err
+		}
+		var v Uint32
+		if err := Unmarshal(leaf, &v); err != nil {
+			t.Fatalf("Unmarshal() failed: %v", err)
+		}
+		return uint32(v), true, nil
+	}
+	for i, k := range keys {
+		v, found, err := find(cell, uint32(k))
+		if err != nil || !found || v != uint32(values[i]) {
+			t.Fatalf("key %v: want %v, got %v, %v, %v", k, values[i], v, found, err)
+		}
+	}
+	if _, found, err := find(cell, 4); err != nil || found {
+		t.Fatalf("key 4 must be absent, got %v, %v", found, err)
+	}
+
+	prover, err := boc.NewMerkleProver(cell)
+	if err != nil {
+		t.Fatalf("NewMerkleProver() failed: %v", err)
+	}
+	cell.ResetCounters()
+	_, proofBoc, err := ProveKeyInHashmap[Uint32](prover, cell, key(100))
+	if err != nil {
+		t.Fatalf("ProveKeyInHashmap() failed: %v", err)
+	}
+	proof, err := boc.DeserializeBoc(proofBoc)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	hash, err := cell.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	root, err := boc.VerifyMerkleProof(proof[0], hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	if v, found, err := find(root, 100); err != nil || !found || v != 1000 {
+		t.Fatalf("key 100: want 1000, got %v, %v, %v", v, found, err)
+	}
+	if _, _, err := find(root, 1); !errors.Is(err, ErrPrunedBranch) {
+		t.Fatalf("key 1: want ErrPrunedBranch, got %v", err)
+	}
+}
+
+func TestWalkProvenHashmap(t *testing.T) {
+	hashmap := NewHashmap([]Uint32{1, 2, 3, 100}, []Uint32{10, 20, 30, 1000})
+	cell := boc.NewCell()
+	if err := Marshal(cell, hashmap); err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	// prune the right branch of the root fork
+	prover, err := boc.NewMerkleProver(cell)
+	if err != nil {
+		t.Fatalf("NewMerkleProver() failed: %v", err)
+	}
+	cursor := prover.Cursor()
+	cursor.Ref(1).Prune()
+	proofBoc, err := prover.CreateProof(cursor)
+	if err != nil {
+		t.Fatalf("CreateProof() failed: %v", err)
+	}
+	proof, err := boc.DeserializeBoc(proofBoc)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	hash, err := cell.Hash256()
+	if err != nil {
+		t.Fatalf("Hash256() failed: %v", err)
+	}
+	root, err := boc.VerifyMerkleProof(proof[0], hash)
+	if err != nil {
+		t.Fatalf("VerifyMerkleProof() failed: %v", err)
+	}
+	var (
+		keys     []uint64
+		prefixes []string
+	)
+	err = WalkProvenHashmap(root, 32, func(key boc.BitString, c *boc.Cell) error {
+		k, err := key.ReadUint(32)
+		keys = append(keys, k)
+		return err
+	}, func(prefix boc.BitString) error {
+		prefixes = append(prefixes, prefix.BinaryString())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkProvenHashmap() failed: %v", err)
+	}
+	// keys 1, 2, 3 are 0...0xx and 100 is 0...01100100, the root fork splits them by the 26th bit
+	if !reflect.DeepEqual(keys, []uint64{1, 2, 3}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	if !reflect.DeepEqual(prefixes, []string{"00000000000000000000000001"}) {
+		t.Fatalf("unexpected pruned prefixes: %v", prefixes)
+	}
+}
@@ -0,0 +1,175 @@
+package tlb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+// PfxHashmapE is a prefix dictionary with keys of variable length up to the size of keyT.
+// No key of a prefix dictionary is a prefix of another key.
+//
+// phme_empty$0 {n:#} {X:Type} = PfxHashmapE n X;
+// phme_root$1 {n:#} {X:Type} root:^(PfxHashmap n X) = PfxHashmapE n X;
+// phm_edge#_ {n:#} {X:Type} {l:#} {m:#} label:(HmLabel ~l n) {n = (~m) + l} node:(PfxHashmapNode m X) = PfxHashmap n X;
+// phmn_leaf$0 {n:#} {X:Type} value:X = PfxHashmapNode n X;
+// phmn_fork$1 {n:#} {X:Type} left:^(PfxHashmap n X) right:^(PfxHashmap n X) = PfxHashmapNode (n + 1) X;
+type PfxHashmapE[keyT fixedSize, T any] struct {
+	keys   []boc.BitString
+	values []T
+}
+
+// NewPfxHashmapE returns a new instance of PfxHashmapE.
+// Make sure that a key at index "i" corresponds to a value at the same index.
+func NewPfxHashmapE[keyT fixedSize, T any](keys []boc.BitString, values []T) PfxHashmapE[keyT, T] {
+	return PfxHashmapE[keyT, T]{keys: keys, values: values}
+}
+
+// Keys returns a list of keys of this dictionary.
+func (h PfxHashmapE[keyT, T]) Keys() []boc.BitString {
+	return h.keys
+}
+
+// Values returns a list of values of this dictionary.
+func (h PfxHashmapE[keyT, T]) Values() []T {
+	return h.values
+}
+
+// LookupPrefix returns a value stored by a key which is a prefix of the given bit string.
+func (h PfxHashmapE[keyT, T]) LookupPrefix(s boc.BitString) (T, bool) {
+	str := s.BinaryString()
+	for i, key := range h.keys {
+		if strings.HasPrefix(str, key.BinaryString()) {
+			return h.values[i], true
+		}
+	}
+	var value T
+	return value, false
+}
+
+func (h PfxHashmapE[keyT, T]) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	if len(h.keys) == 0 {
+		return c.WriteBit(false)
+	}
+	if err := c.WriteBit(true); err != nil {
+		return err
+	}
+	var key keyT
+	keySize := key.FixedSize()
+	keys := make([]string, len(h.keys))
+	for i, k := range h.keys {
+		keys[i] = k.BinaryString()
+		if len(keys[i]) > keySize {
+			return fmt.Errorf("key length %v exceeds %v bits", len(keys[i]), keySize)
+		}
+	}
+	root := boc.NewCell()
+	if err := encodePfxMap(root, keys, h.values, keySize, encoder); err != nil {
+		return err
+	}
+	return c.AddRef(root)
+}
+
+func encodePfxMap[T any](c *boc.Cell, keys []string, values []T, keySize int, encoder *Encoder) error {
+	prefix := keys[0]
+	for _, key := range keys[1:] {
+		n := 0
+		for n < len(prefix) && n < len(key) && prefix[n] == key[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	label := boc.NewBitString(keySize)
+	for _, bit := range prefix {
+		if err := label.WriteBit(bit == '1'); err != nil {
+			return err
+		}
+	}
+	if err := writeLabel(c, label, keySize); err != nil {
+		return err
+	}
+	if len(keys) == 1 {
+		if err := c.WriteBit(false); err != nil {
+			return err
+		}
+		return encoder.Marshal(c, values[0])
+	}
+	if err := c.WriteBit(true); err != nil {
+		return err
+	}
+	var branches [2]struct {
+		keys   []string
+		values []T
+	}
+	for i, key := range keys {
+		if len(key) == len(prefix) {
+			return fmt.Errorf("key %v is a prefix of another key", key)
+		}
+		b := key[len(prefix)] - '0'
+		branches[b].keys = append(branches[b].keys, key[len(prefix)+1:])
+		branches[b].values = append(branches[b].values, values[i])
+	}
+	for _, branch := range branches {
+		ref := boc.NewCell()
+		if err := encodePfxMap(ref, branch.keys, branch.values, keySize-len(prefix)-1, encoder); err != nil {
+			return err
+		}
+		if err := c.AddRef(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *PfxHashmapE[keyT, T]) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
+	h.keys, h.values = nil, nil
+	exists, err := c.ReadBit()
+	if err != nil || !exists {
+		return err
+	}
+	root, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	var key keyT
+	keySize := key.FixedSize()
+	return h.decodePfxMap(root, keySize, boc.NewBitString(keySize), decoder)
+}
+
+func (h *PfxHashmapE[keyT, T]) decodePfxMap(c *boc.Cell, keySize int, prefix boc.BitString, decoder *Decoder) error {
+	size, key, err := loadLabel(keySize, c, &prefix)
+	if err != nil {
+		return err
+	}
+	isFork, err := c.ReadBit()
+	if err != nil {
+		return err
+	}
+	if !isFork {
+		var value T
+		if err := decoder.Unmarshal(c, &value); err != nil {
+			return err
+		}
+		h.keys = append(h.keys, key.Copy())
+		h.values = append(h.values, value)
+		return nil
+	}
+	if keySize-size < 1 {
+		return fmt.Errorf("prefix dictionary fork exceeds key size")
+	}
+	for _, bit := range []bool{false, true} {
+		ref, err := c.NextRef()
+		if err != nil {
+			return err
+		}
+		next := key.Copy()
+		if err := next.WriteBit(bit); err != nil {
+			return err
+		}
+		if err := h.decodePfxMap(ref, keySize-size-1, next, decoder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -59,6 +59,115 @@ type ShardStateUnsplitData struct {
 	Accounts        HashmapAugE[Bits256, ShardAccount, DepthBalanceInfo] `tlb:"^"`
 	Other           ShardStateUnsplitOther                               `tlb:"^"`
 	Custom          Maybe[Ref[McStateExtra]]
+
+	shardAccounts ShardAccounts
+}
+
+func (s *ShardStateUnsplitData) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
+	var head struct {
+		GlobalID        int32
+		ShardID         ShardIdent
+		SeqNo           uint32
+		VertSeqNo       uint32
+		GenUtime        uint32
+		GenLt           uint64
+		MinRefMcSeqno   uint32
+		OutMsgQueueInfo OutMsgQueueInfo `tlb:"^"`
+		BeforeSplit     bool
+	}
+	if err := decoder.Unmarshal(c, &head); err != nil {
+		return err
+	}
+	accounts, err := c.NextRef()
+	if err != nil {
+		return err
+	}
+	*s = ShardStateUnsplitData{
+		GlobalID:        head.GlobalID,
+		ShardID:         head.ShardID,
+		SeqNo:           head.SeqNo,
+		VertSeqNo:       head.VertSeqNo,
+		GenUtime:        head.GenUtime,
+		GenLt:           head.GenLt,
+		MinRefMcSeqno:   head.MinRefMcSeqno,
+		OutMsgQueueInfo: head.OutMsgQueueInfo,
+		BeforeSplit:     head.BeforeSplit,
+	}
+	if accounts.CellType() != boc.PrunedBranchCell {
+		if err := decoder.Unmarshal(accounts, &s.shardAccounts); err != nil {
+			return err
+		}
+		if !decoder.lazyShardAccounts {
+			accounts.ShallowResetCounters()
+			if err := decoder.Unmarshal(accounts, &s.Accounts); err != nil {
+				return err
+			}
+		}
+	}
+	var tail struct {
+		Other  ShardStateUnsplitOther `tlb:"^"`
+		Custom Maybe[Ref[McStateExtra]]
+	}
+	if err := decoder.Unmarshal(c, &tail); err != nil {
+		return err
+	}
+	s.Other = tail.Other
+	s.Custom = tail.Custom
+	return nil
+}
+
+func (s ShardStateUnsplitData) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	head := struct {
+		GlobalID        int32
+		ShardID         ShardIdent
+		SeqNo           uint32
+		VertSeqNo       uint32
+		GenUtime        uint32
+		GenLt           uint64
+		MinRefMcSeqno   uint32
+		OutMsgQueueInfo OutMsgQueueInfo `tlb:"^"`
+		BeforeSplit     bool
+	}{
+		GlobalID:        s.GlobalID,
+		ShardID:         s.ShardID,
+		SeqNo:           s.SeqNo,
+		VertSeqNo:       s.VertSeqNo,
+		GenUtime:        s.GenUtime,
+		GenLt:           s.GenLt,
+		MinRefMcSeqno:   s.MinRefMcSeqno,
+		OutMsgQueueInfo: s.OutMsgQueueInfo,
+		BeforeSplit:     s.BeforeSplit,
+	}
+	if err := encoder.Marshal(c, head); err != nil {
+		return err
+	}
+	accounts := boc.NewCell()
+	if len(s.Accounts.Keys()) == 0 && s.shardAccounts.root != nil {
+		// the state was decoded with WithLazyShardAccounts
+		if err := encoder.Marshal(accounts, s.shardAccounts); err != nil {
+			return err
+		}
+	} else if err := encoder.Marshal(accounts, s.Accounts); err != nil {
+		return err
+	}
+	if err := c.AddRef(accounts); err != nil {
+		return err
+	}
+	tail := struct {
+		Other  ShardStateUnsplitOther `tlb:"^"`
+		Custom Maybe[Ref[McStateExtra]]
+	}{
+		Other:  s.Other,
+		Custom: s.Custom,
+	}
+	return encoder.Marshal(c, tail)
+}
+
+// ShardAccounts returns accounts of the shard state as a lazily decoded dictionary.
+// Unlike Accounts, it is available even if the state was decoded with Decoder.WithLazyShardAccounts.
+// It is empty if the state wasn't unmarshalled from a cell.
+func (s ShardStateUnsplitData) ShardAccounts() ShardAccounts {
+	return s.shardAccounts
 }
 
 // ShardState
@@ -122,6 +231,26 @@ func (s *ShardState) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
 	return nil
 }
 
+// Accounts calls fn for every account of the shard state in ascending order of addresses
+// until fn returns false.
+// For a split state, accounts of the left shard go first.
+func (s *ShardState) Accounts(fn func(address Bits256, account ShardAccount) bool) error {
+	switch s.SumType {
+	case "UnsplitState":
+		return s.UnsplitState.Value.ShardStateUnsplit.ShardAccounts().Range(fn)
+	default:
+		stopped := false
+		err := s.SplitState.Left.ShardStateUnsplit.ShardAccounts().Range(func(address Bits256, account ShardAccount) bool {
+			stopped = !fn(address, account)
+			return !stopped
+		})
+		if err != nil || stopped {
+			return err
+		}
+		return s.SplitState.Right.ShardStateUnsplit.ShardAccounts().Range(fn)
+	}
+}
+
 func (s *ShardState) AccountBalances() map[Bits256]CurrencyCollection {
 	switch s.SumType {
 	case "UnsplitState":
@@ -157,6 +286,103 @@ func (s *ShardState) AccountBalances() map[Bits256]CurrencyCollection {
 	}
 }
 
+// ShardAccounts
+// _ (HashmapAugE 256 ShardAccount DepthBalanceInfo) = ShardAccounts;
+// A shard can contain millions of accounts,
+// so the dictionary is kept as a cell and accounts are decoded on demand by Range.
+type ShardAccounts struct {
+	root  *boc.Cell
+	extra DepthBalanceInfo
+}
+
+func (s *ShardAccounts) UnmarshalTLB(c *boc.Cell, decoder *Decoder) error {
+	exists, err := c.ReadBit()
+	if err != nil {
+		return err
+	}
+	s.root = nil
+	if exists {
+		if s.root, err = c.NextRef(); err != nil {
+			return err
+		}
+	}
+	return decoder.Unmarshal(c, &s.extra)
+}
+
+func (s ShardAccounts) MarshalTLB(c *boc.Cell, encoder *Encoder) error {
+	if err := c.WriteBit(s.root != nil); err != nil {
+		return err
+	}
+	if s.root != nil {
+		if err := c.AddRef(s.root); err != nil {
+			return err
+		}
+	}
+	return encoder.Marshal(c, s.extra)
+}
+
+// Extra returns the total balance of all accounts.
+func (s ShardAccounts) Extra() DepthBalanceInfo {
+	return s.extra
+}
+
+// Range decodes accounts one by one and calls fn for each of them in ascending order of addresses
+// until fn returns false.
+// Pruned branches of the dictionary are skipped.
+// Range must not be called concurrently on the same ShardAccounts.
+func (s ShardAccounts) Range(fn func(address Bits256, account ShardAccount) bool) error {
+	if s.root == nil {
+		return nil
+	}
+	s.root.ResetCounters()
+	decoder := NewDecoder()
+	keyPrefix := boc.NewBitString(256)
+	_, err := rangeShardAccounts(s.root, 256, &keyPrefix, decoder, fn)
+	return err
+}
+
+func rangeShardAccounts(c *boc.Cell, leftKeySize int, keyPrefix *boc.BitString, decoder *Decoder, fn func(Bits256, ShardAccount) bool) (bool, error) {
+	if c.CellType() == boc.PrunedBranchCell {
+		return true, nil
+	}
+	size, keyPrefix, err := loadLabel(leftKeySize, c, keyPrefix)
+	if err != nil {
+		return false, err
+	}
+	if keyPrefix.BitsAvailableForRead() < 256 {
+		for _, bit := range []bool{false, true} {
+			next, err := c.NextRef()
+			if err != nil {
+				return false, err
+			}
+			prefix := keyPrefix.Copy()
+			if err := prefix.WriteBit(bit); err != nil {
+				return false, err
+			}
+			ok, err := rangeShardAccounts(next, leftKeySize-(1+size), &prefix, decoder, fn)
+			if err != nil || !ok {
+				return ok, err
+			}
+		}
+		return true, nil
+	}
+	var extra DepthBalanceInfo
+	if err := decoder.Unmarshal(c, &extra); err != nil {
+		return false, err
+	}
+	var account ShardAccount
+	if err := decoder.Unmarshal(c, &account); err != nil {
+		return false, err
+	}
+	key, err := keyPrefix.ReadBytes(32)
+	if err != nil {
+		return false, err
+	}
+	var address Bits256
+	copy(address[:], key)
+	return fn(address, account), nil
+}
+
 // ShardIdent
 // shard_ident$00 shard_pfx_bits:(#<= 60)
 // workchain_id:int32 shard_prefix:uint64 = ShardIdent;
@@ -1,6 +1,7 @@
 package tlb
 
 import (
+	"os"
 	"reflect"
 	"testing"
 
@@ -46,3 +47,82 @@ func TestConfigParams_CloneKeepingSubsetOfKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestShardAccounts_Range(t *testing.T) {
+	data, err := os.ReadFile("testdata/block-1/block.bin")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	cell, err := boc.DeserializeBoc(data)
+	if err != nil {
+		t.Fatalf("boc.DeserializeBoc() failed: %v", err)
+	}
+	var block Block
+	if err := Unmarshal(cell[0], &block); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	state := block.StateUpdate.ToRoot.UnsplitState.Value.ShardStateUnsplit
+	accounts := state.ShardAccounts()
+	if accounts.root == nil {
+		t.Fatalf("accounts must be present in the state update")
+	}
+	expected := state.Accounts.m
+	if len(expected.keys) == 0 {
+		t.Fatalf("state update must contain accounts")
+	}
+	var keys []Bits256
+	var values []ShardAccount
+	err = accounts.Range(func(address Bits256, account ShardAccount) bool {
+		keys = append(keys, address)
+		values = append(values, account)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Range() failed: %v", err)
+	}
+	if !reflect.DeepEqual(keys, expected.keys) {
+		t.Fatalf("keys mismatch")
+	}
+	for i := range values {
+		if values[i].LastTransLt != expected.values[i].LastTransLt || values[i].LastTransHash != expected.values[i].LastTransHash {
+			t.Fatalf("value mismatch for key %x", keys[i])
+		}
+	}
+	count := 0
+	err = accounts.Range(func(address Bits256, account ShardAccount) bool {
+		count++
+		return false
+	})
+	if err != nil || count != 1 {
+		t.Fatalf("Range() must stop when fn returns false, got %v calls, err: %v", count, err)
+	}
+}
+
+func TestDecoder_WithLazyShardAccounts(t *testing.T) {
+	data, err := os.ReadFile("testdata/block-1/block.bin")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	cell, err := boc.DeserializeBoc(data)
+	if err != nil {
+		t.Fatalf("boc.DeserializeBoc() failed: %v", err)
+	}
+	var block Block
+	if err := NewDecoder().WithLazyShardAccounts().Unmarshal(cell[0], &block); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	state := block.StateUpdate.ToRoot.UnsplitState.Value.ShardStateUnsplit
+	if len(state.Accounts.Keys()) != 0 {
+		t.Fatalf("accounts must not be decoded")
+	}
+	count := 0
+	if err := state.ShardAccounts().Range(func(address Bits256, account ShardAccount) bool {
+		count++
+		return true
+	}); err != nil {
+		t.Fatalf("Range() failed: %v", err)
+	}
+	if count == 0 {
+		t.Fatalf("state update must contain accounts")
+	}
+}
@@ -103,6 +103,13 @@ func (vd ValidatorDescr) PubKey() Bits256 {
 	return vd.ValidatorAddr.PublicKey.PubKey
 }
 
+func (vd ValidatorDescr) Weight() uint64 {
+	if vd.SumType == "Validator" {
+		return vd.Validator.Weight
+	}
+	return vd.ValidatorAddr.Weight
+}
+
 type SigPubKey struct {
 	Magic  Magic `tlb:"pubkey#8e81278a"`
 	PubKey Bits256
@@ -1,7 +1,9 @@
 package toncrypto
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
+	"errors"
 	"slices"
 	"testing"
 )
@@ -29,3 +31,77 @@ func TestPrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestEncryptDecrypt(t *testing.T) {
+	_, senderKey, _ := ed25519.GenerateKey(rand.Reader)
+	receiverPubkey, receiverKey, _ := ed25519.GenerateKey(rand.Reader)
+	salt := []byte("EQD2NmD_lH5f5u1Kj3KfGyTvhZSX0Eg6qp2a5IQUKXxOG21n")
+	for _, size := range []int{0, 1, 15, 16, 17, 100, 1000} {
+		data := make([]byte, size)
+		_, _ = rand.Read(data)
+		encrypted, err := Encrypt(receiverPubkey, senderKey, data, salt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := Decrypt(receiverKey, salt, encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(decrypted, data) {
+			t.Fatalf("data corrupted for size %v", size)
+		}
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	_, senderKey, _ := ed25519.GenerateKey(rand.Reader)
+	receiverPubkey, receiverKey, _ := ed25519.GenerateKey(rand.Reader)
+	salt := []byte("salt")
+	encrypted, err := Encrypt(receiverPubkey, senderKey, []byte("hello"), salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		data    func() []byte
+		salt    []byte
+		wantErr error
+	}{
+		{
+			name:    "too short",
+			data:    func() []byte { return encrypted[:47] },
+			salt:    salt,
+			wantErr: ErrInvalidEncryptedData,
+		},
+		{
+			name:    "not aligned",
+			data:    func() []byte { return encrypted[:len(encrypted)-1] },
+			salt:    salt,
+			wantErr: ErrInvalidEncryptedData,
+		},
+		{
+			name: "corrupted payload",
+			data: func() []byte {
+				d := slices.Clone(encrypted)
+				d[len(d)-1] ^= 1
+				return d
+			},
+			salt:    salt,
+			wantErr: ErrInvalidMsgKey,
+		},
+		{
+			name:    "wrong salt",
+			data:    func() []byte { return encrypted },
+			salt:    []byte("other salt"),
+			wantErr: ErrInvalidMsgKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(receiverKey, tt.salt, tt.data())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("want %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -13,6 +13,12 @@ import (
 	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
 )
 
+var (
+	ErrInvalidEncryptedData = errors.New("encrypted data is too short or not aligned")
+	ErrInvalidMsgKey        = errors.New("msg key does not match decrypted data")
+	ErrInvalidPrefix        = errors.New("invalid prefix of decrypted data")
+)
+
 func Encrypt(receiverPubkey ed25519.PublicKey, ourPrivateKey ed25519.PrivateKey, data []byte, salt []byte) ([]byte, error) {
 	if len(receiverPubkey) != ed25519.PublicKeySize {
 		return nil, errors.New("receiverPubkey size is invalid")
@@ -101,4 +107,51 @@ func combineSecrets(a, b []byte) []byte {
 	return h.Sum(nil)
 }
 
-// TODO: add decrypt
+// Decrypt reverses Encrypt. It recovers the sender's public key from the xor-ed header,
+// decrypts the payload and checks both the msg key and the random prefix.
+// Salt must be the same as the one used by the sender (usually the sender's address).
+func Decrypt(ourPrivateKey ed25519.PrivateKey, salt []byte, data []byte) ([]byte, error) {
+	if len(ourPrivateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("ourPrivateKey size is invalid")
+	}
+	if len(data) < 32+16+16 || (len(data)-48)%16 != 0 {
+		return nil, ErrInvalidEncryptedData
+	}
+	ourPublicKey := ourPrivateKey.Public().(ed25519.PublicKey)
+	senderPubkey := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	for i := 0; i < 32; i++ {
+		senderPubkey[i] = data[i] ^ ourPublicKey[i]
+	}
+	msgKey := data[32:48]
+	sharedSecret, err := getSharedSecret(ourPrivateKey, senderPubkey)
+	if err != nil {
+		return nil, err
+	}
+	x := combineSecrets(sharedSecret, msgKey)
+	key := x[:32]
+	iv := x[32:48]
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	dec := cipher.NewCBCDecrypter(c, iv)
+	prefixedData := make([]byte, len(data)-48)
+	dec.CryptBlocks(prefixedData, data[48:])
+
+	dataHash := combineSecrets(salt, prefixedData)
+	if !hmac.Equal(dataHash[:16], msgKey) {
+		return nil, ErrInvalidMsgKey
+	}
+	return removePrefix(prefixedData)
+}
+
+func removePrefix(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidPrefix
+	}
+	prefixLen := int(data[0])
+	if prefixLen < 16 || prefixLen > 31 || prefixLen > len(data) {
+		return nil, ErrInvalidPrefix
+	}
+	return data[prefixLen:], nil
+}
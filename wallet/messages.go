@@ -363,6 +363,85 @@ func decodeHighloadV2Message(body *SignedMsgBody) (*HighloadV2Message, error) {
 	return &msg, nil
 }
 
+// highloadV3SignedMsgBody is an external message's body of a highload wallet v3.
+// Unlike SignedMsgBody, the signed payload is stored in a reference.
+type highloadV3SignedMsgBody struct {
+	Sign    tlb.Bits512
+	Message *boc.Cell `tlb:"^"`
+}
+
+func extractHighloadV3SignedMsgBody(msg *boc.Cell) (*tlb.Message, *highloadV3SignedMsgBody, error) {
+	var m tlb.Message
+	if err := tlb.Unmarshal(msg, &m); err != nil {
+		return nil, nil, err
+	}
+	var body highloadV3SignedMsgBody
+	bodyCell := boc.Cell(m.Body.Value)
+	if err := tlb.Unmarshal(&bodyCell, &body); err != nil {
+		return nil, nil, err
+	}
+	return &m, &body, nil
+}
+
+func DecodeHighloadV3Message(msg *boc.Cell) (*HighloadV3Message, error) {
+	_, body, err := extractHighloadV3SignedMsgBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHighloadV3Message(body)
+}
+
+func decodeHighloadV3Message(body *highloadV3SignedMsgBody) (*HighloadV3Message, error) {
+	msg := HighloadV3Message{}
+	if err := tlb.Unmarshal(body.Message, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// highloadV3RawMessages returns messages sent by a highload wallet v3,
+// a batch sent through a message to the wallet itself is expanded.
+func highloadV3RawMessages(msg *boc.Cell) ([]RawMessage, error) {
+	m, body, err := extractHighloadV3SignedMsgBody(msg)
+	if err != nil {
+		return nil, err
+	}
+	hl, err := decodeHighloadV3Message(body)
+	if err != nil {
+		return nil, err
+	}
+	single := []RawMessage{{Message: hl.MessageToSend, Mode: hl.SendMode}}
+	var toSend tlb.Message
+	if err := tlb.Unmarshal(hl.MessageToSend, &toSend); err != nil {
+		return nil, err
+	}
+	hl.MessageToSend.ResetCounters()
+	if m.Info.SumType != "ExtInMsgInfo" || toSend.Info.SumType != "IntMsgInfo" {
+		return single, nil
+	}
+	account, err := ton.AccountIDFromTlb(m.Info.ExtInMsgInfo.Dest)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := ton.AccountIDFromTlb(toSend.Info.IntMsgInfo.Dest)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || dest == nil || *account != *dest {
+		return single, nil
+	}
+	var transfer HighloadV3InternalTransfer
+	transferCell := boc.Cell(toSend.Body.Value)
+	if err := tlb.Unmarshal(&transferCell, &transfer); err != nil {
+		return single, nil
+	}
+	messages := make([]RawMessage, 0, len(transfer.Actions))
+	for _, action := range transfer.Actions {
+		messages = append(messages, RawMessage{Message: action.Msg, Mode: action.Mode})
+	}
+	return messages, nil
+}
+
 // ExtractRawMessages extracts a list of RawMessages from an external message.
 func ExtractRawMessages(ver Version, msg *boc.Cell) ([]RawMessage, error) {
 	switch ver {
@@ -396,6 +475,8 @@ func ExtractRawMessages(ver Version, msg *boc.Cell) ([]RawMessage, error) {
 			return nil, err
 		}
 		return hl.RawMessages, nil
+	case HighLoadV3R1:
+		return highloadV3RawMessages(msg)
 	default:
 		return nil, fmt.Errorf("wallet version is not supported: %v", ver)
 	}
@@ -419,6 +500,19 @@ func VerifySignature(ver Version, msg *boc.Cell, publicKey ed25519.PublicKey) er
 			return err
 		}
 		return MessageV5VerifySignature(boc.Cell(m.Body.Value), publicKey)
+	case HighLoadV3R1:
+		_, body, err := extractHighloadV3SignedMsgBody(msg)
+		if err != nil {
+			return err
+		}
+		hash, err := body.Message.Hash()
+		if err != nil {
+			return err
+		}
+		if ed25519.Verify(publicKey, hash, body.Sign[:]) {
+			return nil
+		}
+		return ErrBadSignature
 	default:
 		return fmt.Errorf("wallet version is not supported: %v", ver)
 	}
@@ -2,13 +2,16 @@ package wallet
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"math/big"
 	"time"
+	"unicode/utf8"
 
 	"github.com/tonkeeper/tongo/boc"
 	"github.com/tonkeeper/tongo/tlb"
 	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/toncrypto"
 	"github.com/tonkeeper/tongo/utils"
 )
 
@@ -32,7 +35,7 @@ const (
 	HighLoadV2
 	HighLoadV2R1
 	HighLoadV2R2
-	// TODO: maybe add lockup wallet
+	HighLoadV3R1
 )
 
 var codeVersionToString = map[Version]string{
@@ -43,6 +46,7 @@ var codeVersionToString = map[Version]string{
 	V2R2:         "v2R2",
 	V3R1:         "v3R1",
 	V3R2:         "v3R2",
+	V3R2Lockup:   "v3R2_lockup",
 	V4R1:         "v4R1",
 	V4R2:         "v4R2",
 	V5Beta:       "v5Beta",
@@ -52,6 +56,7 @@ var codeVersionToString = map[Version]string{
 	HighLoadV1R2: "highload_v1R2",
 	HighLoadV2R1: "highload_v2R1",
 	HighLoadV2R2: "highload_v2R2",
+	HighLoadV3R1: "highload_v3R1",
 }
 var stringToVersion = map[string]Version{}
 
@@ -85,6 +90,7 @@ var codes = map[Version]string{
 	HighLoadV1R2: "te6ccgEBCAEAmQABFP8A9KQT9LzyyAsBAgEgAgMCAUgEBQC88oMI1xgg0x/TH9Mf+CMTu/Jj7UTQ0x/TH9P/0VEyuvKhUUS68qIE+QFUEFX5EPKj9ATR+AB/jhghgBD0eG+hb6EgmALTB9QwAfsAkTLiAbPmWwGkyMsfyx/L/8ntVAAE0DACAUgGBwAXuznO1E0NM/MdcL/4ABG4yX7UTQ1wsfg=",
 	HighLoadV2:   "te6ccgEBCQEA5QABFP8A9KQT9LzyyAsBAgEgAgcCAUgDBAAE0DACASAFBgAXvZznaiaGmvmOuF/8AEG+X5dqJoaY+Y6Z/p/5j6AmipEEAgegc30JjJLb/JXdHxQB6vKDCNcYINMf0z/4I6ofUyC58mPtRNDTH9M/0//0BNFTYIBA9A5voTHyYFFzuvKiB/kBVBCH+RDyowL0BNH4AH+OFiGAEPR4b6UgmALTB9QwAfsAkTLiAbPmW4MlochANIBA9EOK5jEByMsfE8s/y//0AMntVAgANCCAQPSWb6VsEiCUMFMDud4gkzM2AZJsIeKz",
 	HighLoadV2R1: "te6ccgEBBwEA1gABFP8A9KQT9KDyyAsBAgEgAgMCAUgEBQHu8oMI1xgg0x/TP/gjqh9TILnyY+1E0NMf0z/T//QE0VNggED0Dm+hMfJgUXO68qIH+QFUEIf5EPKjAvQE0fgAf44YIYAQ9HhvoW+hIJgC0wfUMAH7AJEy4gGz5luDJaHIQDSAQPRDiuYxyBLLHxPLP8v/9ADJ7VQGAATQMABBoZfl2omhpj5jpn+n/mPoCaKkQQCB6BzfQmMktv8ld0fFADgggED0lm+hb6EyURCUMFMDud4gkzM2AZIyMOKz",
+	HighLoadV3R1: "te6ccgECEAEAAigAART/APSkE/S88sgLAQIBIAIDAgFIBAUB9vLUgwjXGNEh+QDtRNDT/9Mf9AT0BNM/0xXR+CMhoVIguY4SM234IySqAKESuZJtMt5Y+CMB3lQWdfkQ8qEG0NMf1NMH0wzTCdM/0xXRUWi68qJRWrrypvgjKqFSULzyowT4I7vyo1MEgA30D2+hmdAk1yHXCgDyZJEw4g4AeNAg10vAAQHAYLCRW+EB0NMDAXGwkVvg+kAw+CjHBbORMODTHwGCEK5C5aS6nYBA1yHXTPgqAe1V+wTgMAIBIAYHAgJzCAkCASAMDQARrc52omhrhf/AAgEgCgsAGqu27UTQgQEi1yHXCz8AGKo77UTQgwfXIdcLHwAbuabu1E0IEBYtch1wsVgA5bi/Ltou37IasJAoQJsO1E0IEBINch9AT0BNM/0xXRBY4b+CMloVIQuZ8ybfgjBaoAFaESuZIwbd6SMDPikjAz4lIwgA30D2+hntAh1yHXCgCVXwN/2zHgkTDiWYAN9A9voZzQAdch1woAk3/bMeCRW+JwgB/lMJgA30D2+hjhPQUATXGNIAAfJkyFjPFs+DAc8WjhAwyCTPQM+DhAlQBaGlFM9A4vgAyUA5gA30FwTIy/8Tyx/0ABL0ABLLPxLLFcntVPgPIdDTAAHyZdMCAXGwkl8D4PpAAdcLAcAA8qX6QDH6ADH0AfoAMfoAMYBg1yHTAAEPACDyZdIAAZPUMdGRMOJysfsA",
 	HighLoadV2R2: "te6ccgEBCQEA6QABFP8A9KQT9LzyyAsBAgEgAgMCAUgEBQHu8oMI1xgg0x/TP/gjqh9TILnyY+1E0NMf0z/T//QE0VNggED0Dm+hMfJgUXO68qIH+QFUEIf5EPKjAvQE0fgAf44YIYAQ9HhvoW+hIJgC0wfUMAH7AJEy4gGz5luDJaHIQDSAQPRDiuYxyBLLHxPLP8v/9ADJ7VQIAATQMAIBIAYHABe9nOdqJoaa+Y64X/wAQb5fl2omhpj5jpn+n/mPoCaKkQQCB6BzfQmMktv8ld0fFAA4IIBA9JZvoW+hMlEQlDBTA7neIJMzNgGSMjDisw==",
 }
 
@@ -344,6 +350,83 @@ func (t *TextComment) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error { //
 	return nil
 }
 
+// EncryptedTransfer is a GRAM transfer with an encrypted text comment.
+// Use NewEncryptedComment or Wallet.EncryptComment to build the comment.
+type EncryptedTransfer struct {
+	Amount     tlb.Grams
+	Address    ton.AccountID
+	Comment    EncryptedComment
+	Bounceable bool
+}
+
+func (m EncryptedTransfer) ToInternal() (tlb.Message, uint8, error) {
+	body := boc.NewCell()
+	if err := tlb.Marshal(body, m.Comment); err != nil {
+		return tlb.Message{}, 0, err
+	}
+	msg := Message{
+		Amount:  m.Amount,
+		Address: m.Address,
+		Body:    body,
+		Bounce:  m.Bounceable,
+		Mode:    DefaultMessageMode,
+	}
+	return msg.ToInternal()
+}
+
+// EncryptedComment contains a cipher text of a comment encrypted with toncrypto.Encrypt.
+// encrypted_text_comment#2167da4b cipher_text:Bytes = InternalMsgBody;
+type EncryptedComment []byte
+
+// NewEncryptedComment encrypts a comment for the recipient's public key.
+// The sender's address is used as a salt, so the recipient has to know it to decrypt the comment.
+func NewEncryptedComment(comment string, senderKey ed25519.PrivateKey, sender ton.AccountID, recipientPubkey ed25519.PublicKey) (EncryptedComment, error) {
+	salt := []byte(sender.ToHuman(true, false))
+	cipherText, err := toncrypto.Encrypt(recipientPubkey, senderKey, []byte(comment), salt)
+	if err != nil {
+		return nil, err
+	}
+	return cipherText, nil
+}
+
+// Decrypt decrypts a comment sent by the given sender to the owner of the private key.
+func (t EncryptedComment) Decrypt(ourKey ed25519.PrivateKey, sender ton.AccountID) (string, error) {
+	salt := []byte(sender.ToHuman(true, false))
+	data, err := toncrypto.Decrypt(ourKey, salt, t)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("invalid unicode characters in comment")
+	}
+	return string(data), nil
+}
+
+func (t EncryptedComment) MarshalTLB(c *boc.Cell, encoder *tlb.Encoder) error {
+	err := c.WriteUint(0x2167da4b, 32) // encrypted text comment tag
+	if err != nil {
+		return err
+	}
+	return tlb.Marshal(c, tlb.Bytes(t))
+}
+
+func (t *EncryptedComment) UnmarshalTLB(c *boc.Cell, decoder *tlb.Decoder) error {
+	val, err := c.ReadUint(32) // encrypted text comment tag
+	if err != nil {
+		return err
+	}
+	if val != 0x2167da4b {
+		return fmt.Errorf("not an encrypted comment")
+	}
+	var b tlb.Bytes
+	err = tlb.Unmarshal(c, &b)
+	if err != nil {
+		return err
+	}
+	*t = EncryptedComment(b)
+	return nil
+}
+
 type ContractDeploy struct {
 	Workchain int32
 	Code      any
@@ -0,0 +1,104 @@
+// Package relay helps a relayer service to deliver gasless transfers of wallet v5.
+//
+// A wallet's owner signs a transfer with wallet.Wallet.CreateRelayedTransfer and passes it to a relayer.
+// The relayer verifies the transfer against the wallet's current state with Prepare, estimates fees
+// and sends wallet.RelayedTransfer.Message from its own wallet.
+package relay
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/txemulator"
+	"github.com/tonkeeper/tongo/wallet"
+)
+
+var (
+	// ErrPublicKeyMismatch is returned when the given public key differs from the one stored in the wallet.
+	ErrPublicKeyMismatch = errors.New("public key doesn't match the wallet's public key")
+	// ErrTransferExpired is returned when the transfer's valid_until has passed.
+	ErrTransferExpired = errors.New("relayed transfer is expired")
+)
+
+// Estimation is a result of emulating delivery of a relayed transfer.
+type Estimation struct {
+	// Success reports whether the wallet executed the transfer.
+	Success bool
+	// WalletFees are fees of the wallet's transaction including forward fees of messages sent by the wallet.
+	WalletFees tlb.Grams
+	// TotalFees are fees of all transactions in the trace.
+	TotalFees tlb.Grams
+	Trace     *txemulator.TxTree
+}
+
+// Prepare verifies the transfer and returns a message which delivers the transfer
+// with the given amount attached. The relayer sends the message from its own wallet.
+// state is the current state of the transfer's wallet, publicKey must be the public key stored in the wallet.
+// The transfer must be an internal signed message signed by this key and not expired yet.
+func Prepare(transfer wallet.RelayedTransfer, state tlb.ShardAccount, publicKey ed25519.PublicKey, amount tlb.Grams) (wallet.Message, error) {
+	walletKey, err := transfer.WalletPublicKey(state)
+	if err != nil {
+		return wallet.Message{}, err
+	}
+	if !publicKey.Equal(walletKey) {
+		return wallet.Message{}, ErrPublicKeyMismatch
+	}
+	if err := transfer.VerifySignature(publicKey); err != nil {
+		return wallet.Message{}, err
+	}
+	if _, err := transfer.RawMessages(); err != nil {
+		return wallet.Message{}, err
+	}
+	validUntil, err := transfer.ValidUntil()
+	if err != nil {
+		return wallet.Message{}, err
+	}
+	if !time.Now().Before(validUntil) {
+		return wallet.Message{}, ErrTransferExpired
+	}
+	return transfer.Message(amount), nil
+}
+
+// EstimateFees emulates delivering the transfer from the relayer to the wallet with the given amount attached.
+// Options are passed to txemulator.NewTraceBuilder, usually they provide an accounts source.
+func EstimateFees(ctx context.Context, relayer ton.AccountID, transfer wallet.RelayedTransfer, amount tlb.Grams, options ...txemulator.TraceOption) (Estimation, error) {
+	message := transfer.Message(amount)
+	message.Src = &relayer
+	msg, _, err := message.ToInternal()
+	if err != nil {
+		return Estimation{}, err
+	}
+	tracer, err := txemulator.NewTraceBuilder(options...)
+	if err != nil {
+		return Estimation{}, err
+	}
+	tree, err := tracer.Run(ctx, msg)
+	if err != nil {
+		return Estimation{}, fmt.Errorf("emulation failed: %w", err)
+	}
+	estimation := Estimation{
+		Success:    tree.TX.IsSuccess(),
+		WalletFees: tree.TX.TotalFees.Grams,
+		TotalFees:  totalFees(tree),
+		Trace:      tree,
+	}
+	for _, outMsg := range tree.TX.Msgs.OutMsgs.Values() {
+		if outMsg.Value.Info.SumType == "IntMsgInfo" {
+			estimation.WalletFees += outMsg.Value.Info.IntMsgInfo.FwdFee
+		}
+	}
+	return estimation, nil
+}
+
+func totalFees(tree *txemulator.TxTree) tlb.Grams {
+	fees := tree.TX.TotalFees.Grams
+	for _, child := range tree.Children {
+		fees += totalFees(child)
+	}
+	return fees
+}
@@ -0,0 +1,170 @@
+package relay
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tontest"
+	"github.com/tonkeeper/tongo/txemulator"
+	"github.com/tonkeeper/tongo/wallet"
+)
+
+type accountSource map[ton.AccountID]tlb.ShardAccount
+
+func (s accountSource) GetAccountState(_ context.Context, account ton.AccountID) (tlb.ShardAccount, error) {
+	if state, ok := s[account]; ok {
+		return state, nil
+	}
+	return tontest.Account().Address(account).MustShardAccount(), nil
+}
+
+func (s accountSource) GetLibraries(_ context.Context, _ []ton.Bits256) (map[ton.Bits256]*boc.Cell, error) {
+	return nil, nil
+}
+
+func (s accountSource) GetAllShardsInfo(_ context.Context, _ ton.BlockIDExt) ([]ton.BlockIDExt, error) {
+	return []ton.BlockIDExt{{
+		BlockID: ton.BlockID{
+			Workchain: 0,
+			Shard:     0x8000000000000000,
+			Seqno:     1,
+		},
+	}}, nil
+}
+
+func (s accountSource) GetMasterchainInfo(_ context.Context) (liteclient.LiteServerMasterchainInfoC, error) {
+	return liteclient.LiteServerMasterchainInfoC{
+		Last: liteclient.TonNodeBlockIdExtC{Seqno: 1},
+	}, nil
+}
+
+var (
+	relayer   = ton.MustParseAccountID("0:00000000000000000000000000000000000000000000000000000000000000f1")
+	recipient = ton.MustParseAccountID("0:00000000000000000000000000000000000000000000000000000000000000a1")
+)
+
+func newTransfer(t *testing.T, key ed25519.PrivateKey, validUntil time.Time) (wallet.RelayedTransfer, tlb.ShardAccount) {
+	w, err := wallet.New(key, wallet.V5R1, nil)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	stateInit, err := w.StateInit()
+	if err != nil {
+		t.Fatalf("StateInit() failed: %v", err)
+	}
+	// the wallet has no TON, the relayer pays for the transfer
+	state := tontest.Account().
+		Address(w.GetAddress()).
+		State(tlb.AccountActive).
+		StateInit(&stateInit.Code.Value.Value, &stateInit.Data.Value.Value).
+		MustShardAccount()
+	blockchain, _ := wallet.NewMockBlockchain(0, state)
+	w, err = wallet.New(key, wallet.V5R1, blockchain)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	transfer, err := w.CreateRelayedTransfer(context.Background(), []wallet.Sendable{
+		wallet.SimpleTransfer{Amount: ton.OneGRAM / 100, Address: recipient},
+	}, validUntil)
+	if err != nil {
+		t.Fatalf("CreateRelayedTransfer() failed: %v", err)
+	}
+	return transfer, state
+}
+
+func TestPrepare(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, 32))
+	publicKey := key.Public().(ed25519.PublicKey)
+	transfer, state := newTransfer(t, key, time.Now().Add(time.Minute))
+	msg, err := Prepare(transfer, state, publicKey, ton.OneGRAM/10)
+	if err != nil {
+		t.Fatalf("Prepare() failed: %v", err)
+	}
+	if msg.Address != transfer.Wallet || msg.Amount != ton.OneGRAM/10 || !msg.Bounce {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	otherKey := ed25519.NewKeyFromSeed(append(make([]byte, 31), 1))
+	otherPublicKey := otherKey.Public().(ed25519.PublicKey)
+	if _, err := Prepare(transfer, state, otherPublicKey, ton.OneGRAM/10); !errors.Is(err, ErrPublicKeyMismatch) {
+		t.Fatalf("want ErrPublicKeyMismatch, got %v", err)
+	}
+	otherTransfer, _ := newTransfer(t, otherKey, time.Now().Add(time.Minute))
+	badSigned := otherTransfer
+	badSigned.Wallet = transfer.Wallet
+	if _, err := Prepare(badSigned, state, publicKey, ton.OneGRAM/10); !errors.Is(err, wallet.ErrBadSignature) {
+		t.Fatalf("want ErrBadSignature, got %v", err)
+	}
+
+	expired, _ := newTransfer(t, key, time.Now().Add(-time.Minute))
+	if _, err := Prepare(expired, state, publicKey, ton.OneGRAM/10); !errors.Is(err, ErrTransferExpired) {
+		t.Fatalf("want ErrTransferExpired, got %v", err)
+	}
+
+	w, err := wallet.New(key, wallet.V5R1, nil)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	external := transfer
+	external.Body, err = w.CreateMessageBody(wallet.MessageConfig{V5MsgType: wallet.V5MsgTypeSignedExternal},
+		wallet.SimpleTransfer{Amount: ton.OneGRAM / 100, Address: recipient})
+	if err != nil {
+		t.Fatalf("CreateMessageBody() failed: %v", err)
+	}
+	if _, err := Prepare(external, state, publicKey, ton.OneGRAM/10); err == nil {
+		t.Fatalf("external signed message must be rejected")
+	}
+
+	// the wallet is not deployed, so the key is taken from the transfer's state init
+	uninit := tontest.Account().Address(otherTransfer.Wallet).MustShardAccount()
+	if _, err := Prepare(otherTransfer, uninit, otherPublicKey, ton.OneGRAM/10); err == nil {
+		t.Fatalf("transfer without state init must be rejected")
+	}
+	blockchain, _ := wallet.NewMockBlockchain(0, uninit)
+	w, err = wallet.New(otherKey, wallet.V5R1, blockchain)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	deploying, err := w.CreateRelayedTransfer(context.Background(), nil, time.Time{})
+	if err != nil {
+		t.Fatalf("CreateRelayedTransfer() failed: %v", err)
+	}
+	if _, err := Prepare(deploying, uninit, otherPublicKey, ton.OneGRAM/10); err != nil {
+		t.Fatalf("Prepare() failed: %v", err)
+	}
+	if _, err := Prepare(deploying, uninit, publicKey, ton.OneGRAM/10); !errors.Is(err, ErrPublicKeyMismatch) {
+		t.Fatalf("want ErrPublicKeyMismatch, got %v", err)
+	}
+}
+
+func TestEstimateFees(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, 32))
+	transfer, state := newTransfer(t, key, time.Now().Add(time.Minute))
+	estimation, err := EstimateFees(context.Background(), relayer, transfer, ton.OneGRAM/10,
+		txemulator.WithAccountsSource(accountSource{transfer.Wallet: state}),
+		txemulator.WithLimit(10),
+	)
+	if err != nil {
+		t.Fatalf("EstimateFees() failed: %v", err)
+	}
+	if !estimation.Success {
+		t.Fatalf("relayed transfer failed")
+	}
+	if estimation.WalletFees == 0 || estimation.TotalFees < estimation.WalletFees {
+		t.Fatalf("unexpected fees: %+v", estimation)
+	}
+	if len(estimation.Trace.Children) != 1 {
+		t.Fatalf("want 1 message, got %v", len(estimation.Trace.Children))
+	}
+	transferred := estimation.Trace.Children[0].TX.Msgs.InMsg.Value.Value
+	if transferred.Info.IntMsgInfo.Value.Grams != ton.OneGRAM/100 {
+		t.Fatalf("unexpected amount: %v", transferred.Info.IntMsgInfo.Value.Grams)
+	}
+}
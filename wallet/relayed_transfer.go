@@ -0,0 +1,181 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// ErrRelayNotSupported is returned when a relayed transfer is created for a wallet which doesn't support it.
+var ErrRelayNotSupported = errors.New("wallet doesn't support relayed transfers")
+
+// RelayedTransfer is a transfer signed by a wallet's owner which a relayer delivers to the wallet
+// in an internal message, so the relayer pays for the transfer instead of the owner.
+// Usually, one of the transfer's messages compensates the relayer in jettons.
+// see https://docs.ton.org/contracts/standard/wallets/gasless
+type RelayedTransfer struct {
+	Version Version
+	Wallet  ton.AccountID
+	// Body is an internal_signed message body.
+	Body *boc.Cell
+	// Init is a state init of the wallet if the wallet is not deployed yet.
+	Init *tlb.StateInit
+}
+
+// CreateRelayedTransfer returns a transfer of the given messages signed by the wallet's owner.
+// The transfer must be delivered to the wallet before validUntil, the default message lifetime is used if validUntil is zero.
+func (w *Wallet) CreateRelayedTransfer(ctx context.Context, messages []Sendable, validUntil time.Time) (RelayedTransfer, error) {
+	if !w.IsRelaySupported() {
+		return RelayedTransfer{}, ErrRelayNotSupported
+	}
+	if w.blockchain == nil {
+		return RelayedTransfer{}, errors.New("blockchain interface is nil")
+	}
+	if len(messages) > w.MaxMessageNumber() {
+		return RelayedTransfer{}, fmt.Errorf("%v wallet support up to %v internal messages", w.ver, w.MaxMessageNumber())
+	}
+	state, err := w.blockchain.GetAccountState(ctx, w.GetAddress())
+	if err != nil {
+		return RelayedTransfer{}, fmt.Errorf("get account state failed: %v", err)
+	}
+	params, err := w.intWallet.NextMessageParams(state)
+	if err != nil {
+		return RelayedTransfer{}, err
+	}
+	msgArray := make([]RawMessage, 0, len(messages))
+	for _, m := range messages {
+		rawMsg, err := ToRawMessage(m)
+		if err != nil {
+			return RelayedTransfer{}, err
+		}
+		msgArray = append(msgArray, rawMsg)
+	}
+	if validUntil.IsZero() {
+		validUntil = time.Now().Add(w.msgDefaultLifetime)
+	}
+	msgConfig := MessageConfig{
+		Seqno:      params.Seqno,
+		ValidUntil: validUntil,
+		V5MsgType:  V5MsgTypeSignedInternal,
+	}
+	body, err := w.createSignedMsgBodyCell(msgArray, msgConfig)
+	if err != nil {
+		return RelayedTransfer{}, fmt.Errorf("can not marshal wallet message body: %v", err)
+	}
+	return RelayedTransfer{
+		Version: w.ver,
+		Wallet:  w.address,
+		Body:    body,
+		Init:    params.Init,
+	}, nil
+}
+
+// Message returns a message a relayer sends to deliver the transfer to the wallet.
+// The amount pays for processing the transfer by the wallet.
+func (t RelayedTransfer) Message(amount tlb.Grams) Message {
+	return Message{
+		Amount:  amount,
+		Address: t.Wallet,
+		Body:    t.Body,
+		Init:    t.Init,
+		Bounce:  t.Init == nil,
+		Mode:    DefaultMessageMode,
+	}
+}
+
+// VerifySignature checks that the transfer is signed by the given public key.
+// A relayer should verify a transfer before paying for it.
+func (t RelayedTransfer) VerifySignature(publicKey ed25519.PublicKey) error {
+	if t.Body == nil {
+		return fmt.Errorf("relayed transfer has no body")
+	}
+	body := *t.Body
+	body.ResetCounters()
+	return MessageV5VerifySignature(body, publicKey)
+}
+
+// RawMessages returns messages the wallet sends executing the transfer.
+func (t RelayedTransfer) RawMessages() ([]RawMessage, error) {
+	_, messages, err := t.parseBody()
+	return messages, err
+}
+
+// ValidUntil returns the time after which the wallet rejects the transfer.
+func (t RelayedTransfer) ValidUntil() (time.Time, error) {
+	validUntil, _, err := t.parseBody()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(validUntil), 0), nil
+}
+
+// WalletPublicKey returns the public key stored in the data of the wallet with the given state.
+// If the wallet is not deployed yet, the key is taken from the transfer's state init
+// which must produce the wallet's address.
+// A relayer should check that the transfer is signed by this key.
+func (t RelayedTransfer) WalletPublicKey(state tlb.ShardAccount) (ed25519.PublicKey, error) {
+	stateInit := t.Init
+	if state.Account.Status() == tlb.AccountActive {
+		stateInit = &state.Account.Account.Storage.State.AccountActive.StateInit
+	} else if stateInit != nil {
+		address, err := generateAddress(int(t.Wallet.Workchain), *stateInit)
+		if err != nil {
+			return nil, err
+		}
+		if address != t.Wallet {
+			return nil, fmt.Errorf("state init doesn't match the wallet address")
+		}
+	}
+	if stateInit == nil || !stateInit.Code.Exists || !stateInit.Data.Exists {
+		return nil, fmt.Errorf("wallet is not deployed and the transfer has no state init")
+	}
+	ver, err := GetVersionByCode(stateInit.Code.Value.Value)
+	if err != nil {
+		return nil, err
+	}
+	if ver != t.Version {
+		return nil, fmt.Errorf("wallet version is %v, not %v", ver.ToString(), t.Version.ToString())
+	}
+	_, _, publicKey, err := parseWalletData(ver, stateInit.Data.Value.Value)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse %v wallet data: %w", ver.ToString(), err)
+	}
+	return publicKey, nil
+}
+
+// parseBody decodes the transfer's body which must be an internal signed message.
+func (t RelayedTransfer) parseBody() (uint32, []RawMessage, error) {
+	if t.Body == nil {
+		return 0, nil, fmt.Errorf("relayed transfer has no body")
+	}
+	body := *t.Body
+	body.ResetCounters()
+	switch t.Version {
+	case V5R1:
+		var m MessageV5
+		if err := tlb.Unmarshal(&body, &m); err != nil {
+			return 0, nil, err
+		}
+		if m.SumType != "SignedInternal" {
+			return 0, nil, fmt.Errorf("relayed transfer must be an internal signed message")
+		}
+		return m.SignedInternal.ValidUntil, m.RawMessages(), nil
+	case V5Beta:
+		var m MessageV5Beta
+		if err := tlb.Unmarshal(&body, &m); err != nil {
+			return 0, nil, err
+		}
+		if m.SumType != "SignedInternal" {
+			return 0, nil, fmt.Errorf("relayed transfer must be an internal signed message")
+		}
+		return m.SignedInternal.ValidUntil, m.RawMessages(), nil
+	default:
+		return 0, nil, ErrRelayNotSupported
+	}
+}
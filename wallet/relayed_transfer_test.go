@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tontest"
+)
+
+func TestCreateRelayedTransfer(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, 32))
+	recipient := ton.MustParseAccountID("0:507dea7d606f22d9e85678d3eede39bbe133a868d2a0e3e07f5502cb70b8a512")
+	blockchain, _ := NewMockBlockchain(0, tontest.Account().Address(ton.AccountID{}).MustShardAccount())
+	for _, ver := range []Version{V5R1, V5Beta} {
+		w, err := New(key, ver, blockchain)
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		transfer, err := w.CreateRelayedTransfer(context.Background(), []Sendable{
+			SimpleTransfer{Amount: 1000, Address: recipient, Comment: "fee"},
+			SimpleTransfer{Amount: 2000, Address: recipient},
+		}, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("%v: CreateRelayedTransfer() failed: %v", ver, err)
+		}
+		if transfer.Init == nil || transfer.Wallet != w.GetAddress() {
+			t.Fatalf("%v: uninitialized wallet requires state init", ver)
+		}
+		if err := transfer.VerifySignature(key.Public().(ed25519.PublicKey)); err != nil {
+			t.Fatalf("%v: VerifySignature() failed: %v", ver, err)
+		}
+		otherKey := ed25519.NewKeyFromSeed(append(make([]byte, 31), 1))
+		if err := transfer.VerifySignature(otherKey.Public().(ed25519.PublicKey)); !errors.Is(err, ErrBadSignature) {
+			t.Fatalf("%v: want ErrBadSignature, got %v", ver, err)
+		}
+		raw, err := transfer.RawMessages()
+		if err != nil {
+			t.Fatalf("%v: RawMessages() failed: %v", ver, err)
+		}
+		if len(raw) != 2 {
+			t.Fatalf("%v: want 2 messages, got %v", ver, len(raw))
+		}
+		var m tlb.Message
+		if err := tlb.Unmarshal(raw[1].Message, &m); err != nil {
+			t.Fatalf("%v: Unmarshal() failed: %v", ver, err)
+		}
+		if m.Info.IntMsgInfo.Value.Grams != 2000 {
+			t.Fatalf("%v: unexpected amount: %v", ver, m.Info.IntMsgInfo.Value.Grams)
+		}
+		msg := transfer.Message(ton.OneGRAM / 10)
+		if msg.Address != w.GetAddress() || msg.Body != transfer.Body || msg.Bounce {
+			t.Fatalf("%v: unexpected relayer message: %+v", ver, msg)
+		}
+	}
+
+	w, err := New(key, V4R2, blockchain)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := w.CreateRelayedTransfer(context.Background(), nil, time.Time{}); !errors.Is(err, ErrRelayNotSupported) {
+		t.Fatalf("want ErrRelayNotSupported, got %v", err)
+	}
+}
@@ -37,6 +37,11 @@ type Options struct {
 	PublicKey       *ed25519.PublicKey
 	PrivateKey      *ed25519.PrivateKey
 	MsgLifetime     time.Duration
+	// HighloadV3Timeout and HighloadV3QueryIDs are only used by highload wallet v3.
+	HighloadV3Timeout  *time.Duration
+	HighloadV3QueryIDs *HighloadV3QueryIDAllocator
+	// Lockup is only used by lockup wallets.
+	Lockup *LockupConfig
 }
 
 type Option func(*Options)
@@ -65,6 +70,29 @@ func WithMessageLifetime(lifetime time.Duration) Option {
 	}
 }
 
+// WithHighloadV3Timeout sets a lifetime of highload wallet v3 messages.
+// The timeout is stored in the contract's data, so wallets with different timeouts have different addresses.
+func WithHighloadV3Timeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.HighloadV3Timeout = &timeout
+	}
+}
+
+// WithHighloadV3QueryIDAllocator sets an allocator of highload wallet v3 query ids.
+// It allows sharing ids between wallet instances and resuming from the last used id after a restart.
+func WithHighloadV3QueryIDAllocator(allocator *HighloadV3QueryIDAllocator) Option {
+	return func(o *Options) {
+		o.HighloadV3QueryIDs = allocator
+	}
+}
+
+// WithLockupConfig sets initial data of a lockup wallet which defines the wallet's address.
+func WithLockupConfig(config LockupConfig) Option {
+	return func(o *Options) {
+		o.Lockup = &config
+	}
+}
+
 func applyOptions(opts ...Option) Options {
 	options := Options{
 		MsgLifetime: DefaultMessageLifetime,
@@ -104,17 +132,30 @@ func NewFromAddress(publicKey ed25519.PublicKey, address ton.AccountID) (Wallet,
 }
 
 // NewFromCodeAndData reconstructs a wallet from the contract's onchain code and data, returning
-// it along with the seqno stored in the data. A non-zero workchain must be provided via WithWorkchain
+// it along with the seqno stored in the data. A non-zero workchain must be provided via WithWorkchain.
+// The address of a lockup wallet depends on its initial data, which the contract changes over time,
+// so the initial config of a lockup wallet must be provided via WithLockupConfig.
 func NewFromCodeAndData(code, data boc.Cell, opts ...Option) (Wallet, uint32, error) {
 	ver, err := GetVersionByCode(code)
 	if err != nil {
 		return Wallet{}, 0, err
 	}
+	if ver == V3R2Lockup && applyOptions(opts...).Lockup == nil {
+		return Wallet{}, 0, ErrLockupConfigRequired
+	}
 	seqno, subWalletID, publicKey, err := parseWalletData(ver, data)
 	if err != nil {
 		return Wallet{}, 0, fmt.Errorf("can't parse %v wallet data: %w", ver.ToString(), err)
 	}
-	w, err := NewFromPublicKey(publicKey, ver, append(opts, WithSubWalletID(subWalletID))...)
+	opts = append(opts, WithSubWalletID(subWalletID))
+	if ver == HighLoadV3R1 {
+		var d DataHighloadV3
+		if err := tlb.Unmarshal(&data, &d); err != nil {
+			return Wallet{}, 0, fmt.Errorf("can't parse %v wallet data: %w", ver.ToString(), err)
+		}
+		opts = append(opts, WithHighloadV3Timeout(time.Duration(d.Timeout)*time.Second))
+	}
+	w, err := NewFromPublicKey(publicKey, ver, opts...)
 	if err != nil {
 		return Wallet{}, 0, err
 	}
@@ -148,6 +189,8 @@ func newWallet(key ed25519.PublicKey, version Version, options Options) (wallet,
 		return newWalletV1V2(version, key, options), nil
 	case V3R1, V3R2:
 		return newWalletV3(version, key, options), nil
+	case V3R2Lockup:
+		return newWalletLockupV3R2(key, options), nil
 	case V4R1, V4R2:
 		return newWalletV4(version, key, options), nil
 	case V5Beta:
@@ -156,6 +199,12 @@ func newWallet(key ed25519.PublicKey, version Version, options Options) (wallet,
 		return NewWalletV5R1(key, options), nil
 	case HighLoadV2R2:
 		return newWalletHighloadV2(version, key, options), nil
+	case HighLoadV3R1:
+		w, err := newWalletHighloadV3(key, options)
+		if err != nil {
+			return nil, err
+		}
+		return w, nil
 	default:
 		return nil, fmt.Errorf("unsupported wallet version: %v", version)
 	}
@@ -239,6 +288,18 @@ func (w *Wallet) RawSendV2(
 	if err != nil {
 		return ton.Bits256{}, fmt.Errorf("can not marshal wallet message body: %v", err)
 	}
+	return w.sendSignedBody(ctx, seqno, signedBodyCell, init, waitingConfirmation)
+}
+
+// sendSignedBody sends an external message with the given signed body to the wallet
+// and waits for the wallet's seqno to increase if waitingConfirmation is not zero.
+func (w *Wallet) sendSignedBody(
+	ctx context.Context,
+	seqno uint32,
+	signedBodyCell *boc.Cell,
+	init *tlb.StateInit,
+	waitingConfirmation time.Duration,
+) (ton.Bits256, error) {
 	extMsg, err := ton.CreateExternalMessage(w.address, signedBodyCell, init, tlb.VarUInteger16{})
 	if err != nil {
 		return ton.Bits256{}, fmt.Errorf("can not create external message: %v", err)
@@ -264,7 +325,7 @@ func (w *Wallet) RawSendV2(
 	if waitingConfirmation == 0 {
 		return msgHash, nil
 	}
-	if w.ver == HighLoadV2R2 {
+	if w.ver == HighLoadV2R2 || w.ver == HighLoadV3R1 {
 		return msgHash, fmt.Errorf("highload wallet doesn't support waiting confirmation")
 	}
 
@@ -399,6 +460,10 @@ type MessageConfig struct {
 	Seqno      uint32
 	ValidUntil time.Time
 	V5MsgType  V5MsgType
+	// HighloadV3QueryID is used by highload wallet v3 instead of the next id of the wallet's allocator.
+	HighloadV3QueryID *HighloadV3QueryID
+	// HighloadV3CreatedAt is used by highload wallet v3, a slightly lagging current time is used by default.
+	HighloadV3CreatedAt time.Time
 }
 
 func (w *Wallet) CreateMessageBody(msgConfig MessageConfig, messages ...Sendable) (*boc.Cell, error) {
@@ -420,6 +485,15 @@ func (w *Wallet) CreateMessageBody(msgConfig MessageConfig, messages ...Sendable
 	return signedBodyCell, nil
 }
 
+// EncryptComment encrypts a comment for the recipient's public key using the wallet's private key
+// and address. The result can be sent with EncryptedTransfer.
+func (w *Wallet) EncryptComment(comment string, recipientPubkey ed25519.PublicKey) (EncryptedComment, error) {
+	if w.key == nil {
+		return nil, errors.New("wallet has no private key")
+	}
+	return NewEncryptedComment(comment, w.key, w.address, recipientPubkey)
+}
+
 func (w *Wallet) GetPublicKey() ed25519.PublicKey {
 	return w.intWallet.GetPublicKey()
 }
@@ -438,6 +512,12 @@ func parseWalletData(ver Version, data boc.Cell) (seqno uint32, subWalletID uint
 			return 0, 0, nil, err
 		}
 		return d.Seqno, d.SubWalletId, append(ed25519.PublicKey{}, d.PublicKey[:]...), nil
+	case V3R2Lockup:
+		var d DataLockupV3R2
+		if err := tlb.Unmarshal(&data, &d); err != nil {
+			return 0, 0, nil, err
+		}
+		return d.Seqno, d.SubWalletId, append(ed25519.PublicKey{}, d.PublicKey[:]...), nil
 	case V4R1, V4R2:
 		var d DataV4
 		if err := tlb.Unmarshal(&data, &d); err != nil {
@@ -456,6 +536,12 @@ func parseWalletData(ver Version, data boc.Cell) (seqno uint32, subWalletID uint
 			return 0, 0, nil, err
 		}
 		return uint32(d.Seqno), 0, append(ed25519.PublicKey{}, d.PublicKey[:]...), nil
+	case HighLoadV3R1:
+		var d DataHighloadV3
+		if err := tlb.Unmarshal(&data, &d); err != nil {
+			return 0, 0, nil, err
+		}
+		return 0, d.SubWalletId, append(ed25519.PublicKey{}, d.PublicKey[:]...), nil
 	default:
 		return 0, 0, nil, fmt.Errorf("unsupported wallet version: %v", ver.ToString())
 	}
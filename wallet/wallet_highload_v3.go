@@ -0,0 +1,294 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// DefaultHighloadV3SubWallet is a subwallet id used by highload wallet v3 by default.
+	DefaultHighloadV3SubWallet = 0x10ad
+	// DefaultHighloadV3Timeout is a default lifetime of a highload wallet v3 message.
+	// Query ids are stored by the contract for this time, so it is a part of the wallet's address.
+	DefaultHighloadV3Timeout = time.Hour
+
+	// highloadV3CreatedAtOffset is subtracted from the current time to get created_at of a message,
+	// the contract rejects messages created in the future and validators' clocks can lag behind.
+	highloadV3CreatedAtOffset = 30 * time.Second
+	// highloadV3InternalTransferValue is attached to the message a wallet sends to itself
+	// to forward a batch of messages, the rest of it returns to the wallet's balance.
+	highloadV3InternalTransferValue = 100_000_000 // 0.1 TON
+
+	highloadV3MaxShift     = 1 << 13
+	highloadV3MaxBitNumber = 1023
+)
+
+var (
+	ErrHighloadV3QueryIDsExhausted = errors.New("all highload wallet v3 query ids are in use")
+)
+
+// DataHighloadV3 represents data of a highload-wallet-v3 contract.
+type DataHighloadV3 struct {
+	PublicKey     tlb.Bits256
+	SubWalletId   uint32
+	OldQueries    tlb.HashmapE[tlb.Uint13, tlb.Any]
+	Queries       tlb.HashmapE[tlb.Uint13, tlb.Any]
+	LastCleanTime uint64
+	Timeout       tlb.Uint22
+}
+
+// HighloadV3QueryID identifies a message of a highload wallet v3.
+// The contract rejects a message if its query id has been used within the last timeout.
+type HighloadV3QueryID struct {
+	Shift     tlb.Uint13
+	BitNumber tlb.Uint10
+}
+
+// NewHighloadV3QueryID returns a query id by its sequence number in range [0, 8192*1023).
+func NewHighloadV3QueryID(seq uint32) HighloadV3QueryID {
+	return HighloadV3QueryID{
+		Shift:     tlb.Uint13(seq / highloadV3MaxBitNumber % highloadV3MaxShift),
+		BitNumber: tlb.Uint10(seq % highloadV3MaxBitNumber),
+	}
+}
+
+// Seq returns a sequence number of the query id.
+func (id HighloadV3QueryID) Seq() uint32 {
+	return uint32(id.Shift)*highloadV3MaxBitNumber + uint32(id.BitNumber)
+}
+
+// HighloadV3Message is a message a highload wallet v3 receives signed by its owner.
+type HighloadV3Message struct {
+	SubWalletId   uint32
+	MessageToSend *boc.Cell `tlb:"^"`
+	SendMode      uint8
+	QueryID       HighloadV3QueryID
+	CreatedAt     uint64
+	Timeout       tlb.Uint22
+}
+
+// HighloadV3InternalTransfer is a body of a message a highload wallet v3 sends to itself
+// to forward several messages at once.
+type HighloadV3InternalTransfer struct {
+	Magic   tlb.Magic `tlb:"#ae42e5a4"`
+	QueryID uint64
+	Actions W5ActionList `tlb:"^"`
+}
+
+// HighloadV3QueryIDAllocator hands out query ids of a highload wallet v3 sequentially.
+// It remembers when each id was used and doesn't return it again until the contract forgets it,
+// which happens not earlier than two timeouts later.
+// An allocator is safe for concurrent use.
+type HighloadV3QueryIDAllocator struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	next    uint32
+	used    map[uint32]time.Time
+}
+
+// NewHighloadV3QueryIDAllocator returns an allocator for a wallet with the given timeout
+// which starts handing out ids from the given one.
+// A service restarting within the timeout should start after the last id it used.
+func NewHighloadV3QueryIDAllocator(timeout time.Duration, start HighloadV3QueryID) *HighloadV3QueryIDAllocator {
+	return &HighloadV3QueryIDAllocator{
+		timeout: timeout,
+		next:    start.Seq(),
+		used:    map[uint32]time.Time{},
+	}
+}
+
+// Next returns an id which hasn't been used within the last two timeouts and marks it as used at the given time.
+func (a *HighloadV3QueryIDAllocator) Next(now time.Time) (HighloadV3QueryID, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cleanup(now)
+	const total = highloadV3MaxShift * highloadV3MaxBitNumber
+	for i := 0; i < total; i++ {
+		seq := a.next
+		a.next = (a.next + 1) % total
+		if _, ok := a.used[seq]; ok {
+			continue
+		}
+		a.used[seq] = now
+		return NewHighloadV3QueryID(seq), nil
+	}
+	return HighloadV3QueryID{}, ErrHighloadV3QueryIDsExhausted
+}
+
+// MarkUsed marks the id as used at the given time, for example, by another instance of a service.
+func (a *HighloadV3QueryIDAllocator) MarkUsed(id HighloadV3QueryID, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if prev, ok := a.used[id.Seq()]; !ok || prev.Before(at) {
+		a.used[id.Seq()] = at
+	}
+}
+
+// IsUsed reports whether the id might still be stored by the contract at the given time.
+func (a *HighloadV3QueryIDAllocator) IsUsed(id HighloadV3QueryID, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	usedAt, ok := a.used[id.Seq()]
+	return ok && now.Sub(usedAt) < 2*a.timeout
+}
+
+func (a *HighloadV3QueryIDAllocator) cleanup(now time.Time) {
+	for seq, usedAt := range a.used {
+		if now.Sub(usedAt) >= 2*a.timeout {
+			delete(a.used, seq)
+		}
+	}
+}
+
+type walletHighloadV3 struct {
+	publicKey   ed25519.PublicKey
+	workchain   int
+	subWalletID uint32
+	timeout     time.Duration
+	queryIDs    *HighloadV3QueryIDAllocator
+	address     ton.AccountID
+}
+
+var _ wallet = &walletHighloadV3{}
+
+func newWalletHighloadV3(key ed25519.PublicKey, options Options) (*walletHighloadV3, error) {
+	timeout := defaultOr(options.HighloadV3Timeout, DefaultHighloadV3Timeout)
+	if timeout < time.Second || timeout >= (1<<22)*time.Second {
+		return nil, fmt.Errorf("invalid highload wallet v3 timeout: %v", timeout)
+	}
+	w := &walletHighloadV3{
+		publicKey:   key,
+		workchain:   defaultOr(options.Workchain, 0),
+		subWalletID: defaultOr(options.SubWalletID, uint32(DefaultHighloadV3SubWallet)),
+		timeout:     timeout,
+		queryIDs:    options.HighloadV3QueryIDs,
+	}
+	if w.queryIDs == nil {
+		w.queryIDs = NewHighloadV3QueryIDAllocator(timeout, HighloadV3QueryID{})
+	}
+	address, err := w.generateAddress()
+	if err != nil {
+		return nil, err
+	}
+	w.address = address
+	return w, nil
+}
+
+func (w *walletHighloadV3) generateAddress() (ton.AccountID, error) {
+	stateInit, err := w.generateStateInit()
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	return generateAddress(w.workchain, *stateInit)
+}
+
+func (w *walletHighloadV3) generateStateInit() (*tlb.StateInit, error) {
+	data := DataHighloadV3{
+		PublicKey:   publicKeyToBits(w.publicKey),
+		SubWalletId: w.subWalletID,
+		Timeout:     tlb.Uint22(w.timeout / time.Second),
+	}
+	return generateStateInit(HighLoadV3R1, data)
+}
+
+func (w *walletHighloadV3) MaxMessageNumber() int {
+	return 254
+}
+
+func (w *walletHighloadV3) CreateMsgBodyWithoutSignature(internalMessages []RawMessage, msgConfig MessageConfig) (*boc.Cell, error) {
+	if len(internalMessages) == 0 {
+		return nil, fmt.Errorf("highload wallet v3 requires at least one message")
+	}
+	if len(internalMessages) > w.MaxMessageNumber() {
+		return nil, fmt.Errorf("highload wallet v3 supports up to %v messages", w.MaxMessageNumber())
+	}
+	now := time.Now()
+	var queryID HighloadV3QueryID
+	if msgConfig.HighloadV3QueryID != nil {
+		queryID = *msgConfig.HighloadV3QueryID
+		w.queryIDs.MarkUsed(queryID, now)
+	} else {
+		var err error
+		queryID, err = w.queryIDs.Next(now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	createdAt := msgConfig.HighloadV3CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now.Add(-highloadV3CreatedAtOffset)
+	}
+	msg := HighloadV3Message{
+		SubWalletId:   w.subWalletID,
+		MessageToSend: internalMessages[0].Message,
+		SendMode:      internalMessages[0].Mode,
+		QueryID:       queryID,
+		CreatedAt:     uint64(createdAt.Unix()),
+		Timeout:       tlb.Uint22(w.timeout / time.Second),
+	}
+	if len(internalMessages) > 1 {
+		transfer, err := w.internalTransfer(internalMessages, queryID)
+		if err != nil {
+			return nil, err
+		}
+		msg.MessageToSend = transfer.Message
+		msg.SendMode = transfer.Mode
+	}
+	bodyCell := boc.NewCell()
+	if err := tlb.Marshal(bodyCell, msg); err != nil {
+		return nil, err
+	}
+	return bodyCell, nil
+}
+
+// internalTransfer creates a message to the wallet itself which makes the wallet send the given messages.
+func (w *walletHighloadV3) internalTransfer(internalMessages []RawMessage, queryID HighloadV3QueryID) (RawMessage, error) {
+	body := boc.NewCell()
+	transfer := HighloadV3InternalTransfer{
+		QueryID: uint64(queryID.Seq()),
+		Actions: newW5Actions(internalMessages),
+	}
+	if err := tlb.Marshal(body, transfer); err != nil {
+		return RawMessage{}, err
+	}
+	return ToRawMessage(Message{
+		Amount:  highloadV3InternalTransferValue,
+		Address: w.address,
+		Body:    body,
+		Mode:    DefaultMessageMode,
+	})
+}
+
+func (w *walletHighloadV3) AttachSignature(body *boc.Cell, signature tlb.Bits512) (*boc.Cell, error) {
+	cell := boc.NewCell()
+	if err := cell.WriteBytes(signature[:]); err != nil {
+		return nil, err
+	}
+	if err := cell.AddRef(body); err != nil {
+		return nil, err
+	}
+	return cell, nil
+}
+
+func (w *walletHighloadV3) NextMessageParams(state tlb.ShardAccount) (NextMsgParams, error) {
+	initRequired := state.Account.Status() == tlb.AccountUninit || state.Account.Status() == tlb.AccountNone
+	if !initRequired {
+		return NextMsgParams{}, nil
+	}
+	stateInit, err := w.generateStateInit()
+	if err != nil {
+		return NextMsgParams{}, err
+	}
+	return NextMsgParams{Init: stateInit}, nil
+}
+
+func (w *walletHighloadV3) GetPublicKey() ed25519.PublicKey {
+	return w.publicKey
+}
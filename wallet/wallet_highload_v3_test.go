@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tontest"
+)
+
+func TestHighloadV3QueryIDAllocator(t *testing.T) {
+	now := time.Now()
+	a := NewHighloadV3QueryIDAllocator(time.Minute, NewHighloadV3QueryID(1022))
+
+	first, err := a.Next(now)
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if first != (HighloadV3QueryID{Shift: 0, BitNumber: 1022}) {
+		t.Fatalf("unexpected first id: %+v", first)
+	}
+	second, err := a.Next(now)
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if second != (HighloadV3QueryID{Shift: 1, BitNumber: 0}) {
+		t.Fatalf("unexpected second id: %+v", second)
+	}
+	if !a.IsUsed(first, now.Add(time.Minute)) {
+		t.Fatalf("id must be used within two timeouts")
+	}
+	if a.IsUsed(first, now.Add(2*time.Minute)) {
+		t.Fatalf("id must be released after two timeouts")
+	}
+
+	a = NewHighloadV3QueryIDAllocator(time.Minute, NewHighloadV3QueryID(highloadV3MaxShift*highloadV3MaxBitNumber-1))
+	a.MarkUsed(HighloadV3QueryID{}, now)
+	if _, err := a.Next(now); err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	id, err := a.Next(now)
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if id.Seq() != 1 {
+		t.Fatalf("allocator must wrap around and skip used ids, got %v", id.Seq())
+	}
+}
+
+func TestWalletHighloadV3(t *testing.T) {
+	if hash := GetCodeHashByVer(HighLoadV3R1); hash.Hex() != "11acad7955844090f283bf238bc1449871f783e7cc0979408d3f4859483e8525" {
+		t.Fatalf("unexpected code hash: %v", hash.Hex())
+	}
+	key := ed25519.NewKeyFromSeed(make([]byte, 32))
+	blockchain, messages := NewMockBlockchain(0, tontest.Account().Balance(10_000_000_000).Address(ton.AccountID{}).MustShardAccount())
+	w, err := New(key, HighLoadV3R1, blockchain, WithHighloadV3Timeout(10*time.Minute))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defaultWallet, err := NewFromPublicKey(key.Public().(ed25519.PublicKey), HighLoadV3R1)
+	if err != nil {
+		t.Fatalf("NewFromPublicKey() failed: %v", err)
+	}
+	if defaultWallet.GetAddress() == w.GetAddress() {
+		t.Fatalf("timeout must change the wallet's address")
+	}
+	stateInit, err := w.StateInit()
+	if err != nil {
+		t.Fatalf("StateInit() failed: %v", err)
+	}
+	restored, _, err := NewFromCodeAndData(stateInit.Code.Value.Value, stateInit.Data.Value.Value)
+	if err != nil {
+		t.Fatalf("NewFromCodeAndData() failed: %v", err)
+	}
+	if restored.GetAddress() != w.GetAddress() || restored.GetVersion() != HighLoadV3R1 {
+		t.Fatalf("restored wallet mismatch: %v", restored.GetAddress())
+	}
+
+	recipient := ton.MustParseAccountID("0:507dea7d606f22d9e85678d3eede39bbe133a868d2a0e3e07f5502cb70b8a512")
+	for _, count := range []int{1, 3} {
+		transfers := make([]Sendable, count)
+		for i := range transfers {
+			transfers[i] = SimpleTransfer{Amount: tlb.Grams(1000 + i), Address: recipient}
+		}
+		if _, err := w.SendV2(context.Background(), 0, transfers...); err != nil {
+			t.Fatalf("SendV2() failed: %v", err)
+		}
+		cells, err := boc.DeserializeBoc(<-messages)
+		if err != nil {
+			t.Fatalf("DeserializeBoc() failed: %v", err)
+		}
+		if err := VerifySignature(HighLoadV3R1, cells[0], key.Public().(ed25519.PublicKey)); err != nil {
+			t.Fatalf("VerifySignature() failed: %v", err)
+		}
+		cells[0].ResetCounters()
+		raw, err := ExtractRawMessages(HighLoadV3R1, cells[0])
+		if err != nil {
+			t.Fatalf("ExtractRawMessages() failed: %v", err)
+		}
+		if len(raw) != count {
+			t.Fatalf("want %v messages, got %v", count, len(raw))
+		}
+		for i, m := range raw {
+			var msg tlb.Message
+			if err := tlb.Unmarshal(m.Message, &msg); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			if msg.Info.IntMsgInfo.Value.Grams != tlb.Grams(1000+i) {
+				t.Fatalf("unexpected message %v value: %v", i, msg.Info.IntMsgInfo.Value.Grams)
+			}
+		}
+		cells[0].ResetCounters()
+		hl, err := DecodeHighloadV3Message(cells[0])
+		if err != nil {
+			t.Fatalf("DecodeHighloadV3Message() failed: %v", err)
+		}
+		if hl.SubWalletId != DefaultHighloadV3SubWallet || hl.Timeout != 600 {
+			t.Fatalf("unexpected message: %+v", hl)
+		}
+	}
+}
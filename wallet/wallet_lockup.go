@@ -0,0 +1,222 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// ErrLockupConfigRequired is returned when the address of a lockup wallet can't be derived without its initial config.
+var ErrLockupConfigRequired = errors.New("initial lockup config is required")
+
+// DataLockupV3R2 represents data of a universal lockup wallet contract.
+// Locked funds can't be spent until their unlock time,
+// restricted funds can only be sent to allowed destinations until their unlock time.
+// Locked and Restricted map unlock times to amounts.
+type DataLockupV3R2 struct {
+	Seqno                uint32
+	SubWalletId          uint32
+	PublicKey            tlb.Bits256
+	ConfigPublicKey      tlb.Bits256
+	AllowedDestinations  tlb.PfxHashmapE[tlb.InternalAddress, struct{}]
+	TotalLockedValue     tlb.Grams
+	Locked               tlb.HashmapE[tlb.Uint32, tlb.Grams]
+	TotalRestrictedValue tlb.Grams
+	Restricted           tlb.HashmapE[tlb.Uint32, tlb.Grams]
+}
+
+// LockupTranche is an amount unlocked at the given time.
+type LockupTranche struct {
+	UnlockAt time.Time
+	Amount   tlb.Grams
+}
+
+// LockupConfig describes initial data of a lockup wallet.
+type LockupConfig struct {
+	// ConfigPublicKey is a key of a party allowed to add locked and restricted funds to the wallet.
+	ConfigPublicKey ed25519.PublicKey
+	// AllowedDestinations are prefixes of addresses restricted funds can be sent to,
+	// see LockupDestination and LockupWorkchainDestination.
+	AllowedDestinations []boc.BitString
+	Locked              []LockupTranche
+	Restricted          []LockupTranche
+}
+
+// LockupBalances is a breakdown of a lockup wallet's balance at some moment.
+type LockupBalances struct {
+	// Locked can't be spent.
+	Locked tlb.Grams
+	// Restricted can only be sent to allowed destinations.
+	Restricted tlb.Grams
+	// Unlocked can be sent anywhere.
+	Unlocked tlb.Grams
+}
+
+// LockupDestination returns a key of allowed destinations matching the given account only.
+func LockupDestination(account ton.AccountID) boc.BitString {
+	c := boc.NewCell()
+	if err := tlb.Marshal(c, account.ToMsgAddress()); err != nil {
+		panic(err)
+	}
+	return c.RawBitString()
+}
+
+// LockupWorkchainDestination returns a key of allowed destinations matching all accounts of the given workchain.
+// For example, -1 allows sending restricted funds to the elector to take part in validation.
+func LockupWorkchainDestination(workchain int32) boc.BitString {
+	s := boc.NewBitString(11)
+	// addr_std$10 anycast:(Maybe Anycast) workchain_id:int8
+	if err := s.WriteUint(0b100, 3); err != nil {
+		panic(err)
+	}
+	if err := s.WriteInt(int64(workchain), 8); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// IsAllowedDestination reports whether restricted funds can be sent to the given account.
+func (d DataLockupV3R2) IsAllowedDestination(account ton.AccountID) bool {
+	_, ok := d.AllowedDestinations.LookupPrefix(LockupDestination(account))
+	return ok
+}
+
+// Balances splits the given balance of a wallet into locked, restricted and unlocked parts at the given time.
+func (d DataLockupV3R2) Balances(balance tlb.Grams, at time.Time) LockupBalances {
+	locked := lockedAt(d.Locked, at)
+	restricted := lockedAt(d.Restricted, at)
+	balances := LockupBalances{
+		Locked:     min(locked, balance),
+		Restricted: min(restricted, balance-min(locked, balance)),
+	}
+	balances.Unlocked = balance - balances.Locked - balances.Restricted
+	return balances
+}
+
+func lockedAt(tranches tlb.HashmapE[tlb.Uint32, tlb.Grams], at time.Time) tlb.Grams {
+	var total tlb.Grams
+	for _, item := range tranches.Items() {
+		if int64(item.Key) > at.Unix() {
+			total += item.Value
+		}
+	}
+	return total
+}
+
+// GetLockupBalances returns a breakdown of a lockup wallet's balance at the given time.
+func GetLockupBalances(state tlb.ShardAccount, at time.Time) (LockupBalances, error) {
+	if state.Account.Status() != tlb.AccountActive {
+		return LockupBalances{}, ErrAccountIsNotInitialized
+	}
+	var data DataLockupV3R2
+	cell := boc.Cell(state.Account.Account.Storage.State.AccountActive.StateInit.Data.Value.Value)
+	if err := tlb.Unmarshal(&cell, &data); err != nil {
+		return LockupBalances{}, err
+	}
+	return data.Balances(state.Account.Account.Storage.Balance.Grams, at), nil
+}
+
+func newLockupTranches(tranches []LockupTranche) (tlb.HashmapE[tlb.Uint32, tlb.Grams], tlb.Grams) {
+	var m tlb.HashmapE[tlb.Uint32, tlb.Grams]
+	var total tlb.Grams
+	for _, t := range tranches {
+		key := tlb.Uint32(t.UnlockAt.Unix())
+		value, _ := m.Get(key)
+		m.Put(key, value+t.Amount)
+		total += t.Amount
+	}
+	return m, total
+}
+
+type walletLockupV3R2 struct {
+	publicKey   ed25519.PublicKey
+	workchain   int
+	subWalletID uint32
+	config      LockupConfig
+}
+
+var _ wallet = &walletLockupV3R2{}
+
+func newWalletLockupV3R2(key ed25519.PublicKey, options Options) *walletLockupV3R2 {
+	workchain := defaultOr(options.Workchain, 0)
+	subWalletID := defaultOr(options.SubWalletID, uint32(DefaultSubWallet+workchain))
+	return &walletLockupV3R2{
+		publicKey:   key,
+		workchain:   workchain,
+		subWalletID: subWalletID,
+		config:      defaultOr(options.Lockup, LockupConfig{}),
+	}
+}
+
+func (w *walletLockupV3R2) generateAddress() (ton.AccountID, error) {
+	stateInit, err := w.generateStateInit()
+	if err != nil {
+		return ton.AccountID{}, err
+	}
+	return generateAddress(w.workchain, *stateInit)
+}
+
+func (w *walletLockupV3R2) generateStateInit() (*tlb.StateInit, error) {
+	if len(w.config.ConfigPublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("lockup wallet requires a config public key")
+	}
+	destinations := make([]struct{}, len(w.config.AllowedDestinations))
+	data := DataLockupV3R2{
+		SubWalletId:         w.subWalletID,
+		PublicKey:           publicKeyToBits(w.publicKey),
+		ConfigPublicKey:     publicKeyToBits(w.config.ConfigPublicKey),
+		AllowedDestinations: tlb.NewPfxHashmapE[tlb.InternalAddress](w.config.AllowedDestinations, destinations),
+	}
+	data.Locked, data.TotalLockedValue = newLockupTranches(w.config.Locked)
+	data.Restricted, data.TotalRestrictedValue = newLockupTranches(w.config.Restricted)
+	return generateStateInit(V3R2Lockup, data)
+}
+
+func (w *walletLockupV3R2) MaxMessageNumber() int {
+	return 4
+}
+
+func (w *walletLockupV3R2) CreateMsgBodyWithoutSignature(internalMessages []RawMessage, msgConfig MessageConfig) (*boc.Cell, error) {
+	body := MessageV3{
+		SubWalletId: w.subWalletID,
+		ValidUntil:  uint32(msgConfig.ValidUntil.Unix()),
+		Seqno:       msgConfig.Seqno,
+		RawMessages: PayloadV1toV4(internalMessages),
+	}
+	bodyCell := boc.NewCell()
+	if err := tlb.Marshal(bodyCell, body); err != nil {
+		return nil, err
+	}
+	return bodyCell, nil
+}
+
+func (w *walletLockupV3R2) AttachSignature(body *boc.Cell, signature tlb.Bits512) (*boc.Cell, error) {
+	return attachSignatureAsSignedMsgBody(body, signature)
+}
+
+func (w *walletLockupV3R2) NextMessageParams(state tlb.ShardAccount) (NextMsgParams, error) {
+	if state.Account.Status() == tlb.AccountActive {
+		var data DataLockupV3R2
+		cell := boc.Cell(state.Account.Account.Storage.State.AccountActive.StateInit.Data.Value.Value)
+		if err := tlb.Unmarshal(&cell, &data); err != nil {
+			return NextMsgParams{}, err
+		}
+		return NextMsgParams{
+			Seqno: data.Seqno,
+		}, nil
+	}
+	init, err := w.generateStateInit()
+	if err != nil {
+		return NextMsgParams{}, err
+	}
+	return NextMsgParams{Init: init}, nil
+}
+
+func (w *walletLockupV3R2) GetPublicKey() ed25519.PublicKey {
+	return w.publicKey
+}
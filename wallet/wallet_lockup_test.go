@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+	"github.com/tonkeeper/tongo/tontest"
+)
+
+func TestWalletLockupV3R2(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, 32))
+	configKey := ed25519.NewKeyFromSeed(append(make([]byte, 31), 1)).Public().(ed25519.PublicKey)
+	elector := ton.MustParseAccountID("-1:3333333333333333333333333333333333333333333333333333333333333333")
+	nominator := ton.MustParseAccountID("0:507dea7d606f22d9e85678d3eede39bbe133a868d2a0e3e07f5502cb70b8a512")
+	start := time.Unix(1700000000, 0)
+	config := LockupConfig{
+		ConfigPublicKey:     configKey,
+		AllowedDestinations: []boc.BitString{LockupWorkchainDestination(-1), LockupDestination(nominator)},
+		Locked: []LockupTranche{
+			{UnlockAt: start.Add(time.Hour), Amount: 100},
+			{UnlockAt: start.Add(2 * time.Hour), Amount: 200},
+		},
+		Restricted: []LockupTranche{
+			{UnlockAt: start.Add(3 * time.Hour), Amount: 50},
+		},
+	}
+	if _, err := NewFromPublicKey(key.Public().(ed25519.PublicKey), V3R2Lockup); err == nil {
+		t.Fatalf("lockup wallet without config must fail")
+	}
+	w, err := NewFromPublicKey(key.Public().(ed25519.PublicKey), V3R2Lockup, WithLockupConfig(config))
+	if err != nil {
+		t.Fatalf("NewFromPublicKey() failed: %v", err)
+	}
+	stateInit, err := w.StateInit()
+	if err != nil {
+		t.Fatalf("StateInit() failed: %v", err)
+	}
+	if _, _, err := NewFromCodeAndData(stateInit.Code.Value.Value, stateInit.Data.Value.Value); err != ErrLockupConfigRequired {
+		t.Fatalf("want ErrLockupConfigRequired, got: %v", err)
+	}
+	restored, seqno, err := NewFromCodeAndData(stateInit.Code.Value.Value, stateInit.Data.Value.Value, WithLockupConfig(config))
+	if err != nil {
+		t.Fatalf("NewFromCodeAndData() failed: %v", err)
+	}
+	if restored.GetAddress() != w.GetAddress() || restored.GetVersion() != V3R2Lockup || seqno != 0 {
+		t.Fatalf("restored wallet mismatch: %v", restored.GetAddress())
+	}
+	if s := V3R2Lockup.ToString(); s != "v3R2_lockup" {
+		t.Fatalf("unexpected version name: %v", s)
+	}
+	if v, err := VersionFromString("v3R2_lockup"); err != nil || v != V3R2Lockup {
+		t.Fatalf("VersionFromString() failed: %v", err)
+	}
+
+	data := stateInit.Data.Value.Value
+	var d DataLockupV3R2
+	if err := tlb.Unmarshal(&data, &d); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if d.TotalLockedValue != 300 || d.TotalRestrictedValue != 50 {
+		t.Fatalf("unexpected totals: %v %v", d.TotalLockedValue, d.TotalRestrictedValue)
+	}
+	if !d.IsAllowedDestination(elector) || !d.IsAllowedDestination(nominator) || d.IsAllowedDestination(ton.AccountID{}) {
+		t.Fatalf("unexpected allowed destinations")
+	}
+	tests := []struct {
+		at      time.Time
+		balance tlb.Grams
+		want    LockupBalances
+	}{
+		{at: start, balance: 1000, want: LockupBalances{Locked: 300, Restricted: 50, Unlocked: 650}},
+		{at: start.Add(time.Hour), balance: 1000, want: LockupBalances{Locked: 200, Restricted: 50, Unlocked: 750}},
+		{at: start.Add(3 * time.Hour), balance: 1000, want: LockupBalances{Unlocked: 1000}},
+		{at: start, balance: 320, want: LockupBalances{Locked: 300, Restricted: 20}},
+	}
+	for _, tt := range tests {
+		if got := d.Balances(tt.balance, tt.at); got != tt.want {
+			t.Errorf("Balances(%v, %v): want %+v, got %+v", tt.balance, tt.at, tt.want, got)
+		}
+	}
+
+	blockchain, messages := NewMockBlockchain(0, tontest.Account().Balance(1000).Address(w.GetAddress()).MustShardAccount())
+	w, err = New(key, V3R2Lockup, blockchain, WithLockupConfig(config))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := w.SendV2(context.Background(), 0, SimpleTransfer{Amount: 10, Address: nominator}); err != nil {
+		t.Fatalf("SendV2() failed: %v", err)
+	}
+	cells, err := boc.DeserializeBoc(<-messages)
+	if err != nil {
+		t.Fatalf("DeserializeBoc() failed: %v", err)
+	}
+	if err := VerifySignature(V3R2Lockup, cells[0], key.Public().(ed25519.PublicKey)); err != nil {
+		t.Fatalf("VerifySignature() failed: %v", err)
+	}
+	cells[0].ResetCounters()
+	raw, err := ExtractRawMessages(V3R2Lockup, cells[0])
+	if err != nil || len(raw) != 1 {
+		t.Fatalf("ExtractRawMessages() failed: %v", err)
+	}
+}
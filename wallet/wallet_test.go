@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 
 	"github.com/tonkeeper/tongo/boc"
@@ -90,6 +91,39 @@ func TestLongCommentSerialization(t *testing.T) {
 	}
 }
 
+func TestEncryptedCommentSerialization(t *testing.T) {
+	sender, err := New(ed25519.NewKeyFromSeed(make([]byte, 32)), V4R2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipientPubkey, recipientKey, _ := ed25519.GenerateKey(nil)
+	comment := strings.Repeat("The Quick Brown Fox Jumps Over The Lazy Dog ", 10)
+	encrypted, err := sender.EncryptComment(comment, recipientPubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _, err := EncryptedTransfer{Comment: encrypted}.ToInternal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := boc.Cell(msg.Body.Value)
+	if body.RefsSize() == 0 {
+		t.Fatal("long encrypted comment should be stored in snake cells")
+	}
+	var decoded EncryptedComment
+	err = tlb.Unmarshal(&body, &decoded)
+	if err != nil {
+		t.Fatalf("encrypted comment deserialization error: %v", err)
+	}
+	text, err := decoded.Decrypt(recipientKey, sender.GetAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != comment {
+		t.Fatal("EncryptedComment invalid serialization/deserialization")
+	}
+}
+
 func TestSimpleSend(t *testing.T) {
 	t.Skip()
 	recipientAddr, _ := ton.AccountIDFromRaw("0:507dea7d606f22d9e85678d3eede39bbe133a868d2a0e3e07f5502cb70b8a512")
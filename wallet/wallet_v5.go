@@ -1,8 +1,11 @@
 package wallet
 
 import (
+	"context"
 	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/tonkeeper/tongo/boc"
 	"github.com/tonkeeper/tongo/tlb"
@@ -185,3 +188,162 @@ func GetW5R1ExtensionsList(state tlb.ShardAccount, workchain int) (map[ton.Accou
 }
 
 var _ wallet = &walletV5R1{}
+
+// ErrExtendedActionsNotSupported is returned when extended actions are used with a wallet other than V5R1.
+var ErrExtendedActionsNotSupported = errors.New("extended actions are supported by wallet v5r1 only")
+
+type extV5R1ExtensionRequest struct {
+	QueryID         uint64
+	Actions         *W5ActionList      `tlb:"maybe^"`
+	ExtendedActions *W5ExtendedActions `tlb:"maybe"`
+}
+
+// W5ExtensionRequest is a request an extension sends to a wallet v5r1 in an internal message.
+// The wallet executes it only if the sender is one of its extensions.
+type W5ExtensionRequest struct {
+	QueryID         uint64
+	Messages        []Sendable
+	ExtendedActions W5ExtendedActions
+}
+
+// ToCell returns a body of an internal message carrying the request.
+func (r W5ExtensionRequest) ToCell() (*boc.Cell, error) {
+	req := extV5R1ExtensionRequest{QueryID: r.QueryID}
+	if len(r.Messages) > 0 {
+		msgArray := make([]RawMessage, 0, len(r.Messages))
+		for _, m := range r.Messages {
+			rawMsg, err := ToRawMessage(m)
+			if err != nil {
+				return nil, err
+			}
+			msgArray = append(msgArray, rawMsg)
+		}
+		actions := newW5Actions(msgArray)
+		req.Actions = &actions
+	}
+	if len(r.ExtendedActions) > 0 {
+		req.ExtendedActions = &r.ExtendedActions
+	}
+	body := boc.NewCell()
+	if err := body.WriteUint(uint64(V5MsgTypeExtensionAction), 32); err != nil {
+		return nil, err
+	}
+	if err := tlb.Marshal(body, req); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// NewW5AddExtensionAction returns an extended action which adds the given extension.
+func NewW5AddExtensionAction(extension ton.AccountID) W5ExtendedAction {
+	return W5ExtendedAction{
+		SumType:      "AddExtension",
+		AddExtension: &struct{ Addr tlb.MsgAddress }{Addr: extension.ToMsgAddress()},
+	}
+}
+
+// NewW5RemoveExtensionAction returns an extended action which removes the given extension.
+func NewW5RemoveExtensionAction(extension ton.AccountID) W5ExtendedAction {
+	return W5ExtendedAction{
+		SumType:         "RemoveExtension",
+		RemoveExtension: &struct{ Addr tlb.MsgAddress }{Addr: extension.ToMsgAddress()},
+	}
+}
+
+// NewW5SetSignatureAllowedAction returns an extended action which enables or disables
+// authentication of requests by the wallet's signature.
+func NewW5SetSignatureAllowedAction(allowed bool) W5ExtendedAction {
+	return W5ExtendedAction{
+		SumType:             "SetSignatureAllowed",
+		SetSignatureAllowed: &struct{ Allowed bool }{Allowed: allowed},
+	}
+}
+
+// ExtensionRequest returns a message an extension of this wallet sends to execute the request.
+// The amount pays for processing the request and for the request's messages with no balance of their own.
+func (w *Wallet) ExtensionRequest(amount tlb.Grams, request W5ExtensionRequest) (Message, error) {
+	if w.ver != V5R1 {
+		return Message{}, ErrExtendedActionsNotSupported
+	}
+	body, err := request.ToCell()
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Amount:  amount,
+		Address: w.address,
+		Body:    body,
+		Bounce:  true,
+		Mode:    DefaultMessageMode,
+	}, nil
+}
+
+// AddExtension sends a signed external message which adds the given extension to the wallet.
+// The extension must be in the same workchain as the wallet.
+func (w *Wallet) AddExtension(ctx context.Context, extension ton.AccountID) (ton.Bits256, error) {
+	if extension.Workchain != w.address.Workchain {
+		return ton.Bits256{}, fmt.Errorf("extension must be in workchain %v", w.address.Workchain)
+	}
+	return w.SendExtendedActionsV2(ctx, 0, W5ExtendedActions{NewW5AddExtensionAction(extension)})
+}
+
+// RemoveExtension sends a signed external message which removes the given extension from the wallet.
+func (w *Wallet) RemoveExtension(ctx context.Context, extension ton.AccountID) (ton.Bits256, error) {
+	return w.SendExtendedActionsV2(ctx, 0, W5ExtendedActions{NewW5RemoveExtensionAction(extension)})
+}
+
+// SetSignatureAuthAllowed sends a signed external message which enables or disables
+// authentication by the wallet's signature.
+// Signature authentication can be disabled only if the wallet has at least one extension,
+// after that the wallet is controlled by its extensions only.
+func (w *Wallet) SetSignatureAuthAllowed(ctx context.Context, allowed bool) (ton.Bits256, error) {
+	return w.SendExtendedActionsV2(ctx, 0, W5ExtendedActions{NewW5SetSignatureAllowedAction(allowed)})
+}
+
+// SendExtendedActionsV2 sends a signed external message with the given extended actions and internal messages.
+// The wallet executes internal messages first and extended actions after them.
+func (w *Wallet) SendExtendedActionsV2(ctx context.Context, waitingConfirmation time.Duration, actions W5ExtendedActions, messages ...Sendable) (ton.Bits256, error) {
+	v5, ok := w.intWallet.(*walletV5R1)
+	if !ok {
+		return ton.Bits256{}, ErrExtendedActionsNotSupported
+	}
+	if w.blockchain == nil {
+		return ton.Bits256{}, errors.New("blockchain interface is nil")
+	}
+	if w.key == nil {
+		return ton.Bits256{}, errors.New("wallet has no private key")
+	}
+	if len(messages) > w.MaxMessageNumber() {
+		return ton.Bits256{}, fmt.Errorf("%v wallet support up to %v internal messages", w.ver, w.MaxMessageNumber())
+	}
+	state, err := w.blockchain.GetAccountState(ctx, w.GetAddress())
+	if err != nil {
+		return ton.Bits256{}, fmt.Errorf("get account state failed: %v", err)
+	}
+	params, err := v5.NextMessageParams(state)
+	if err != nil {
+		return ton.Bits256{}, err
+	}
+	msgArray := make([]RawMessage, 0, len(messages))
+	for _, m := range messages {
+		rawMsg, err := ToRawMessage(m)
+		if err != nil {
+			return ton.Bits256{}, err
+		}
+		msgArray = append(msgArray, rawMsg)
+	}
+	var extendedActions *W5ExtendedActions
+	if len(actions) > 0 {
+		extendedActions = &actions
+	}
+	msgConfig := MessageConfig{
+		Seqno:      params.Seqno,
+		ValidUntil: time.Now().Add(w.msgDefaultLifetime),
+		V5MsgType:  V5MsgTypeSignedExternal,
+	}
+	body, err := v5.CreateSignedMsgBodyCell(w.key, msgArray, extendedActions, msgConfig)
+	if err != nil {
+		return ton.Bits256{}, fmt.Errorf("can not marshal wallet message body: %v", err)
+	}
+	return w.sendSignedBody(ctx, params.Seqno, body, params.Init, waitingConfirmation)
+}
@@ -357,3 +357,166 @@ func (s walletV5OrderTestAccountSource) GetMasterchainInfo(_ context.Context) (l
 		Last: liteclient.TonNodeBlockIdExtC{Seqno: 1},
 	}, nil
 }
+
+func TestWalletV5R1ExtendedActions(t *testing.T) {
+	privateKey, err := SeedToPrivateKey(RandomSeed())
+	require.NoError(t, err)
+	extension := ton.MustParseAccountID("0:00000000000000000000000000000000000000000000000000000000000000e1")
+	blockchain, messages := NewMockBlockchain(0, tontest.Account().Balance(1_000_000_000).Address(ton.AccountID{}).MustShardAccount())
+	w, err := New(privateKey, V5R1, blockchain)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		send func() (ton.Bits256, error)
+		want W5ExtendedAction
+	}{
+		{
+			name: "add extension",
+			send: func() (ton.Bits256, error) { return w.AddExtension(context.Background(), extension) },
+			want: NewW5AddExtensionAction(extension),
+		},
+		{
+			name: "remove extension",
+			send: func() (ton.Bits256, error) { return w.RemoveExtension(context.Background(), extension) },
+			want: NewW5RemoveExtensionAction(extension),
+		},
+		{
+			name: "disable signature",
+			send: func() (ton.Bits256, error) { return w.SetSignatureAuthAllowed(context.Background(), false) },
+			want: NewW5SetSignatureAllowedAction(false),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.send()
+			require.NoError(t, err)
+			cells, err := boc.DeserializeBoc(<-messages)
+			require.NoError(t, err)
+			require.NoError(t, VerifySignature(V5R1, cells[0], privateKey.Public().(ed25519.PublicKey)))
+			cells[0].ResetCounters()
+			var msg tlb.Message
+			require.NoError(t, tlb.Unmarshal(cells[0], &msg))
+			body := boc.Cell(msg.Body.Value)
+			var v5 MessageV5
+			require.NoError(t, tlb.Unmarshal(&body, &v5))
+			require.Equal(t, "SignedExternal", string(v5.SumType))
+			// an empty action list is still attached as before extended actions were supported
+			require.NotNil(t, v5.SignedExternal.Actions)
+			assert.Empty(t, v5.RawMessages())
+			require.NotNil(t, v5.SignedExternal.ExtendedActions)
+			assert.Equal(t, W5ExtendedActions{tt.want}, *v5.SignedExternal.ExtendedActions)
+		})
+	}
+
+	_, err = w.AddExtension(context.Background(), ton.MustParseAccountID("-1:00000000000000000000000000000000000000000000000000000000000000e1"))
+	assert.Error(t, err)
+	v4, err := New(privateKey, V4R2, blockchain)
+	require.NoError(t, err)
+	_, err = v4.AddExtension(context.Background(), extension)
+	assert.ErrorIs(t, err, ErrExtendedActionsNotSupported)
+
+	recipient := ton.MustParseAccountID("0:00000000000000000000000000000000000000000000000000000000000000a1")
+	request, err := w.ExtensionRequest(50_000_000, W5ExtensionRequest{
+		QueryID:         7,
+		Messages:        []Sendable{SimpleTransfer{Amount: 10_000_000, Address: recipient}},
+		ExtendedActions: W5ExtendedActions{NewW5SetSignatureAllowedAction(false)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, w.GetAddress(), request.Address)
+	var v5 MessageV5
+	require.NoError(t, tlb.Unmarshal(request.Body, &v5))
+	require.Equal(t, "ExtensionAction", string(v5.SumType))
+	assert.Equal(t, uint64(7), v5.ExtensionAction.QueryID)
+	require.NotNil(t, v5.ExtensionAction.Actions)
+	assert.Len(t, *v5.ExtensionAction.Actions, 1)
+	require.NotNil(t, v5.ExtensionAction.ExtendedActions)
+	assert.Len(t, *v5.ExtensionAction.ExtendedActions, 1)
+}
+
+func TestW5ExtensionsEmulated(t *testing.T) {
+	privateKey, err := SeedToPrivateKey(RandomSeed())
+	require.NoError(t, err)
+	extensionKey, err := SeedToPrivateKey(RandomSeed())
+	require.NoError(t, err)
+	activeState := func(w Wallet, balance tlb.Grams) tlb.ShardAccount {
+		stateInit, err := w.StateInit()
+		require.NoError(t, err)
+		return tontest.Account().
+			Address(w.GetAddress()).
+			State(tlb.AccountActive).
+			Balance(balance).
+			StateInit(&stateInit.Code.Value.Value, &stateInit.Data.Value.Value).
+			MustShardAccount()
+	}
+	ext, err := New(extensionKey, V5R1, nil)
+	require.NoError(t, err)
+	extState := activeState(ext, 1_000_000_000)
+	blockchain, messages := NewMockBlockchain(0, tontest.Account().Address(ton.AccountID{}).MustShardAccount())
+	w, err := New(privateKey, V5R1, blockchain)
+	require.NoError(t, err)
+	blockchain.state = activeState(w, 1_000_000_000)
+
+	// the owner adds an extension with a signed external message
+	_, err = w.AddExtension(context.Background(), ext.GetAddress())
+	require.NoError(t, err)
+	cells, err := boc.DeserializeBoc(<-messages)
+	require.NoError(t, err)
+	var msg tlb.Message
+	require.NoError(t, tlb.Unmarshal(cells[0], &msg))
+	tracer, err := txemulator.NewTraceBuilder(
+		txemulator.WithAccountsSource(walletV5OrderTestAccountSource{
+			accounts: map[ton.AccountID]tlb.ShardAccount{w.GetAddress(): blockchain.state},
+		}),
+		txemulator.WithLimit(10),
+	)
+	require.NoError(t, err)
+	_, err = tracer.Run(context.Background(), msg)
+	require.NoError(t, err)
+	walletState := tracer.FinalStates()[w.GetAddress()]
+	extensions, err := GetW5R1ExtensionsList(walletState, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[ton.AccountID]struct{}{ext.GetAddress(): {}}, extensions)
+
+	// the extension sends a transfer from the wallet and disables the signature
+	recipient := ton.MustParseAccountID("0:00000000000000000000000000000000000000000000000000000000000000a1")
+	request, err := w.ExtensionRequest(50_000_000, W5ExtensionRequest{
+		QueryID:         1,
+		Messages:        []Sendable{SimpleTransfer{Amount: 10_000_000, Address: recipient}},
+		ExtendedActions: W5ExtendedActions{NewW5SetSignatureAllowedAction(false)},
+	})
+	require.NoError(t, err)
+	body, err := ext.CreateMessageBody(MessageConfig{
+		Seqno:      0,
+		ValidUntil: time.Now().Add(time.Minute),
+		V5MsgType:  V5MsgTypeSignedExternal,
+	}, request)
+	require.NoError(t, err)
+	extMsg, err := ton.CreateExternalMessage(ext.GetAddress(), body, nil, tlb.VarUInteger16{})
+	require.NoError(t, err)
+	tracer, err = txemulator.NewTraceBuilder(
+		txemulator.WithAccountsSource(walletV5OrderTestAccountSource{
+			accounts: map[ton.AccountID]tlb.ShardAccount{
+				w.GetAddress():   walletState,
+				ext.GetAddress(): extState,
+			},
+		}),
+		txemulator.WithLimit(10),
+	)
+	require.NoError(t, err)
+	tree, err := tracer.Run(context.Background(), extMsg)
+	require.NoError(t, err)
+	require.Len(t, tree.Children, 1)
+	require.Len(t, tree.Children[0].Children, 1)
+	transfer := tree.Children[0].Children[0].TX.Msgs.InMsg.Value.Value
+	dest, err := ton.AccountIDFromTlb(transfer.Info.IntMsgInfo.Dest)
+	require.NoError(t, err)
+	require.NotNil(t, dest)
+	assert.Equal(t, recipient, *dest)
+	assert.Equal(t, tlb.Grams(10_000_000), transfer.Info.IntMsgInfo.Value.Grams)
+
+	var data DataV5R1
+	dataCell := boc.Cell(tracer.FinalStates()[w.GetAddress()].Account.Account.Storage.State.AccountActive.StateInit.Data.Value.Value)
+	require.NoError(t, tlb.Unmarshal(&dataCell, &data))
+	assert.False(t, data.IsSignatureAllowed)
+}